@@ -0,0 +1,32 @@
+// Command auditverify checks the hash chain of an audit log written by the
+// server, exiting non-zero at the first entry that does not verify.
+//
+//	auditverify audit.log
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"assignment_infracloud/internal/audit"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: auditverify <audit log>")
+		os.Exit(2)
+	}
+	path := os.Args[1]
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	n, err := audit.Verify(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v (%d entries verified before it)\n", path, err, n)
+		os.Exit(1)
+	}
+	fmt.Printf("%s: %d entries, chain intact\n", path, n)
+}
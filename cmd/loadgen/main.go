@@ -0,0 +1,262 @@
+// Command loadgen measures how much traffic a node sustains. It seeds a set
+// of links, then sends a mix of resolves and shortens at a fixed rate for a
+// while and reports throughput and latency percentiles per operation.
+//
+//	loadgen -rate 5000 -duration 30s -resolve 0.95            # in-process server
+//	loadgen -target http://10.0.0.5:8080 -key ... -rate 20000
+//
+// Without -target the server runs in-process on a loopback listener, with an
+// empty in-memory store, so the numbers cover the full HTTP stack.
+//
+// Load is open-loop: requests are due at fixed intervals whether or not
+// earlier ones have finished, and latency is measured from when a request
+// was due, not when it could be sent. A saturated server therefore shows up
+// as growing latency instead of a quietly lower request rate. -concurrency
+// caps the requests in flight; once it is reached, requests wait and the
+// wait is counted in their latency.
+//
+// Resolves pick codes from a Zipf distribution (-zipf-s, -zipf-v) over the
+// seeded links, so a few hot links get most of the traffic, as in real use.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/histogram"
+	apphttp "assignment_infracloud/internal/http"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+// op is one kind of request and what it has measured so far.
+type op struct {
+	name string
+
+	mu     sync.Mutex
+	hist   *histogram.Histogram
+	errors int
+}
+
+func (o *op) record(d time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err != nil {
+		o.errors++
+		return
+	}
+	o.hist.Record(d)
+}
+
+func main() {
+	target := flag.String("target", "", "base URL of the server to load (default an in-process server)")
+	key := flag.String("key", "", "API key for shortens and seeding")
+	rate := flag.Float64("rate", 1000, "requests per second")
+	duration := flag.Duration("duration", 10*time.Second, "how long to send requests for")
+	resolveShare := flag.Float64("resolve", 0.9, "fraction of requests that are resolves; the rest are shortens")
+	codes := flag.Int("codes", 10000, "links to seed and resolve")
+	zipfS := flag.Float64("zipf-s", 1.1, "Zipf exponent s (> 1); larger concentrates resolves on fewer links")
+	zipfV := flag.Float64("zipf-v", 1, "Zipf parameter v (>= 1)")
+	concurrency := flag.Int("concurrency", 512, "maximum requests in flight")
+	seed := flag.Int64("seed", 1, "random seed for the request mix")
+	flag.Parse()
+	if *rate <= 0 || *duration <= 0 || *codes < 1 || *concurrency < 1 ||
+		*resolveShare < 0 || *resolveShare > 1 || *zipfS <= 1 || *zipfV < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	base := *target
+	if base == "" {
+		srv := httptest.NewServer(inProcessServer())
+		defer srv.Close()
+		base = srv.URL
+	}
+	base = strings.TrimRight(base, "/")
+	client := &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			MaxIdleConns:        *concurrency,
+			MaxIdleConnsPerHost: *concurrency,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	l := &loader{base: base, key: *key, client: client}
+
+	log.Printf("seeding %d links on %s", *codes, base)
+	pool, err := l.seed(*codes, *concurrency)
+	if err != nil {
+		log.Fatalf("seed: %v", err)
+	}
+
+	resolve := &op{name: "resolve", hist: histogram.New()}
+	shorten := &op{name: "shorten", hist: histogram.New()}
+	rng := rand.New(rand.NewSource(*seed))
+	zipf := rand.NewZipf(rng, *zipfS, *zipfV, uint64(len(pool)-1))
+	interval := time.Duration(float64(time.Second) / *rate)
+	total := int(duration.Seconds() * *rate)
+	log.Printf("sending %d requests at %.0f/s for %v", total, *rate, *duration)
+
+	slots := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for i := 0; i < total; i++ {
+		due := start.Add(time.Duration(i) * interval)
+		if d := time.Until(due); d > 0 {
+			time.Sleep(d)
+		}
+		slots <- struct{}{}
+		o, run := shorten, func() error {
+			_, err := l.shorten(fmt.Sprintf("https://loadgen.example/%d/%d", start.UnixNano(), i))
+			return err
+		}
+		if rng.Float64() < *resolveShare {
+			code := pool[zipf.Uint64()]
+			o, run = resolve, func() error { return l.resolve(code) }
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := run()
+			o.record(time.Since(due), err)
+			<-slots
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	report(os.Stdout, base, elapsed, resolve, shorten)
+}
+
+// inProcessServer is the API server over an empty in-memory store, without
+// background work that would compete with the load.
+func inProcessServer() http.Handler {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	return apphttp.NewServer(context.Background(), shortener, config.Config{
+		BaseURL:      "http://loadgen.local",
+		RegionHeader: "X-Country",
+		UnlockTTL:    10 * time.Minute,
+	})
+}
+
+type loader struct {
+	base   string
+	key    string
+	client *http.Client
+}
+
+func (l *loader) shorten(longURL string) (string, error) {
+	body, _ := json.Marshal(apphttp.ShortenRequest{URL: longURL})
+	req, err := http.NewRequest(http.MethodPost, l.base+"/api/v1/shorten", strings.NewReader(string(body)))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.key != "" {
+		req.Header.Set("Authorization", "Bearer "+l.key)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("shorten: %s", resp.Status)
+	}
+	var out apphttp.ShortenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	return out.Code, nil
+}
+
+// resolve visits code and expects to be redirected.
+func (l *loader) resolve(code string) error {
+	resp, err := l.client.Get(l.base + "/" + code)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		return fmt.Errorf("resolve %s: %s", code, resp.Status)
+	}
+	return nil
+}
+
+// seed creates n links to resolve, with up to concurrency at a time.
+func (l *loader) seed(n, concurrency int) ([]string, error) {
+	pool := make([]string, n)
+	errs := make(chan error, n)
+	slots := make(chan struct{}, concurrency)
+	run := time.Now().UnixNano()
+	var wg sync.WaitGroup
+	for i := range pool {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			code, err := l.shorten(fmt.Sprintf("https://loadgen.example/seed/%d/%d", run, i))
+			if err != nil {
+				errs <- err
+				return
+			}
+			pool[i] = code
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func report(w io.Writer, base string, elapsed time.Duration, ops ...*op) {
+	fmt.Fprintf(w, "target %s, %v\n\n", base, elapsed.Round(time.Millisecond))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\trequests\terrors\treq/s\tmean\tp50\tp99\tp999\tmax\t")
+	all := &op{name: "all", hist: histogram.New()}
+	for _, o := range ops {
+		all.hist.Merge(o.hist)
+		all.errors += o.errors
+	}
+	for _, o := range append(ops, all) {
+		n := o.hist.Count() + uint64(o.errors)
+		if n == 0 {
+			continue
+		}
+		h := o.hist
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.0f\t%v\t%v\t%v\t%v\t%v\t\n", o.name, n, o.errors,
+			float64(n)/elapsed.Seconds(), round(h.Mean()), round(h.Quantile(0.5)),
+			round(h.Quantile(0.99)), round(h.Quantile(0.999)), round(h.Max()))
+	}
+	tw.Flush()
+}
+
+// round keeps three significant digits or so of a latency.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= 100*time.Millisecond:
+		return d.Round(time.Millisecond)
+	case d >= 100*time.Microsecond:
+		return d.Round(time.Microsecond)
+	default:
+		return d.Round(100 * time.Nanosecond)
+	}
+}
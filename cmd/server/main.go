@@ -2,26 +2,240 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"assignment_infracloud/internal/audit"
 	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/events"
+	"assignment_infracloud/internal/health"
 	apphttp "assignment_infracloud/internal/http"
+	"assignment_infracloud/internal/opengraph"
 	"assignment_infracloud/internal/service"
 	"assignment_infracloud/internal/storage"
+	"assignment_infracloud/internal/tenant"
+	"assignment_infracloud/internal/webhook"
 )
 
+const (
+	healthCheckConcurrency = 8
+	healthCheckHostDelay   = time.Second
+
+	metadataMaxBytes  = 512 << 10
+	metadataTimeout   = 5 * time.Second
+	metadataWorkers   = 4
+	metadataQueueSize = 256
+
+	webhookWorkers   = 4
+	webhookQueueSize = 1024
+
+	eventsHistory = 1024
+	eventsBuffer  = 64
+
+	storeSaveInterval = time.Minute
+)
+
+// webhookBackoff keeps retrying a failing endpoint for roughly a quarter of
+// an hour before a delivery is dead-lettered.
+var webhookBackoff = webhook.Backoff{Attempts: 10, Base: 2 * time.Second, Max: 5 * time.Minute}
+
 func main() {
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("config: %v", err)
 	}
+	// Background work stops only after the last request has been served, so
+	// nothing those requests hand off is dropped early.
+	work, stopWork := context.WithCancel(context.Background())
+	defer stopWork()
+
+	auditLog := audit.New(nil)
+	if cfg.AuditLog != "" {
+		if auditLog, err = audit.Open(cfg.AuditLog); err != nil {
+			log.Fatalf("audit log: %v", err)
+		}
+	}
+	recordConfig(auditLog, cfg)
 
-	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
-	srv := apphttp.NewServer(context.Background(), shortener, cfg)
+	store := storage.NewInMemoryStore()
+	var saved chan error
+	if cfg.StoreFile != "" {
+		backend, err := storage.OpenBackend(cfg.StoreFile)
+		if err != nil {
+			log.Fatalf("store: %v", err)
+		}
+		if store, err = storage.LoadStore(backend); err != nil {
+			log.Fatalf("store: %v", err)
+		}
+		if problems := store.Check(); len(problems) > 0 {
+			log.Printf("store %s: %d inconsistencies, rebuilding indexes", backend, len(problems))
+			store.Reindex()
+		}
+		saved = make(chan error, 1)
+		go func() { saved <- saveStore(work, store, backend, storeSaveInterval) }()
+	}
+	webhooks := webhook.NewDispatcher(nil, webhookWorkers, webhookQueueSize, webhookBackoff)
+	webhooksDone := make(chan struct{})
+	go func() {
+		webhooks.Run(work)
+		close(webhooksDone)
+	}()
+	broker := events.NewBroker(eventsHistory, eventsBuffer)
+	publish := func(typ, stream string) func(storage.Link) {
+		return func(link storage.Link) {
+			webhooks.Publish(webhook.NewEvent(typ, link))
+			if stream != "" {
+				broker.Publish(stream, link)
+			}
+		}
+	}
+	hooks := service.Hooks{
+		Created: publish(webhook.EventCreated, events.TypeCreated),
+		Clicked: publish(webhook.EventClicked, events.TypeClicked),
+		Expired: publish(webhook.EventExpired, ""),
+		Changed: func(ctx context.Context, action string, link storage.Link) {
+			record(auditLog, audit.Entry{
+				Actor:     service.ActorFrom(ctx),
+				Action:    action,
+				Target:    link.Key(),
+				RequestID: service.RequestIDFrom(ctx),
+			})
+		},
+	}
+	if cfg.FetchMetadata {
+		worker := opengraph.NewWorker(
+			opengraph.NewFetcher(nil, metadataMaxBytes, metadataTimeout),
+			store, metadataWorkers, metadataQueueSize)
+		go worker.Run(work)
+		notify := hooks.Created
+		hooks.Created = func(link storage.Link) {
+			worker.Enqueue(link.Key(), link.URL)
+			notify(link)
+		}
+	}
+	shortener := service.NewInMemoryShortenerWithHooks(store, hooks)
+	srv := apphttp.NewServerWithOptions(work, shortener, cfg, apphttp.Options{
+		Audit:    auditLog,
+		Webhooks: webhooks,
+		Events:   broker,
+	})
+
+	if cfg.HealthCheckInterval > 0 {
+		checker := health.NewChecker(store, nil, healthCheckConcurrency, healthCheckHostDelay)
+		go checker.Run(work, cfg.HealthCheckInterval)
+	}
+
+	httpServer := &http.Server{
+		Addr:         ":" + cfg.HTTPPort,
+		Handler:      srv,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	httpServer.RegisterOnShutdown(srv.CloseStreams)
 
+	signals, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+	served := make(chan error, 1)
+	go func() { served <- httpServer.ListenAndServe() }()
 	log.Printf("listening on :%s", cfg.HTTPPort)
-	if err := http.ListenAndServe(":"+cfg.HTTPPort, srv); err != nil {
+	select {
+	case err := <-served:
 		log.Fatal(err)
+	case <-signals.Done():
+	}
+	// A second signal kills the process instead of waiting for the drain.
+	stopSignals()
+
+	log.Printf("shutting down; draining for up to %v", cfg.ShutdownDelay+cfg.ShutdownTimeout)
+	srv.SetReady(false)
+	time.Sleep(cfg.ShutdownDelay)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Printf("shutdown: %v; closing remaining connections", err)
+		httpServer.Close()
+	}
+	if err := <-served; !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("serve: %v", err)
+	}
+
+	shortener.Flush()
+	stopWork()
+	<-webhooksDone
+	if saved != nil {
+		if err := <-saved; err != nil {
+			log.Printf("save store: %v", err)
+		}
+	}
+	if err := auditLog.Close(); err != nil {
+		log.Printf("close audit log: %v", err)
+	}
+	log.Printf("stopped")
+}
+
+// saveStore writes the store to backend every interval, and a last time
+// when ctx is done, returning that save's error. A failed periodic save is
+// logged and retried at the next tick.
+func saveStore(ctx context.Context, store *storage.InMemoryStore, backend storage.Backend, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return backend.Save(store.Snapshot())
+		case <-ticker.C:
+			if err := backend.Save(store.Snapshot()); err != nil {
+				log.Printf("save store: %v", err)
+			}
+		}
+	}
+}
+
+// recordConfig audits the configuration the server starts with. It is only
+// read at startup, so there are no reloads to audit; instead, settings that
+// differ from the previous start, such as added or revoked API keys (listed
+// by fingerprint), are named in the entry's "changed" detail.
+func recordConfig(auditLog *audit.Log, cfg config.Config) {
+	registry, _ := tenant.Parse(cfg.Tenants, cfg.APIKeys)
+	details := map[string]string{
+		"api_keys":      strings.Join(registry.KeyIDs(), ","),
+		"tenants":       strings.Join(registry.IDs(), ","),
+		"short_domains": cfg.ShortDomains,
+	}
+	entries := auditLog.Query(audit.Query{Actor: "system"})
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Action != "config.load" {
+			continue
+		}
+		var changed []string
+		for _, name := range []string{"api_keys", "tenants", "short_domains"} {
+			if entries[i].Details[name] != details[name] {
+				changed = append(changed, name)
+			}
+		}
+		if len(changed) > 0 {
+			details["changed"] = strings.Join(changed, ",")
+		}
+		break
+	}
+	record(auditLog, audit.Entry{
+		Actor:   "system",
+		Action:  "config.load",
+		Details: details,
+	})
+}
+
+// record writes an audit entry, logging rather than failing the action when
+// the audit log cannot be written.
+func record(auditLog *audit.Log, e audit.Entry) {
+	if _, err := auditLog.Record(e); err != nil {
+		log.Printf("audit %s %s: %v", e.Action, e.Target, err)
 	}
 }
@@ -0,0 +1,226 @@
+// Command shortadmin inspects and repairs a persisted store while the server
+// is stopped. Changes made while the server runs are overwritten by its next
+// save.
+//
+//	shortadmin [-store backend] list [-tenant t]
+//	shortadmin [-store backend] lookup <key> ...
+//	shortadmin [-store backend] delete <key> ...
+//	shortadmin [-store backend] verify
+//	shortadmin [-store backend] rebuild
+//	shortadmin [-store backend] migrate [-force] <backend>
+//
+// The backend is given as for STORE_FILE ("json:<path>", "ndjson:<path>" or
+// a path) and defaults to $STORE_FILE. Keys are codes for the default tenant
+// and domain, otherwise "tenant@domain/code".
+//
+// verify checks the derived indexes (code and URL maps, per-domain counts,
+// tag and campaign groups, histories, the ID counter) against the links and
+// exits non-zero if they disagree; rebuild recomputes them from the links.
+// migrate copies the store unchanged to another backend.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"assignment_infracloud/internal/storage"
+)
+
+func main() {
+	flags := flag.NewFlagSet("shortadmin", flag.ExitOnError)
+	spec := flags.String("store", os.Getenv("STORE_FILE"), "store backend (default $STORE_FILE)")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: shortadmin [-store backend] list|lookup|delete|verify|rebuild|migrate [args]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 || *spec == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	backend, err := storage.OpenBackend(*spec)
+	if err != nil {
+		fatal(err)
+	}
+
+	cmd, args := flags.Arg(0), flags.Args()[1:]
+	switch cmd {
+	case "list":
+		err = list(backend, args)
+	case "lookup":
+		err = lookup(backend, args)
+	case "delete":
+		err = remove(backend, args)
+	case "verify":
+		err = verify(backend)
+	case "rebuild":
+		err = rebuild(backend)
+	case "migrate":
+		err = migrate(backend, args)
+	default:
+		fmt.Fprintf(os.Stderr, "shortadmin: unknown command %q\n", cmd)
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "shortadmin: %v\n", err)
+	os.Exit(1)
+}
+
+// open restores the store saved in backend, which must exist.
+func open(backend storage.Backend) (*storage.InMemoryStore, error) {
+	snap, err := backend.Load()
+	if err != nil {
+		return nil, err
+	}
+	store, err := storage.Restore(snap)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", backend, err)
+	}
+	return store, nil
+}
+
+func list(backend storage.Backend, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	tenant := flags.String("tenant", "", "only this tenant's links (default all)")
+	flags.Parse(args)
+	store, err := open(backend)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tCLICKS\tCREATED\tURL")
+	for _, link := range store.Links() {
+		if *tenant != "" && link.Tenant != *tenant {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", link.Key(), link.Clicks,
+			link.CreatedAt.UTC().Format(time.DateTime), link.URL)
+	}
+	return tw.Flush()
+}
+
+func lookup(backend storage.Backend, keys []string) error {
+	if len(keys) == 0 {
+		return errors.New("lookup: no keys given")
+	}
+	store, err := open(backend)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	var missing int
+	for _, key := range keys {
+		link, err := store.GetLink(key)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", key, err)
+			missing++
+			continue
+		}
+		history, _ := store.History(key)
+		enc.Encode(struct {
+			Link    storage.Link
+			History []storage.Version
+		}{link, history})
+	}
+	if missing > 0 {
+		return fmt.Errorf("lookup: %d not found", missing)
+	}
+	return nil
+}
+
+// remove deletes the links and saves the store, as long as every key was
+// found.
+func remove(backend storage.Backend, keys []string) error {
+	if len(keys) == 0 {
+		return errors.New("delete: no keys given")
+	}
+	store, err := open(backend)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, err := store.DeleteLink(key, "shortadmin", time.Now()); err != nil {
+			return fmt.Errorf("delete %s: %w; nothing saved", key, err)
+		}
+	}
+	if err := backend.Save(store.Snapshot()); err != nil {
+		return err
+	}
+	fmt.Printf("%s: deleted %d links\n", backend, len(keys))
+	return nil
+}
+
+func verify(backend storage.Backend) error {
+	store, err := open(backend)
+	if err != nil {
+		return err
+	}
+	problems := store.Check()
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: %d problems; run rebuild to repair the indexes", backend, len(problems))
+	}
+	fmt.Printf("%s: %d links, indexes consistent\n", backend, len(store.Links()))
+	return nil
+}
+
+func rebuild(backend storage.Backend) error {
+	store, err := open(backend)
+	if err != nil {
+		return err
+	}
+	before := len(store.Check())
+	store.Reindex()
+	if problems := store.Check(); len(problems) > 0 {
+		return fmt.Errorf("%s: %d problems remain after rebuilding: %v", backend, len(problems), problems)
+	}
+	if err := backend.Save(store.Snapshot()); err != nil {
+		return err
+	}
+	fmt.Printf("%s: rebuilt indexes of %d links, %d problems fixed\n", backend, len(store.Links()), before)
+	return nil
+}
+
+// migrate copies the store to dst as it is, refusing to overwrite an
+// existing store unless forced.
+func migrate(backend storage.Backend, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	force := flags.Bool("force", false, "overwrite an existing destination")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		return errors.New("migrate: expected one destination backend")
+	}
+	dst, err := storage.OpenBackend(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	snap, err := backend.Load()
+	if err != nil {
+		return err
+	}
+	if _, err := storage.Restore(snap); err != nil {
+		return fmt.Errorf("%s: %w", backend, err)
+	}
+	if _, err := dst.Load(); !*force && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s already exists; use -force to overwrite it", dst)
+	}
+	if err := dst.Save(snap); err != nil {
+		return err
+	}
+	fmt.Printf("%s -> %s: %d links\n", backend, dst, len(snap.Links))
+	return nil
+}
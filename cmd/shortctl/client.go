@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// client calls the shortener API at endpoint, scoped to a short domain when
+// one is set.
+type client struct {
+	endpoint string
+	key      string
+	domain   string
+	http     *http.Client
+}
+
+func newClient(endpoint, key, domain string) *client {
+	return &client{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		key:      key,
+		domain:   domain,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// do sends a request with body encoded as JSON, if not nil, and decodes the
+// response into out, if not nil. Responses other than 2xx become errors
+// carrying the server's message.
+func (c *client) do(method, path string, query url.Values, body, out any) error {
+	if c.domain != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("domain", c.domain)
+	}
+	u := c.endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, u, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.key != "" {
+		req.Header.Set("Authorization", "Bearer "+c.key)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func linkPath(code string) string {
+	return "/api/v1/links/" + url.PathEscape(code)
+}
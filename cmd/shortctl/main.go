@@ -0,0 +1,365 @@
+// Command shortctl is a command-line client for the shortener API.
+//
+//	shortctl [flags] shorten [-tag t]... [-campaign c] [-max-clicks n] [url ...]
+//	shortctl [flags] resolve [code ...]
+//	shortctl [flags] stats [code ...]
+//	shortctl [flags] list [-tag t] [-campaign c] [-health ok|broken|unchecked]
+//	shortctl [flags] delete [code ...]
+//	shortctl [flags] metrics
+//
+// Without url or code arguments, shorten, resolve, stats and delete read
+// them from standard input, one per line, and carry on past failures,
+// reporting each on standard error.
+//
+// The endpoint and API key come from -endpoint and -key, else from
+// SHORTCTL_ENDPOINT and SHORTCTL_API_KEY, else from the config file
+// (-config, SHORTCTL_CONFIG or shortctl/config in the user's config
+// directory), which holds "endpoint = ..." and "api_key = ..." lines.
+//
+// -o picks the output: an aligned table (the default), one JSON object per
+// line, or bare values: codes, or the destination URL for resolve and the
+// click count for stats.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	apphttp "assignment_infracloud/internal/http"
+)
+
+const defaultEndpoint = "http://localhost:8080"
+
+// stringsFlag collects every value of a repeatable flag.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func main() {
+	flags := flag.NewFlagSet("shortctl", flag.ExitOnError)
+	endpoint := flags.String("endpoint", "", "API endpoint (default "+defaultEndpoint+")")
+	key := flags.String("key", "", "API key")
+	configPath := flags.String("config", "", "config file")
+	domain := flags.String("domain", "", "short domain to work on")
+	format := flags.String("o", "table", "output: table, json or code")
+	flags.Usage = usage(flags)
+	flags.Parse(os.Args[1:])
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *format != "table" && *format != "json" && *format != "code" {
+		fmt.Fprintf(os.Stderr, "shortctl: invalid output %q\n", *format)
+		os.Exit(2)
+	}
+
+	settings, err := loadSettings(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shortctl: %v\n", err)
+		os.Exit(1)
+	}
+	if *endpoint != "" {
+		settings.endpoint = *endpoint
+	}
+	if *key != "" {
+		settings.key = *key
+	}
+
+	c := newClient(settings.endpoint, settings.key, *domain)
+	out := newOutput(os.Stdout, *format)
+	cmd, args := flags.Arg(0), flags.Args()[1:]
+	var run func(*client, *output, []string) error
+	switch cmd {
+	case "shorten":
+		run = shorten
+	case "resolve":
+		run = resolve
+	case "stats":
+		run = stats
+	case "list":
+		run = list
+	case "delete":
+		run = remove
+	case "metrics":
+		run = metrics
+	default:
+		fmt.Fprintf(os.Stderr, "shortctl: unknown command %q\n", cmd)
+		flags.Usage()
+		os.Exit(2)
+	}
+	err = run(c, out, args)
+	out.flush()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "shortctl %s: %v\n", cmd, err)
+		os.Exit(1)
+	}
+}
+
+func usage(flags *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(os.Stderr, "usage: shortctl [flags] shorten|resolve|stats|list|delete|metrics [args]")
+		flags.PrintDefaults()
+	}
+}
+
+type settings struct {
+	endpoint string
+	key      string
+}
+
+// loadSettings reads the config file, then lets the environment override it.
+// A missing file is only an error when it was named explicitly.
+func loadSettings(path string) (settings, error) {
+	s := settings{endpoint: defaultEndpoint}
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv("SHORTCTL_CONFIG")
+		explicit = path != ""
+	}
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "shortctl", "config")
+		}
+	}
+	if path != "" {
+		err := readConfig(path, &s)
+		if err != nil && (explicit || !errors.Is(err, os.ErrNotExist)) {
+			return s, err
+		}
+	}
+	if v := os.Getenv("SHORTCTL_ENDPOINT"); v != "" {
+		s.endpoint = v
+	}
+	if v := os.Getenv("SHORTCTL_API_KEY"); v != "" {
+		s.key = v
+	}
+	return s, nil
+}
+
+func readConfig(path string, s *settings) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(text, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected key = value", path, line)
+		}
+		switch v = strings.TrimSpace(v); strings.TrimSpace(k) {
+		case "endpoint":
+			s.endpoint = v
+		case "api_key":
+			s.key = v
+		default:
+			return fmt.Errorf("%s:%d: unknown setting %q", path, line, strings.TrimSpace(k))
+		}
+	}
+	return sc.Err()
+}
+
+// output prints results as a table, JSON lines or bare values. Table rows
+// are buffered so their columns line up.
+type output struct {
+	format  string
+	w       io.Writer
+	tw      *tabwriter.Writer
+	enc     *json.Encoder
+	columns []string
+}
+
+func newOutput(w io.Writer, format string) *output {
+	return &output{
+		format: format,
+		w:      w,
+		tw:     tabwriter.NewWriter(w, 0, 4, 2, ' ', 0),
+		enc:    json.NewEncoder(w),
+	}
+}
+
+// header sets the table's column names, printed before its first row.
+func (o *output) header(columns ...string) {
+	o.columns = columns
+}
+
+// emit prints one result: v as JSON, plain as a bare value, or row.
+func (o *output) emit(v any, plain string, row ...string) {
+	switch o.format {
+	case "json":
+		o.enc.Encode(v)
+	case "code":
+		fmt.Fprintln(o.w, plain)
+	default:
+		if o.columns != nil {
+			fmt.Fprintln(o.tw, strings.Join(o.columns, "\t"))
+			o.columns = nil
+		}
+		fmt.Fprintln(o.tw, strings.Join(row, "\t"))
+	}
+}
+
+func (o *output) flush() {
+	o.tw.Flush()
+}
+
+// eachInput calls fn with every argument or, without any, every non-blank
+// line of standard input. Failures are reported as they happen and do not
+// stop the batch; the returned error says how many there were.
+func eachInput(args []string, fn func(string) error) error {
+	var failed int
+	try := func(in string) {
+		if err := fn(in); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", in, err)
+			failed++
+		}
+	}
+	if len(args) > 0 {
+		for _, arg := range args {
+			try(arg)
+		}
+	} else {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			if in := strings.TrimSpace(sc.Text()); in != "" && !strings.HasPrefix(in, "#") {
+				try(in)
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d failed", failed)
+	}
+	return nil
+}
+
+func shorten(c *client, out *output, args []string) error {
+	flags := flag.NewFlagSet("shorten", flag.ExitOnError)
+	var tags stringsFlag
+	flags.Var(&tags, "tag", "tag the links (repeatable)")
+	campaign := flags.String("campaign", "", "add the links to a campaign")
+	maxClicks := flags.Int("max-clicks", 0, "stop resolving after this many visits")
+	flags.Parse(args)
+
+	out.header("CODE", "SHORT URL", "URL")
+	return eachInput(flags.Args(), func(longURL string) error {
+		req := apphttp.ShortenRequest{
+			URL:       longURL,
+			MaxClicks: *maxClicks,
+			Tags:      tags,
+			Campaign:  *campaign,
+			Domain:    c.domain,
+		}
+		var resp apphttp.ShortenResponse
+		if err := c.do(http.MethodPost, "/api/v1/shorten", nil, req, &resp); err != nil {
+			return err
+		}
+		out.emit(resp, resp.Code, resp.Code, resp.ShortURL, longURL)
+		return nil
+	})
+}
+
+// resolve looks links up without visiting them, so their clicks are not
+// counted.
+func resolve(c *client, out *output, args []string) error {
+	out.header("CODE", "URL")
+	return eachInput(args, func(code string) error {
+		var link apphttp.LinkResponse
+		if err := c.do(http.MethodGet, linkPath(code), nil, nil, &link); err != nil {
+			return err
+		}
+		out.emit(link, link.URL, link.Code, link.URL)
+		return nil
+	})
+}
+
+func stats(c *client, out *output, args []string) error {
+	out.header("CODE", "CLICKS", "VARIANTS")
+	return eachInput(args, func(code string) error {
+		var resp apphttp.StatsResponse
+		if err := c.do(http.MethodGet, linkPath(code)+"/stats", nil, nil, &resp); err != nil {
+			return err
+		}
+		var variants []string
+		for _, v := range resp.Variants {
+			variants = append(variants, fmt.Sprintf("%s=%d", v.Name, v.Clicks))
+		}
+		clicks := strconv.Itoa(resp.Clicks)
+		out.emit(resp, clicks, resp.Code, clicks, strings.Join(variants, " "))
+		return nil
+	})
+}
+
+func list(c *client, out *output, args []string) error {
+	flags := flag.NewFlagSet("list", flag.ExitOnError)
+	tag := flags.String("tag", "", "only links with this tag")
+	campaign := flags.String("campaign", "", "only links in this campaign")
+	health := flags.String("health", "", "only links whose destination is ok, broken or unchecked")
+	flags.Parse(args)
+
+	query := url.Values{}
+	for name, v := range map[string]string{"tag": *tag, "campaign": *campaign, "health": *health} {
+		if v != "" {
+			query.Set(name, v)
+		}
+	}
+	var resp apphttp.LinksResponse
+	if err := c.do(http.MethodGet, "/api/v1/links", query, nil, &resp); err != nil {
+		return err
+	}
+	out.header("CODE", "CLICKS", "CREATED", "TAGS", "URL")
+	for _, link := range resp.Links {
+		out.emit(link, link.Code, link.Code, strconv.Itoa(link.Clicks),
+			link.CreatedAt.Local().Format(time.DateTime), strings.Join(link.Tags, ","), link.URL)
+	}
+	return nil
+}
+
+func remove(c *client, out *output, args []string) error {
+	out.header("DELETED")
+	return eachInput(args, func(code string) error {
+		if err := c.do(http.MethodDelete, linkPath(code), nil, nil, nil); err != nil {
+			return err
+		}
+		out.emit(struct {
+			Code string `json:"code"`
+		}{code}, code, code)
+		return nil
+	})
+}
+
+func metrics(c *client, out *output, _ []string) error {
+	var resp apphttp.MetricsResponse
+	if err := c.do(http.MethodGet, "/api/v1/metrics", nil, nil, &resp); err != nil {
+		return err
+	}
+	out.header("DOMAIN", "LINKS")
+	for _, d := range resp.TopDomains {
+		out.emit(d, d.Domain, d.Domain, strconv.Itoa(d.Count))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package analytics
+
+import (
+	"sync"
+)
+
+// Sink receives aggregated variant click counts for one link.
+type Sink interface {
+	AddVariantClicks(code string, counts map[string]int)
+}
+
+// Recorder buffers per-variant clicks and hands them to the sink in batches,
+// so the redirect path only touches a small mutex instead of the store lock.
+// Counts are flushed once flushEvery clicks have been buffered, and whenever
+// Flush is called, e.g. before stats are read.
+type Recorder struct {
+	sink       Sink
+	flushEvery int
+
+	mu       sync.Mutex
+	buffered int
+	pending  map[string]map[string]int
+}
+
+func NewRecorder(sink Sink, flushEvery int) *Recorder {
+	if flushEvery <= 0 {
+		flushEvery = 1
+	}
+	return &Recorder{
+		sink:       sink,
+		flushEvery: flushEvery,
+		pending:    make(map[string]map[string]int),
+	}
+}
+
+// Record counts one visit to code that was served variant.
+func (r *Recorder) Record(code, variant string) {
+	r.mu.Lock()
+	counts, ok := r.pending[code]
+	if !ok {
+		counts = make(map[string]int)
+		r.pending[code] = counts
+	}
+	counts[variant]++
+	r.buffered++
+	var batch map[string]map[string]int
+	if r.buffered >= r.flushEvery {
+		batch = r.swap()
+	}
+	r.mu.Unlock()
+	r.write(batch)
+}
+
+// Flush writes everything buffered so far to the sink.
+func (r *Recorder) Flush() {
+	r.mu.Lock()
+	batch := r.swap()
+	r.mu.Unlock()
+	r.write(batch)
+}
+
+// swap detaches the pending counts; callers hold r.mu.
+func (r *Recorder) swap() map[string]map[string]int {
+	if r.buffered == 0 {
+		return nil
+	}
+	batch := r.pending
+	r.pending = make(map[string]map[string]int)
+	r.buffered = 0
+	return batch
+}
+
+func (r *Recorder) write(batch map[string]map[string]int) {
+	for code, counts := range batch {
+		r.sink.AddVariantClicks(code, counts)
+	}
+}
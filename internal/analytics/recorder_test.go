@@ -0,0 +1,86 @@
+package analytics
+
+import (
+	"sync"
+	"testing"
+)
+
+type memorySink struct {
+	mu     sync.Mutex
+	writes int
+	counts map[string]map[string]int
+}
+
+func (m *memorySink) AddVariantClicks(code string, counts map[string]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writes++
+	if m.counts == nil {
+		m.counts = make(map[string]map[string]int)
+	}
+	if m.counts[code] == nil {
+		m.counts[code] = make(map[string]int)
+	}
+	for variant, n := range counts {
+		m.counts[code][variant] += n
+	}
+}
+
+func TestRecorder_BuffersUntilFlush(t *testing.T) {
+	sink := &memorySink{}
+	rec := NewRecorder(sink, 100)
+
+	rec.Record("abc", "a")
+	rec.Record("abc", "b")
+	rec.Record("abc", "a")
+	if sink.writes != 0 {
+		t.Fatalf("sink written %d times before flush, want 0", sink.writes)
+	}
+
+	rec.Flush()
+	if sink.counts["abc"]["a"] != 2 || sink.counts["abc"]["b"] != 1 {
+		t.Errorf("counts = %v, want a=2 b=1", sink.counts["abc"])
+	}
+	if sink.writes != 1 {
+		t.Errorf("sink written %d times, want 1 aggregated write", sink.writes)
+	}
+
+	rec.Flush()
+	if sink.writes != 1 {
+		t.Errorf("empty flush wrote to sink")
+	}
+}
+
+func TestRecorder_FlushesWhenFull(t *testing.T) {
+	sink := &memorySink{}
+	rec := NewRecorder(sink, 3)
+
+	for i := 0; i < 3; i++ {
+		rec.Record("abc", "a")
+	}
+	if sink.counts["abc"]["a"] != 3 {
+		t.Errorf("counts = %v, want a=3 after reaching the batch size", sink.counts["abc"])
+	}
+}
+
+func TestRecorder_Concurrent(t *testing.T) {
+	sink := &memorySink{}
+	rec := NewRecorder(sink, 7)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				rec.Record("abc", "a")
+			}
+		}()
+	}
+	wg.Wait()
+	rec.Flush()
+
+	if sink.counts["abc"]["a"] != 1000 {
+		t.Errorf("counted %d clicks, want 1000", sink.counts["abc"]["a"])
+	}
+}
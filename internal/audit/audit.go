@@ -0,0 +1,167 @@
+// Package audit keeps a tamper-evident trail of administrative actions as
+// JSON lines. Every entry carries the hash of the one before it, so editing,
+// dropping or reordering entries breaks the chain and is caught by Verify.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrTampered = errors.New("audit log tampered")
+
+// maxLine bounds the length of one entry when reading a log back.
+const maxLine = 1 << 20
+
+// Entry is one audited action. Seq, Time, PrevHash and Hash are filled in by
+// Record.
+type Entry struct {
+	Seq       uint64            `json:"seq"`
+	Time      time.Time         `json:"time"`
+	Actor     string            `json:"actor"`
+	Action    string            `json:"action"`
+	Target    string            `json:"target,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
+
+// sum is the hash of the entry with its Hash field cleared.
+func (e Entry) sum() string {
+	e.Hash = ""
+	b, _ := json.Marshal(e)
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// Query selects entries: Since is inclusive, Until exclusive, and zero
+// values match everything.
+type Query struct {
+	Since time.Time
+	Until time.Time
+	Actor string
+}
+
+func (q Query) match(e Entry) bool {
+	return (q.Since.IsZero() || !e.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || e.Time.Before(q.Until)) &&
+		(q.Actor == "" || e.Actor == q.Actor)
+}
+
+// Log appends entries to a writer and keeps them in memory for queries.
+type Log struct {
+	mu      sync.Mutex
+	w       io.Writer
+	entries []Entry
+	now     func() time.Time
+}
+
+// New starts an empty log written to w; a nil w keeps entries in memory only.
+func New(w io.Writer) *Log {
+	return &Log{w: w, now: time.Now}
+}
+
+// Open continues the log in the file at path, creating it if needed. It
+// refuses a file whose chain does not verify, so tampering is noticed at
+// startup rather than papered over by new entries.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := read(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	l := New(f)
+	l.entries = entries
+	return l, nil
+}
+
+// Record appends e to the log, chaining it to the previous entry, and
+// returns it as written.
+func (l *Log) Record(e Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e.Seq = uint64(len(l.entries)) + 1
+	e.Time = l.now().UTC()
+	e.PrevHash = ""
+	if n := len(l.entries); n > 0 {
+		e.PrevHash = l.entries[n-1].Hash
+	}
+	e.Hash = e.sum()
+	if l.w != nil {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return Entry{}, err
+		}
+		if _, err := l.w.Write(append(b, '\n')); err != nil {
+			return Entry{}, fmt.Errorf("write audit entry: %w", err)
+		}
+	}
+	l.entries = append(l.entries, e)
+	return e, nil
+}
+
+// Query returns the entries matching q, oldest first.
+func (l *Log) Query(q Query) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []Entry
+	for _, e := range l.entries {
+		if q.match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// Close closes the underlying writer if it is a file or other closer.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Verify reads a log and checks its chain, returning the number of entries.
+// Errors wrapping ErrTampered name the first line that does not verify.
+func Verify(r io.Reader) (int, error) {
+	entries, err := read(r)
+	return len(entries), err
+}
+
+func read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	prev := ""
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxLine)
+	for line := 1; sc.Scan(); line++ {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return entries, fmt.Errorf("line %d: %w: %v", line, ErrTampered, err)
+		}
+		// Re-encoding must reproduce the line exactly, so no byte of it,
+		// including fields this version does not know, escapes the hash.
+		b, _ := json.Marshal(e)
+		if !bytes.Equal(b, sc.Bytes()) || e.Seq != uint64(line) || e.PrevHash != prev || e.Hash != e.sum() {
+			return entries, fmt.Errorf("line %d: %w", line, ErrTampered)
+		}
+		entries = append(entries, e)
+		prev = e.Hash
+	}
+	return entries, sc.Err()
+}
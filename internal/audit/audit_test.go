@@ -0,0 +1,115 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestLog(w io.Writer) *Log {
+	l := New(w)
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	l.now = func() time.Time {
+		now = now.Add(time.Minute)
+		return now
+	}
+	return l
+}
+
+func TestLog_RecordAndVerify(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLog(&buf)
+	l.Record(Entry{Actor: "alice", Action: "link.create", Target: "abc", RequestID: "r1"})
+	l.Record(Entry{Actor: "bob", Action: "link.edit", Target: "abc", Details: map[string]string{"fields": "tags"}})
+	e, err := l.Record(Entry{Actor: "alice", Action: "link.rollback", Target: "abc"})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if e.Seq != 3 || e.PrevHash == "" || e.Hash == "" {
+		t.Errorf("Record() = %+v, want seq 3 chained to the previous entry", e)
+	}
+
+	if n, err := Verify(bytes.NewReader(buf.Bytes())); n != 3 || err != nil {
+		t.Errorf("Verify() = %d, %v, want 3, nil", n, err)
+	}
+}
+
+func TestVerify_Tampered(t *testing.T) {
+	var buf bytes.Buffer
+	l := newTestLog(&buf)
+	for _, actor := range []string{"alice", "bob", "carol"} {
+		l.Record(Entry{Actor: actor, Action: "link.edit", Target: "abc"})
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	tests := []struct {
+		name string
+		log  string
+		line string
+	}{
+		{"edited field", strings.Replace(buf.String(), `"bob"`, `"eve"`, 1), "line 2"},
+		{"dropped entry", lines[0] + lines[2], "line 2"},
+		{"reordered", lines[1] + lines[0] + lines[2], "line 1"},
+		{"extra field", strings.Replace(buf.String(), `"actor":"carol"`, `"actor":"carol","note":"x"`, 1), "line 3"},
+		{"not json", buf.String() + "garbage\n", "line 4"},
+	}
+	for _, tt := range tests {
+		_, err := Verify(strings.NewReader(tt.log))
+		if !errors.Is(err, ErrTampered) || !strings.Contains(err.Error(), tt.line) {
+			t.Errorf("%s: Verify() error = %v, want %v at %s", tt.name, err, ErrTampered, tt.line)
+		}
+	}
+}
+
+func TestLog_Query(t *testing.T) {
+	l := newTestLog(nil)
+	for _, actor := range []string{"alice", "bob", "alice", "bob"} {
+		l.Record(Entry{Actor: actor, Action: "link.edit"})
+	}
+	all := l.Query(Query{})
+	if len(all) != 4 {
+		t.Fatalf("Query() = %d entries, want 4", len(all))
+	}
+	if got := l.Query(Query{Actor: "alice"}); len(got) != 2 || got[1].Seq != 3 {
+		t.Errorf("Query(alice) = %+v, want entries 1 and 3", got)
+	}
+	if got := l.Query(Query{Since: all[1].Time, Until: all[3].Time}); len(got) != 2 || got[0].Seq != 2 {
+		t.Errorf("Query(range) = %+v, want entries 2 and 3", got)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	l.Record(Entry{Actor: "alice", Action: "link.create"})
+	l.Close()
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatalf("reopen error = %v", err)
+	}
+	e, _ := l.Record(Entry{Actor: "bob", Action: "link.edit"})
+	l.Close()
+	if e.Seq != 2 {
+		t.Errorf("Record() after reopen Seq = %d, want 2", e.Seq)
+	}
+	f, _ := os.Open(path)
+	defer f.Close()
+	if n, err := Verify(f); n != 2 || err != nil {
+		t.Errorf("Verify() = %d, %v, want 2, nil", n, err)
+	}
+
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, bytes.Replace(data, []byte("bob"), []byte("eve"), 1), 0o600)
+	if _, err := Open(path); !errors.Is(err, ErrTampered) {
+		t.Errorf("Open(tampered) error = %v, want %v", err, ErrTampered)
+	}
+}
@@ -1,33 +1,215 @@
 package config
 
 import (
-    "fmt"
-    "net/url"
-    "os"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment_infracloud/internal/signing"
+	"assignment_infracloud/internal/storage"
+	"assignment_infracloud/internal/tenant"
 )
 
 type Config struct {
-    HTTPPort string
-    BaseURL  string
+	HTTPPort string
+	BaseURL  string
+	// ShortDomains is a comma-separated list of extra branded hosts served
+	// alongside BaseURL's host, e.g. "go.acme.com,acme.link".
+	ShortDomains string
+	// Tenants assigns short domains to tenants, "id:domain domain,...";
+	// every listed domain must also be in ShortDomains.
+	Tenants string
+	// APIKeys is "tenant:key,..." (or a bare key for the default tenant).
+	// When set, API calls must present a key and are scoped to its tenant.
+	APIKeys string
+
+	// CookieSecret signs the cookies issued after a password-protected link
+	// is unlocked. When empty the server generates a random one at startup,
+	// which invalidates outstanding cookies on restart.
+	CookieSecret string
+	// UnlockTTL is how long an unlock cookie stays valid.
+	UnlockTTL time.Duration
+	// SigningKeys is the "kid:secret,..." key set for signed links; the first
+	// key signs, all of them verify.
+	SigningKeys string
+	// RegionHeader names the request header carrying the visitor's country,
+	// as set by the edge, for locale rules.
+	RegionHeader string
+	// HealthCheckInterval is how often link destinations are probed; zero
+	// disables the checker.
+	HealthCheckInterval time.Duration
+	// FetchMetadata enables fetching the title, description and image of
+	// new links' destinations in the background.
+	FetchMetadata bool
+	// AuditLog is the file administrative actions are appended to; when
+	// empty the audit trail is kept in memory only.
+	AuditLog string
+	// StoreFile names the backend, as accepted by storage.OpenBackend, that
+	// links are loaded from at startup and saved to while running; when
+	// empty they are kept in memory only.
+	StoreFile string
+
+	// ReadTimeout, WriteTimeout and IdleTimeout bound how long the server
+	// reads a request, writes a response and keeps an idle connection open;
+	// zero means no limit. Event streams and exports are exempt from
+	// WriteTimeout.
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	// ShutdownDelay is how long the server keeps serving after it reports
+	// itself not ready, so load balancers stop routing to it first.
+	ShutdownDelay time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// once shutdown begins.
+	ShutdownTimeout time.Duration
 }
 
 func Load() (Config, error) {
-    port := os.Getenv("PORT")
-    if port == "" {
-        port = "8080"
-    }
-    baseURL := os.Getenv("BASE_URL")
-    if baseURL == "" {
-        baseURL = fmt.Sprintf("http://localhost:%s", port)
-    }
-    if _, err := url.ParseRequestURI(baseURL); err != nil {
-        return Config{}, fmt.Errorf("invalid BASE_URL: %w", err)
-    }
-
-    return Config{
-        HTTPPort: port,
-        BaseURL:  baseURL,
-    }, nil
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("http://localhost:%s", port)
+	}
+	if _, err := url.ParseRequestURI(baseURL); err != nil {
+		return Config{}, fmt.Errorf("invalid BASE_URL: %w", err)
+	}
+	shortDomains := os.Getenv("SHORT_DOMAINS")
+	if _, err := ParseDomains(shortDomains); err != nil {
+		return Config{}, fmt.Errorf("invalid SHORT_DOMAINS: %w", err)
+	}
+	tenants, apiKeys := os.Getenv("TENANTS"), os.Getenv("API_KEYS")
+	registry, err := tenant.Parse(tenants, apiKeys)
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid TENANTS or API_KEYS: %w", err)
+	}
+	domains, _ := ParseDomains(shortDomains)
+	for _, id := range registry.IDs() {
+		for _, d := range registry.Domains(id) {
+			if !slices.Contains(domains, d) {
+				return Config{}, fmt.Errorf("invalid TENANTS: domain %q is not in SHORT_DOMAINS", d)
+			}
+		}
+	}
+	unlockTTL := 10 * time.Minute
+	if v := os.Getenv("UNLOCK_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid UNLOCK_TTL: %q", v)
+		}
+		unlockTTL = d
+	}
+	signingKeys := os.Getenv("SIGNING_KEYS")
+	if _, err := signing.ParseKeyring(signingKeys); err != nil {
+		return Config{}, fmt.Errorf("invalid SIGNING_KEYS: %w", err)
+	}
+	regionHeader := os.Getenv("REGION_HEADER")
+	if regionHeader == "" {
+		regionHeader = "X-Country"
+	}
+	healthInterval := time.Hour
+	if v := os.Getenv("HEALTH_CHECK_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return Config{}, fmt.Errorf("invalid HEALTH_CHECK_INTERVAL: %q", v)
+		}
+		healthInterval = d
+	}
+	fetchMetadata := true
+	if v := os.Getenv("FETCH_METADATA"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid FETCH_METADATA: %q", v)
+		}
+		fetchMetadata = b
+	}
+	storeFile := os.Getenv("STORE_FILE")
+	if storeFile != "" {
+		if _, err := storage.OpenBackend(storeFile); err != nil {
+			return Config{}, fmt.Errorf("invalid STORE_FILE: %w", err)
+		}
+	}
+	readTimeout, err := duration("READ_TIMEOUT", 15*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+	writeTimeout, err := duration("WRITE_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+	idleTimeout, err := duration("IDLE_TIMEOUT", 2*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+	shutdownDelay, err := duration("SHUTDOWN_DELAY", 0)
+	if err != nil {
+		return Config{}, err
+	}
+	shutdownTimeout, err := duration("SHUTDOWN_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+	if shutdownTimeout == 0 {
+		return Config{}, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %q", os.Getenv("SHUTDOWN_TIMEOUT"))
+	}
+
+	return Config{
+		HTTPPort:     port,
+		BaseURL:      baseURL,
+		ShortDomains: shortDomains,
+		Tenants:      tenants,
+		APIKeys:      apiKeys,
+		CookieSecret: os.Getenv("COOKIE_SECRET"),
+		UnlockTTL:    unlockTTL,
+		SigningKeys:  signingKeys,
+		RegionHeader: regionHeader,
+
+		HealthCheckInterval: healthInterval,
+		FetchMetadata:       fetchMetadata,
+		AuditLog:            os.Getenv("AUDIT_LOG"),
+		StoreFile:           storeFile,
+
+		ReadTimeout:     readTimeout,
+		WriteTimeout:    writeTimeout,
+		IdleTimeout:     idleTimeout,
+		ShutdownDelay:   shutdownDelay,
+		ShutdownTimeout: shutdownTimeout,
+	}, nil
 }
 
+// duration reads a non-negative duration from the environment variable
+// name, or returns def when it is unset.
+func duration(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", name, v)
+	}
+	return d, nil
+}
 
+// ParseDomains splits a comma-separated list of hosts, lower-casing them and
+// skipping empty entries. Entries must be bare hosts, optionally with a port.
+func ParseDomains(spec string) ([]string, error) {
+	var domains []string
+	for _, d := range strings.Split(spec, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if d == "" {
+			continue
+		}
+		if u, err := url.Parse("//" + d); err != nil || u.Host != d || u.User != nil {
+			return nil, fmt.Errorf("invalid domain %q", d)
+		}
+		domains = append(domains, d)
+	}
+	return domains, nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoad_DefaultValues(t *testing.T) {
@@ -22,6 +23,10 @@ func TestLoad_DefaultValues(t *testing.T) {
 	if cfg.BaseURL != "http://localhost:8080" {
 		t.Errorf("Load().BaseURL = %v, want %v", cfg.BaseURL, "http://localhost:8080")
 	}
+
+	if cfg.RegionHeader != "X-Country" {
+		t.Errorf("Load().RegionHeader = %v, want %v", cfg.RegionHeader, "X-Country")
+	}
 }
 
 func TestLoad_CustomPort(t *testing.T) {
@@ -74,3 +79,220 @@ func TestLoad_InvalidBaseURL(t *testing.T) {
 		t.Error("Load() should return error for invalid BASE_URL")
 	}
 }
+
+func TestLoad_UnlockTTL(t *testing.T) {
+	os.Unsetenv("UNLOCK_TTL")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.UnlockTTL != 10*time.Minute {
+		t.Errorf("Load().UnlockTTL = %v, want %v", cfg.UnlockTTL, 10*time.Minute)
+	}
+
+	os.Setenv("UNLOCK_TTL", "90s")
+	defer os.Unsetenv("UNLOCK_TTL")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.UnlockTTL != 90*time.Second {
+		t.Errorf("Load().UnlockTTL = %v, want %v", cfg.UnlockTTL, 90*time.Second)
+	}
+
+	os.Setenv("UNLOCK_TTL", "soon")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should return error for invalid UNLOCK_TTL")
+	}
+}
+
+func TestLoad_SigningKeys(t *testing.T) {
+	os.Setenv("SIGNING_KEYS", "k2:new,k1:old")
+	defer os.Unsetenv("SIGNING_KEYS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SigningKeys != "k2:new,k1:old" {
+		t.Errorf("Load().SigningKeys = %v, want %v", cfg.SigningKeys, "k2:new,k1:old")
+	}
+
+	os.Setenv("SIGNING_KEYS", "missing-secret")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should return error for malformed SIGNING_KEYS")
+	}
+}
+
+func TestLoad_HealthCheckInterval(t *testing.T) {
+	os.Unsetenv("HEALTH_CHECK_INTERVAL")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.HealthCheckInterval != time.Hour {
+		t.Errorf("Load().HealthCheckInterval = %v, want %v", cfg.HealthCheckInterval, time.Hour)
+	}
+
+	os.Setenv("HEALTH_CHECK_INTERVAL", "0")
+	defer os.Unsetenv("HEALTH_CHECK_INTERVAL")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.HealthCheckInterval != 0 {
+		t.Errorf("Load().HealthCheckInterval = %v, want 0 (disabled)", cfg.HealthCheckInterval)
+	}
+
+	os.Setenv("HEALTH_CHECK_INTERVAL", "-1m")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should return error for negative HEALTH_CHECK_INTERVAL")
+	}
+}
+
+func TestLoad_FetchMetadata(t *testing.T) {
+	os.Unsetenv("FETCH_METADATA")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.FetchMetadata {
+		t.Errorf("Load().FetchMetadata = %v, want %v", cfg.FetchMetadata, true)
+	}
+
+	os.Setenv("FETCH_METADATA", "false")
+	defer os.Unsetenv("FETCH_METADATA")
+	if cfg, _ = Load(); cfg.FetchMetadata {
+		t.Errorf("Load().FetchMetadata = %v, want %v", cfg.FetchMetadata, false)
+	}
+
+	os.Setenv("FETCH_METADATA", "maybe")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should return error for invalid FETCH_METADATA")
+	}
+}
+
+func TestLoad_ShortDomains(t *testing.T) {
+	os.Setenv("SHORT_DOMAINS", "go.acme.com, acme.link")
+	defer os.Unsetenv("SHORT_DOMAINS")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ShortDomains != "go.acme.com, acme.link" {
+		t.Errorf("Load().ShortDomains = %v, want %v", cfg.ShortDomains, "go.acme.com, acme.link")
+	}
+
+	os.Setenv("SHORT_DOMAINS", "https://acme.link/x")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should return error for a SHORT_DOMAINS entry that is not a host")
+	}
+}
+
+func TestParseDomains(t *testing.T) {
+	got, err := ParseDomains(" Go.Acme.com,,acme.link:8443 ")
+	if err != nil {
+		t.Fatalf("ParseDomains() error = %v", err)
+	}
+	if len(got) != 2 || got[0] != "go.acme.com" || got[1] != "acme.link:8443" {
+		t.Errorf("ParseDomains() = %v, want [go.acme.com acme.link:8443]", got)
+	}
+	for _, bad := range []string{"acme.link/path", "user@acme.link", "acme link"} {
+		if _, err := ParseDomains(bad); err == nil {
+			t.Errorf("ParseDomains(%q) error = nil, want error", bad)
+		}
+	}
+}
+
+func TestLoad_Tenants(t *testing.T) {
+	os.Setenv("SHORT_DOMAINS", "go.acme.com,glbx.io")
+	os.Setenv("TENANTS", "acme:go.acme.com,globex:glbx.io")
+	os.Setenv("API_KEYS", "acme:k1,globex:k2")
+	defer func() {
+		os.Unsetenv("SHORT_DOMAINS")
+		os.Unsetenv("TENANTS")
+		os.Unsetenv("API_KEYS")
+	}()
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Tenants != "acme:go.acme.com,globex:glbx.io" || cfg.APIKeys != "acme:k1,globex:k2" {
+		t.Errorf("Load() Tenants = %q, APIKeys = %q", cfg.Tenants, cfg.APIKeys)
+	}
+
+	os.Setenv("TENANTS", "acme:go.acme.com,globex:elsewhere.io")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should return error for a tenant domain missing from SHORT_DOMAINS")
+	}
+	os.Setenv("TENANTS", "acme:go.acme.com")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should return error for an API key of an unknown tenant")
+	}
+}
+
+func TestLoad_AuditLog(t *testing.T) {
+	os.Setenv("AUDIT_LOG", "/var/log/shortener/audit.log")
+	defer os.Unsetenv("AUDIT_LOG")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.AuditLog != "/var/log/shortener/audit.log" {
+		t.Errorf("Load().AuditLog = %v, want %v", cfg.AuditLog, "/var/log/shortener/audit.log")
+	}
+}
+
+func TestLoad_StoreFile(t *testing.T) {
+	os.Setenv("STORE_FILE", "ndjson:/var/lib/shortener/links")
+	defer os.Unsetenv("STORE_FILE")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.StoreFile != "ndjson:/var/lib/shortener/links" {
+		t.Errorf("Load().StoreFile = %v, want %v", cfg.StoreFile, "ndjson:/var/lib/shortener/links")
+	}
+
+	os.Setenv("STORE_FILE", "redis:localhost:6379")
+	if _, err := Load(); err == nil {
+		t.Error("Load() should return error for an unknown store backend")
+	}
+}
+
+func TestLoad_ServerTimeouts(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ReadTimeout != 15*time.Second || cfg.WriteTimeout != 30*time.Second || cfg.IdleTimeout != 2*time.Minute ||
+		cfg.ShutdownDelay != 0 || cfg.ShutdownTimeout != 30*time.Second {
+		t.Errorf("Load() timeouts = %v %v %v %v %v, want the defaults",
+			cfg.ReadTimeout, cfg.WriteTimeout, cfg.IdleTimeout, cfg.ShutdownDelay, cfg.ShutdownTimeout)
+	}
+
+	for name, v := range map[string]string{"READ_TIMEOUT": "5s", "WRITE_TIMEOUT": "0", "SHUTDOWN_DELAY": "10s"} {
+		os.Setenv(name, v)
+		defer os.Unsetenv(name)
+	}
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.ReadTimeout != 5*time.Second || cfg.WriteTimeout != 0 || cfg.ShutdownDelay != 10*time.Second {
+		t.Errorf("Load() timeouts = %v %v %v, want 5s 0s 10s", cfg.ReadTimeout, cfg.WriteTimeout, cfg.ShutdownDelay)
+	}
+
+	for name, v := range map[string]string{"IDLE_TIMEOUT": "-1s", "SHUTDOWN_TIMEOUT": "0", "READ_TIMEOUT": "soon"} {
+		old := os.Getenv(name)
+		os.Setenv(name, v)
+		if _, err := Load(); err == nil {
+			t.Errorf("Load() should return error for %s=%q", name, v)
+		}
+		os.Setenv(name, old)
+	}
+}
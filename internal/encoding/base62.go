@@ -4,6 +4,7 @@ import (
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/hex"
+	"math"
 	"strings"
 )
 
@@ -28,6 +29,24 @@ func Base62Encode(num uint64) string {
 	return string(r)
 }
 
+// Base62Decode is the inverse of Base62Encode. It reports false for strings
+// Base62Encode never produces: empty ones, ones with leading zeros or
+// characters outside the alphabet, and ones too large for a uint64.
+func Base62Decode(s string) (uint64, bool) {
+	if s == "" || (len(s) > 1 && s[0] == alphabet[0]) {
+		return 0, false
+	}
+	var num uint64
+	for i := 0; i < len(s); i++ {
+		d := strings.IndexByte(alphabet, s[i])
+		if d < 0 || num > (math.MaxUint64-uint64(d))/62 {
+			return 0, false
+		}
+		num = num*62 + uint64(d)
+	}
+	return num, true
+}
+
 // MD5Hex returns the MD5 hash of s in hex (not recommended for security uses).
 func MD5Hex(s string) string {
 	sum := md5.Sum([]byte(s))
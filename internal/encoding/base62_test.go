@@ -1,6 +1,7 @@
 package encoding
 
 import (
+	"math"
 	"testing"
 )
 
@@ -86,3 +87,17 @@ func BenchmarkBase62Encode(b *testing.B) {
 		Base62Encode(uint64(i))
 	}
 }
+
+func TestBase62Decode(t *testing.T) {
+	for _, n := range []uint64{0, 1, 61, 62, 1000000, 123456789, math.MaxUint64} {
+		got, ok := Base62Decode(Base62Encode(n))
+		if !ok || got != n {
+			t.Errorf("Base62Decode(Base62Encode(%d)) = %d, %v", n, got, ok)
+		}
+	}
+	for _, s := range []string{"", "00", "01", "a-b", "my code", "zzzzzzzzzzzzzz"} {
+		if n, ok := Base62Decode(s); ok {
+			t.Errorf("Base62Decode(%q) = %d, want failure", s, n)
+		}
+	}
+}
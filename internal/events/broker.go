@@ -0,0 +1,171 @@
+// Package events fans link events out to live subscribers, such as the
+// server-sent events stream, without ever blocking the publisher.
+package events
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"assignment_infracloud/internal/storage"
+)
+
+// Event types.
+const (
+	TypeCreated = "link.created"
+	TypeClicked = "link.clicked"
+)
+
+// Event is one published event. IDs increase by one per event, so a
+// subscriber can resume after the last one it saw.
+type Event struct {
+	ID     uint64    `json:"id"`
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Tenant string    `json:"-"`
+	Code   string    `json:"code"`
+	Domain string    `json:"domain,omitempty"`
+	URL    string    `json:"url"`
+	Clicks int       `json:"clicks"`
+	Tags   []string  `json:"tags,omitempty"`
+}
+
+// Filter selects the events of one tenant, optionally only those about
+// some codes or carrying one of some tags.
+type Filter struct {
+	Tenant string
+	Codes  []string
+	Tags   []string
+}
+
+func (f Filter) match(e Event) bool {
+	if e.Tenant != f.Tenant {
+		return false
+	}
+	if len(f.Codes) > 0 && !slices.Contains(f.Codes, e.Code) {
+		return false
+	}
+	if len(f.Tags) > 0 && !slices.ContainsFunc(e.Tags, func(t string) bool { return slices.Contains(f.Tags, t) }) {
+		return false
+	}
+	return true
+}
+
+// Subscription receives matching events on C. When the subscriber falls
+// behind by more than its buffer, events are either dropped and counted or,
+// if it asked to be disconnected, C is closed.
+type Subscription struct {
+	C <-chan Event
+	// Backlog holds the retained events after the requested resume point,
+	// to be handled before anything from C.
+	Backlog []Event
+
+	c          chan Event
+	filter     Filter
+	disconnect bool
+	dropped    int
+}
+
+// Broker keeps the subscribers and a ring of recent events for resumption.
+type Broker struct {
+	mu     sync.Mutex
+	lastID uint64
+	ring   []Event
+	start  int // index of the oldest event once the ring is full
+	buffer int
+	subs   map[*Subscription]struct{}
+	now    func() time.Time
+}
+
+// NewBroker creates a broker retaining the last history events and giving
+// each subscriber a buffer of that many undelivered events.
+func NewBroker(history, buffer int) *Broker {
+	return &Broker{
+		ring:   make([]Event, 0, history),
+		buffer: buffer,
+		subs:   make(map[*Subscription]struct{}),
+		now:    time.Now,
+	}
+}
+
+// Publish records that typ happened to link and hands it to every matching
+// subscriber. It never blocks.
+func (b *Broker) Publish(typ string, link storage.Link) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	e := Event{
+		ID:     b.lastID,
+		Type:   typ,
+		Time:   b.now().UTC(),
+		Tenant: link.Tenant,
+		Code:   link.Code,
+		Domain: link.Domain,
+		URL:    link.URL,
+		Clicks: link.Clicks,
+		Tags:   link.Tags,
+	}
+	if len(b.ring) < cap(b.ring) {
+		b.ring = append(b.ring, e)
+	} else if len(b.ring) > 0 {
+		b.ring[b.start] = e
+		b.start = (b.start + 1) % len(b.ring)
+	}
+	for sub := range b.subs {
+		if !sub.filter.match(e) {
+			continue
+		}
+		select {
+		case sub.c <- e:
+		default:
+			if sub.disconnect {
+				b.remove(sub)
+			} else {
+				sub.dropped++
+			}
+		}
+	}
+}
+
+// Subscribe registers a subscriber. With resume set, the retained events
+// published after lastID are returned in the subscription's Backlog; events
+// older than the ring are gone. disconnect picks what happens when the
+// subscriber cannot keep up.
+func (b *Broker) Subscribe(filter Filter, lastID uint64, resume, disconnect bool) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := make(chan Event, b.buffer)
+	sub := &Subscription{C: c, c: c, filter: filter, disconnect: disconnect}
+	if resume {
+		for i := range b.ring {
+			e := b.ring[(b.start+i)%len(b.ring)]
+			if e.ID > lastID && filter.match(e) {
+				sub.Backlog = append(sub.Backlog, e)
+			}
+		}
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes sub; it is safe to call after the broker has already
+// disconnected it.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// Dropped returns how many events sub has missed by falling behind.
+func (b *Broker) Dropped(sub *Subscription) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return sub.dropped
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.c)
+	}
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"strings"
+	"testing"
+
+	"assignment_infracloud/internal/storage"
+)
+
+func codes(events []Event) []string {
+	var out []string
+	for _, e := range events {
+		out = append(out, e.Code)
+	}
+	return out
+}
+
+func drain(c <-chan Event) []Event {
+	var out []Event
+	for {
+		select {
+		case e, ok := <-c:
+			if !ok {
+				return out
+			}
+			out = append(out, e)
+		default:
+			return out
+		}
+	}
+}
+
+func TestBroker_Filters(t *testing.T) {
+	b := NewBroker(16, 16)
+	all := b.Subscribe(Filter{}, 0, false, false)
+	byCode := b.Subscribe(Filter{Codes: []string{"b"}}, 0, false, false)
+	byTag := b.Subscribe(Filter{Tags: []string{"launch"}}, 0, false, false)
+	acme := b.Subscribe(Filter{Tenant: "acme"}, 0, false, false)
+
+	b.Publish(TypeCreated, storage.Link{Code: "a", Tags: []string{"launch", "q3"}})
+	b.Publish(TypeClicked, storage.Link{Code: "b"})
+	b.Publish(TypeClicked, storage.Link{Code: "c", Tenant: "acme"})
+
+	tests := []struct {
+		name string
+		sub  *Subscription
+		want string
+	}{
+		{"all", all, "a,b"},
+		{"code", byCode, "b"},
+		{"tag", byTag, "a"},
+		{"tenant", acme, "c"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(codes(drain(tt.sub.C)), ","); got != tt.want {
+			t.Errorf("%s: received %v, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBroker_Resume(t *testing.T) {
+	b := NewBroker(3, 16)
+	for _, code := range []string{"a", "b", "c", "d", "e"} {
+		b.Publish(TypeClicked, storage.Link{Code: code})
+	}
+
+	sub := b.Subscribe(Filter{}, 3, true, false)
+	if got := strings.Join(codes(sub.Backlog), ","); got != "d,e" {
+		t.Errorf("Backlog after 3 = %s, want d,e", got)
+	}
+	if ids := sub.Backlog; ids[0].ID != 4 || ids[1].ID != 5 {
+		t.Errorf("Backlog IDs = %d,%d, want 4,5", ids[0].ID, ids[1].ID)
+	}
+	// Events that fell out of the ring cannot be replayed.
+	if got := strings.Join(codes(b.Subscribe(Filter{}, 0, true, false).Backlog), ","); got != "c,d,e" {
+		t.Errorf("Backlog after 0 = %s, want the retained c,d,e", got)
+	}
+
+	b.Publish(TypeClicked, storage.Link{Code: "f"})
+	if got := strings.Join(codes(drain(sub.C)), ","); got != "f" {
+		t.Errorf("live events = %s, want f", got)
+	}
+}
+
+func TestBroker_SlowSubscribers(t *testing.T) {
+	b := NewBroker(16, 2)
+	dropper := b.Subscribe(Filter{}, 0, false, false)
+	quitter := b.Subscribe(Filter{}, 0, false, true)
+	for _, code := range []string{"a", "b", "c", "d"} {
+		b.Publish(TypeClicked, storage.Link{Code: code})
+	}
+
+	if got := strings.Join(codes(drain(dropper.C)), ","); got != "a,b" {
+		t.Errorf("dropping subscriber received %s, want a,b", got)
+	}
+	if got := b.Dropped(dropper); got != 2 {
+		t.Errorf("Dropped() = %d, want 2", got)
+	}
+	drain(quitter.C)
+	if _, open := <-quitter.C; open {
+		t.Error("slow subscriber asking to be disconnected is still connected")
+	}
+	b.Unsubscribe(quitter) // already gone
+	b.Unsubscribe(dropper)
+	if _, open := <-dropper.C; open {
+		t.Error("Unsubscribe() left the channel open")
+	}
+}
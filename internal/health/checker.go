@@ -0,0 +1,174 @@
+// Package health periodically checks that link destinations still answer.
+package health
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"assignment_infracloud/internal/netguard"
+	"assignment_infracloud/internal/storage"
+)
+
+// Store is the subset of the link store the checker reads from and records
+// results to.
+type Store interface {
+	Links() []storage.Link
+	SetHealth(key string, h storage.Health)
+}
+
+const (
+	userAgent    = "shortener-health-checker/1.0"
+	checkTimeout = 10 * time.Second
+)
+
+// Checker issues a HEAD request, falling back to GET, to every distinct
+// destination URL. At most concurrency requests are in flight at once, and
+// requests to the same host are made one at a time, hostDelay apart.
+type Checker struct {
+	store       Store
+	client      *http.Client
+	concurrency int
+	hostDelay   time.Duration
+	now         func() time.Time
+}
+
+// NewChecker returns a checker using client, or, when client is nil, one
+// that refuses to connect to loopback, private and link-local addresses, so
+// shortened links cannot be used to probe internal services.
+func NewChecker(store Store, client *http.Client, concurrency int, hostDelay time.Duration) *Checker {
+	if client == nil {
+		client = &http.Client{Timeout: checkTimeout, Transport: netguard.Transport(checkTimeout)}
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Checker{
+		store:       store,
+		client:      client,
+		concurrency: concurrency,
+		hostDelay:   hostDelay,
+		now:         time.Now,
+	}
+}
+
+// Run checks all links immediately and then every interval until ctx is done.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.CheckAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll runs one pass over every stored link and records the results.
+// Links sharing a destination are checked once.
+func (c *Checker) CheckAll(ctx context.Context) {
+	// Group link keys by URL, and URLs by host so each host gets one worker.
+	codes := make(map[string][]string)
+	var hosts []string
+	byHost := make(map[string][]string)
+	for _, link := range c.store.Links() {
+		if _, seen := codes[link.URL]; !seen {
+			host := ""
+			if u, err := url.Parse(link.URL); err == nil {
+				host = u.Host
+			}
+			if _, ok := byHost[host]; !ok {
+				hosts = append(hosts, host)
+			}
+			byHost[host] = append(byHost[host], link.URL)
+		}
+		codes[link.URL] = append(codes[link.URL], link.Key())
+	}
+
+	queue := make(chan []string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(c.concurrency, len(hosts)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for urls := range queue {
+				for i, u := range urls {
+					if i > 0 && !sleep(ctx, c.hostDelay) {
+						break
+					}
+					h := c.Check(ctx, u)
+					if ctx.Err() != nil {
+						break
+					}
+					for _, key := range codes[u] {
+						c.store.SetHealth(key, h)
+					}
+				}
+			}
+		}()
+	}
+	for _, host := range hosts {
+		select {
+		case queue <- byHost[host]:
+		case <-ctx.Done():
+		}
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// Check probes one destination. Servers that reject HEAD are retried with
+// GET; a network error or a final status of 400 or above marks the
+// destination broken. Redirects are followed.
+func (c *Checker) Check(ctx context.Context, dest string) storage.Health {
+	h := storage.Health{Status: storage.HealthBroken, CheckedAt: c.now()}
+	status, err := c.probe(ctx, http.MethodHead, dest)
+	if err == nil && status >= 400 {
+		status, err = c.probe(ctx, http.MethodGet, dest)
+	}
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	h.StatusCode = status
+	if status < 400 {
+		h.Status = storage.HealthOK
+	}
+	return h
+}
+
+func (c *Checker) probe(ctx context.Context, method, dest string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, dest, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Read a little so the connection can be reused, without downloading
+	// whole pages.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	return resp.StatusCode, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,180 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/netguard"
+	"assignment_infracloud/internal/storage"
+)
+
+func TestChecker_Check(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		path       string
+		wantStatus string
+		wantCode   int
+	}{
+		{"/ok", storage.HealthOK, 200},
+		{"/moved", storage.HealthOK, 200},
+		{"/gone", storage.HealthBroken, 410},
+		{"/no-head", storage.HealthOK, 200},
+		{"/error", storage.HealthBroken, 500},
+		{"/missing", storage.HealthBroken, 404},
+	}
+	c := NewChecker(nil, srv.Client(), 1, 0)
+	for _, tt := range tests {
+		h := c.Check(context.Background(), srv.URL+tt.path)
+		if h.Status != tt.wantStatus || h.StatusCode != tt.wantCode {
+			t.Errorf("Check(%s) = %s %d, want %s %d", tt.path, h.Status, h.StatusCode, tt.wantStatus, tt.wantCode)
+		}
+		if h.CheckedAt.IsZero() {
+			t.Errorf("Check(%s) did not set CheckedAt", tt.path)
+		}
+	}
+}
+
+func TestChecker_Check_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	addr := srv.URL
+	srv.Close()
+
+	h := NewChecker(nil, &http.Client{}, 1, 0).Check(context.Background(), addr)
+	if h.Status != storage.HealthBroken || h.Error == "" {
+		t.Errorf("Check(closed server) = %+v, want broken with an error", h)
+	}
+}
+
+func TestChecker_Check_PrivateAddress(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+
+	h := NewChecker(nil, nil, 1, 0).Check(context.Background(), srv.URL)
+	if h.Status != storage.HealthBroken || h.StatusCode != 0 || !strings.Contains(h.Error, netguard.ErrPrivateAddress.Error()) {
+		t.Errorf("Check(loopback) = %+v, want broken by the address check", h)
+	}
+	if hits.Load() != 0 {
+		t.Errorf("loopback server got %d requests, want none", hits.Load())
+	}
+}
+
+func TestChecker_CheckAll(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.NotFoundHandler())
+	defer broken.Close()
+
+	store := storage.NewInMemoryStore()
+	store.SaveMapping("a", ok.URL+"/page")
+	store.SaveLink(storage.Link{Code: "b", URL: ok.URL + "/page", MaxClicks: 3}) // same destination
+	store.SaveMapping("c", broken.URL+"/page")
+
+	NewChecker(store, &http.Client{}, 4, 0).CheckAll(context.Background())
+
+	for code, want := range map[string]string{"a": storage.HealthOK, "b": storage.HealthOK, "c": storage.HealthBroken} {
+		link, _ := store.GetLink(code)
+		if link.Health.Status != want {
+			t.Errorf("link %s health = %q, want %q", code, link.Health.Status, want)
+		}
+	}
+}
+
+func TestChecker_CheckAll_Limits(t *testing.T) {
+	const hostDelay = 30 * time.Millisecond
+	var inFlight, maxInFlight atomic.Int32
+	var mu sync.Mutex
+	last := make(map[string]time.Time) // per host
+	var politeness atomic.Bool
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		mu.Lock()
+		if prev, ok := last[r.Host]; ok && time.Since(prev) < hostDelay {
+			politeness.Store(true)
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		last[r.Host] = time.Now()
+		mu.Unlock()
+	})
+
+	store := storage.NewInMemoryStore()
+	for i := 0; i < 4; i++ {
+		srv := httptest.NewServer(handler)
+		defer srv.Close()
+		for _, path := range []string{"/1", "/2", "/3"} {
+			store.SaveMapping(srv.Listener.Addr().String()+path, srv.URL+path)
+		}
+	}
+
+	NewChecker(store, &http.Client{}, 2, hostDelay).CheckAll(context.Background())
+
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("max concurrent requests = %d, want <= 2", got)
+	}
+	if politeness.Load() {
+		t.Error("requests to one host were closer together than the host delay")
+	}
+	for _, link := range store.Links() {
+		if link.Health.Status != storage.HealthOK {
+			t.Errorf("link %s not checked: %+v", link.Code, link.Health)
+		}
+	}
+}
+
+func TestChecker_Run_StopsOnCancel(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { hits.Add(1) }))
+	defer srv.Close()
+	store := storage.NewInMemoryStore()
+	store.SaveMapping("a", srv.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewChecker(store, &http.Client{}, 1, 0).Run(ctx, time.Hour)
+		close(done)
+	}()
+	for hits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after cancel")
+	}
+}
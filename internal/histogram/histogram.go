@@ -0,0 +1,110 @@
+// Package histogram records latencies in the manner of HdrHistogram: buckets
+// are exact below 256ns and log-linear above, 128 to a power of two, so every
+// recorded value is kept within 1% at a fixed cost per histogram whatever
+// the range, and quantiles stay accurate far out in the tail.
+package histogram
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+const (
+	subBits    = 7
+	subBuckets = 1 << subBits
+	// numBuckets covers every non-negative int64, whose top bit is bit 62.
+	numBuckets = (63-subBits-1)*subBuckets + 2*subBuckets
+)
+
+// Histogram counts durations. It is not safe for concurrent use.
+type Histogram struct {
+	counts [numBuckets]uint64
+	total  uint64
+	sum    float64
+	min    int64
+	max    int64
+}
+
+func New() *Histogram {
+	return &Histogram{min: math.MaxInt64}
+}
+
+func bucket(v int64) int {
+	if v < 2*subBuckets {
+		return int(v)
+	}
+	exp := bits.Len64(uint64(v)) - subBits - 1
+	return exp*subBuckets + int(v>>exp)
+}
+
+// highest returns the largest value that falls in bucket i.
+func highest(i int) int64 {
+	if i < 2*subBuckets {
+		return int64(i)
+	}
+	exp := i/subBuckets - 1
+	mantissa := int64(i - exp*subBuckets)
+	return (mantissa+1)<<exp - 1
+}
+
+// Record counts one duration; negative ones count as zero.
+func (h *Histogram) Record(d time.Duration) {
+	v := max(int64(d), 0)
+	h.counts[bucket(v)]++
+	h.total++
+	h.sum += float64(v)
+	h.min = min(h.min, v)
+	h.max = max(h.max, v)
+}
+
+// Merge adds everything recorded in o to h.
+func (h *Histogram) Merge(o *Histogram) {
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.total += o.total
+	h.sum += o.sum
+	h.min = min(h.min, o.min)
+	h.max = max(h.max, o.max)
+}
+
+func (h *Histogram) Count() uint64 {
+	return h.total
+}
+
+func (h *Histogram) Min() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.min)
+}
+
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max)
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum / float64(h.total))
+}
+
+// Quantile returns the value at or below which a fraction q of the recorded
+// durations lie, e.g. 0.999 for p99.9. Like HdrHistogram it reports the top
+// of the bucket holding that value, capped at the largest value recorded.
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.total)))
+	rank = min(max(rank, 1), h.total)
+	var seen uint64
+	for i, c := range h.counts {
+		if seen += c; seen >= rank {
+			return time.Duration(min(highest(i), h.max))
+		}
+	}
+	return time.Duration(h.max)
+}
@@ -0,0 +1,63 @@
+package histogram
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBuckets(t *testing.T) {
+	prev := -1
+	for _, v := range []int64{0, 1, 255, 256, 257, 511, 512, 1000, 1 << 20, 123456789, math.MaxInt64} {
+		i := bucket(v)
+		if i < prev || i >= numBuckets {
+			t.Fatalf("bucket(%d) = %d, previous %d, numBuckets %d", v, i, prev, numBuckets)
+		}
+		prev = i
+		if hi := highest(i); hi < v || float64(hi-v) > float64(v)/subBuckets {
+			t.Errorf("highest(bucket(%d)) = %d, want within 1%% above", v, hi)
+		}
+	}
+	for i := 0; i < numBuckets-1; i++ {
+		if bucket(highest(i)) != i || bucket(highest(i)+1) != i+1 {
+			t.Fatalf("buckets %d and %d are not contiguous", i, i+1)
+		}
+	}
+}
+
+func TestHistogram_Quantile(t *testing.T) {
+	h := New()
+	if h.Quantile(0.5) != 0 || h.Min() != 0 || h.Mean() != 0 {
+		t.Error("empty histogram should report zeros")
+	}
+	for i := 1; i <= 10000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	for _, tt := range []struct {
+		q    float64
+		want time.Duration
+	}{
+		{0.5, 5000 * time.Microsecond},
+		{0.99, 9900 * time.Microsecond},
+		{0.999, 9990 * time.Microsecond},
+		{1, 10000 * time.Microsecond},
+	} {
+		got := h.Quantile(tt.q)
+		if got < tt.want || float64(got-tt.want) > float64(tt.want)/100 {
+			t.Errorf("Quantile(%v) = %v, want %v within 1%%", tt.q, got, tt.want)
+		}
+	}
+	if h.Max() != 10*time.Millisecond || h.Min() != time.Microsecond {
+		t.Errorf("Min, Max = %v, %v", h.Min(), h.Max())
+	}
+	if mean := h.Mean(); mean < 5000*time.Microsecond || mean > 5001*time.Microsecond {
+		t.Errorf("Mean() = %v, want 5.0005ms", mean)
+	}
+
+	slow := New()
+	slow.Record(time.Second)
+	h.Merge(slow)
+	if h.Count() != 10001 || h.Quantile(1) != time.Second || h.Quantile(0.999) > 11*time.Millisecond {
+		t.Errorf("after Merge: count %d, p100 %v, p99.9 %v", h.Count(), h.Quantile(1), h.Quantile(0.999))
+	}
+}
@@ -0,0 +1,23 @@
+package http
+
+import (
+	stdhttp "net/http"
+
+	"assignment_infracloud/internal/service"
+)
+
+// admin reports whether the request may use the admin endpoints, answering
+// it with 403 if not. Admins are callers presenting one of the default
+// tenant's API keys; without API_KEYS nobody is, since the API is open and
+// every caller would be.
+func (s *Server) admin(w stdhttp.ResponseWriter, r *stdhttp.Request) bool {
+	if !s.tenants.RequireKeys() {
+		stdhttp.Error(w, "admin endpoints need API_KEYS to be configured", stdhttp.StatusForbidden)
+		return false
+	}
+	if service.TenantFrom(r.Context()) != "" {
+		stdhttp.Error(w, "forbidden", stdhttp.StatusForbidden)
+		return false
+	}
+	return true
+}
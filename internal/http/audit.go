@@ -0,0 +1,50 @@
+package http
+
+import (
+	"encoding/json"
+	stdhttp "net/http"
+	"time"
+
+	"assignment_infracloud/internal/audit"
+)
+
+type auditResponse struct {
+	Entries []audit.Entry `json:"entries"`
+}
+
+// handleAudit lets admins, the default tenant's keys, query the audit log by
+// time range (since inclusive, until exclusive, RFC 3339) and actor.
+func (s *Server) handleAudit(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	if !s.admin(w, r) {
+		return
+	}
+	if s.audit == nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	q := r.URL.Query()
+	query := audit.Query{Actor: q.Get("actor")}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &query.Since}, {"until", &query.Until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				stdhttp.Error(w, "invalid "+p.name, stdhttp.StatusBadRequest)
+				return
+			}
+			*p.dst = t
+		}
+	}
+	resp := auditResponse{Entries: s.audit.Query(query)}
+	if resp.Entries == nil {
+		resp.Entries = []audit.Entry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/audit"
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+	"assignment_infracloud/internal/tenant"
+)
+
+func TestServer_Audit(t *testing.T) {
+	auditLog := audit.New(nil)
+	shortener := service.NewInMemoryShortenerWithHooks(storage.NewInMemoryStore(), service.Hooks{
+		Changed: func(ctx context.Context, action string, link storage.Link) {
+			auditLog.Record(audit.Entry{
+				Actor:     service.ActorFrom(ctx),
+				Action:    action,
+				Target:    link.Key(),
+				RequestID: service.RequestIDFrom(ctx),
+			})
+		},
+	})
+	server := NewServerWithOptions(context.Background(), shortener, config.Config{
+		BaseURL:      "https://sho.rt",
+		ShortDomains: "acme.link",
+		Tenants:      "acme:acme.link",
+		APIKeys:      "admin-key,acme:acme-key",
+	}, Options{Audit: auditLog})
+
+	call := func(method, path, key, requestID, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		if requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	w := call(http.MethodPost, "/api/v1/shorten", "acme-key", "req-1", `{"url": "https://example.com"}`)
+	if got := w.Header().Get("X-Request-ID"); got != "req-1" {
+		t.Errorf("X-Request-ID = %q, want the client's", got)
+	}
+	var created ShortenResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	w = call(http.MethodPost, "/api/v1/links/"+created.Code+"/tags", "acme-key", "bad id\n", `{"tags": ["q3"]}`)
+	generated := w.Header().Get("X-Request-ID")
+	if generated == "" || generated == "bad id\n" {
+		t.Errorf("X-Request-ID = %q, want a generated ID", generated)
+	}
+	call(http.MethodPost, "/api/v1/shorten", "admin-key", "", `{"url": "https://example.org"}`)
+
+	if w := call(http.MethodGet, "/api/v1/admin/audit", "acme-key", "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("tenant audit query status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	query := func(params string) []audit.Entry {
+		t.Helper()
+		w := call(http.MethodGet, "/api/v1/admin/audit?"+params, "admin-key", "", "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("audit query status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var resp auditResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Entries
+	}
+	acme := "key:" + tenant.KeyID("acme-key")
+	entries := query("actor=" + acme)
+	if len(entries) != 2 {
+		t.Fatalf("audit entries for %s = %+v, want 2", acme, entries)
+	}
+	if e := entries[0]; e.Action != service.ActionCreate || e.Target != "acme@acme.link/"+created.Code || e.RequestID != "req-1" {
+		t.Errorf("entries[0] = %+v, want acme's create with the client's request ID", e)
+	}
+	if e := entries[1]; e.Action != service.ActionEdit || e.RequestID != generated {
+		t.Errorf("entries[1] = %+v, want the tag edit with the generated request ID", e)
+	}
+	if got := query("since=" + time.Now().Add(time.Hour).Format(time.RFC3339)); len(got) != 0 {
+		t.Errorf("audit entries since an hour from now = %+v, want none", got)
+	}
+	if w := call(http.MethodGet, "/api/v1/admin/audit?until=yesterday", "admin-key", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid until status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	open := NewServerWithOptions(context.Background(), shortener, config.Config{BaseURL: "https://sho.rt"}, Options{Audit: auditLog})
+	w = httptest.NewRecorder()
+	open.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/audit", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("audit query without API_KEYS status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
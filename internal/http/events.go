@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment_infracloud/internal/events"
+	"assignment_infracloud/internal/service"
+)
+
+// eventsHeartbeat is how often an idle stream gets a comment line, so
+// proxies do not time it out.
+const eventsHeartbeat = 15 * time.Second
+
+// handleEvents streams the tenant's click and create events as server-sent
+// events. ?code= and ?tag= (repeatable) narrow the stream; a Last-Event-ID
+// header resumes it from the broker's ring of recent events. Slow readers
+// miss events, or with ?slow=disconnect are disconnected so they can resume.
+func (s *Server) handleEvents(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if s.events == nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	var disconnect bool
+	switch q.Get("slow") {
+	case "", "drop":
+	case "disconnect":
+		disconnect = true
+	default:
+		stdhttp.Error(w, "invalid slow policy", stdhttp.StatusBadRequest)
+		return
+	}
+	var lastID uint64
+	resume := r.Header.Get("Last-Event-ID") != ""
+	if resume {
+		var err error
+		if lastID, err = strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64); err != nil {
+			stdhttp.Error(w, "invalid Last-Event-ID", stdhttp.StatusBadRequest)
+			return
+		}
+	}
+	filter := events.Filter{Tenant: service.TenantFrom(r.Context()), Codes: q["code"]}
+	for _, tag := range q["tag"] {
+		filter.Tags = append(filter.Tags, strings.ToLower(tag))
+	}
+
+	rc := stdhttp.NewResponseController(w)
+	// The stream outlives any server-wide write timeout.
+	rc.SetWriteDeadline(time.Time{})
+	sub := s.events.Subscribe(filter, lastID, resume, disconnect)
+	defer s.events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(stdhttp.StatusOK)
+	for _, e := range sub.Backlog {
+		writeEvent(w, e)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			writeEvent(w, e)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w stdhttp.ResponseWriter, e events.Event) {
+	data, _ := json.Marshal(e)
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
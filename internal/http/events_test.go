@@ -0,0 +1,118 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/events"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+// readEvents reads n events off an SSE stream, skipping comments.
+func readEvents(t *testing.T, sc *bufio.Scanner, n int) []events.Event {
+	t.Helper()
+	var out []events.Event
+	var id string
+	for len(out) < n && sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			var e events.Event
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+				t.Fatalf("event data: %v", err)
+			}
+			if id == "" {
+				t.Errorf("event %+v without an id line", e)
+			}
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func TestServer_Events(t *testing.T) {
+	broker := events.NewBroker(16, 16)
+	shortener := service.NewInMemoryShortenerWithHooks(storage.NewInMemoryStore(), service.Hooks{
+		Created: func(link storage.Link) { broker.Publish(events.TypeCreated, link) },
+		Clicked: func(link storage.Link) { broker.Publish(events.TypeClicked, link) },
+	})
+	server := NewServerWithOptions(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"}, Options{Events: broker})
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	ctx := context.Background()
+	tagged, _ := shortener.ShortenWithOptions(ctx, "https://example.com/a", service.Options{Tags: []string{"launch"}})
+	plain, _ := shortener.Shorten(ctx, "https://example.com/b")
+
+	open := func(query, lastID string) (*bufio.Scanner, func()) {
+		t.Helper()
+		reqCtx, cancel := context.WithCancel(ctx)
+		req, _ := http.NewRequestWithContext(reqCtx, http.MethodGet, srv.URL+"/api/v1/events"+query, nil)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatalf("GET events: %v", err)
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Content-Type = %q, want text/event-stream", ct)
+		}
+		return bufio.NewScanner(resp.Body), func() {
+			cancel()
+			resp.Body.Close()
+		}
+	}
+
+	// Resuming from the first event replays the second create.
+	stream, closeStream := open("", "1")
+	got := readEvents(t, stream, 1)
+	if len(got) != 1 || got[0].ID != 2 || got[0].Code != plain || got[0].Type != events.TypeCreated {
+		t.Errorf("replayed %+v, want the create of %s", got, plain)
+	}
+	closeStream()
+
+	stream, closeStream = open("?tag=launch", "")
+	defer closeStream()
+	visitor := srv.Client()
+	visitor.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	for _, code := range []string{plain, tagged} {
+		resp, err := visitor.Get(srv.URL + "/" + code)
+		if err != nil {
+			t.Fatalf("visit %s: %v", code, err)
+		}
+		resp.Body.Close()
+	}
+	got = readEvents(t, stream, 1)
+	if len(got) != 1 || got[0].Code != tagged || got[0].Type != events.TypeClicked || got[0].Clicks != 1 {
+		t.Errorf("streamed %+v, want only the click on %s", got, tagged)
+	}
+}
+
+func TestServer_Events_BadRequests(t *testing.T) {
+	server := NewServerWithOptions(context.Background(), service.NewInMemoryShortener(storage.NewInMemoryStore()),
+		config.Config{BaseURL: "http://localhost:8080"}, Options{Events: events.NewBroker(1, 1)})
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/events?slow=sometimes", nil),
+		func() *http.Request {
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+			r.Header.Set("Last-Event-ID", "latest")
+			return r
+		}(),
+	} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("GET %s = %d, want %d", req.URL, w.Code, http.StatusBadRequest)
+		}
+	}
+}
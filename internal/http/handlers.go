@@ -2,25 +2,121 @@ package http
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	stdhttp "net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"assignment_infracloud/internal/audit"
 	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/events"
 	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/signing"
+	"assignment_infracloud/internal/storage"
+	"assignment_infracloud/internal/tenant"
+	"assignment_infracloud/internal/webhook"
 )
 
 type Server struct {
 	mux       *stdhttp.ServeMux
 	shortener service.Shortener
 	cfg       config.Config
+
+	cookieKey      []byte
+	unlockTTL      time.Duration
+	unlockAttempts *attemptLimiter
+	keyring        *signing.Keyring
+	regionHeader   string
+
+	// defaultHost is BaseURL's host; domains holds the extra branded hosts.
+	defaultHost string
+	domains     map[string]bool
+	tenants     *tenant.Registry
+
+	audit    *audit.Log
+	webhooks *webhook.Dispatcher
+	events   *events.Broker
+
+	// ready is reported at /readyz; closing ends open event streams once
+	// closeOnce has closed it.
+	ready     atomic.Bool
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// Options are the optional subsystems a Server exposes through the API.
+type Options struct {
+	// Audit is served to admins at /api/v1/admin/audit. Recording into it
+	// is up to the caller.
+	Audit *audit.Log
+	// Webhooks is managed at /api/v1/webhooks. Publishing events to it is
+	// up to the caller.
+	Webhooks *webhook.Dispatcher
+	// Events is streamed at /api/v1/events. Publishing to it is up to the
+	// caller.
+	Events *events.Broker
 }
 
 func NewServer(ctx context.Context, shortener service.Shortener, cfg config.Config) *Server {
+	return NewServerWithOptions(ctx, shortener, cfg, Options{})
+}
+
+// NewServerWithOptions is NewServer with optional subsystems.
+func NewServerWithOptions(ctx context.Context, shortener service.Shortener, cfg config.Config, opts Options) *Server {
 	s := &Server{
-		mux:       stdhttp.NewServeMux(),
-		shortener: shortener,
-		cfg:       cfg,
+		audit:          opts.Audit,
+		webhooks:       opts.Webhooks,
+		events:         opts.Events,
+		mux:            stdhttp.NewServeMux(),
+		shortener:      shortener,
+		cfg:            cfg,
+		cookieKey:      []byte(cfg.CookieSecret),
+		unlockTTL:      cfg.UnlockTTL,
+		regionHeader:   stdhttp.CanonicalHeaderKey(cfg.RegionHeader),
+		unlockAttempts: newAttemptLimiter(maxUnlockFailures, unlockWindow),
+		closing:        make(chan struct{}),
+	}
+	s.ready.Store(true)
+	if len(s.cookieKey) == 0 {
+		s.cookieKey = make([]byte, 32)
+		if _, err := rand.Read(s.cookieKey); err != nil {
+			log.Fatalf("generate cookie key: %v", err)
+		}
+	}
+	if s.unlockTTL <= 0 {
+		s.unlockTTL = 10 * time.Minute
+	}
+	if s.regionHeader == "" {
+		s.regionHeader = "X-Country"
+	}
+	keyring, err := signing.ParseKeyring(cfg.SigningKeys)
+	if err != nil {
+		log.Fatalf("signing keys: %v", err)
+	}
+	s.keyring = keyring
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		log.Fatalf("base url: %v", err)
+	}
+	s.defaultHost = strings.ToLower(base.Host)
+	domains, err := config.ParseDomains(cfg.ShortDomains)
+	if err != nil {
+		log.Fatalf("short domains: %v", err)
+	}
+	s.domains = make(map[string]bool, len(domains))
+	for _, d := range domains {
+		s.domains[d] = true
+	}
+	if s.tenants, err = tenant.Parse(cfg.Tenants, cfg.APIKeys); err != nil {
+		log.Fatalf("tenants: %v", err)
 	}
 	s.routes()
 	return s
@@ -29,27 +125,196 @@ func NewServer(ctx context.Context, shortener service.Shortener, cfg config.Conf
 func (s *Server) routes() {
 	s.mux.HandleFunc("/api/v1/shorten", s.handleShorten)
 	s.mux.HandleFunc("/api/v1/metrics", s.handleMetrics)
+	s.mux.HandleFunc("/api/v1/links", s.handleLinks)
+	s.mux.HandleFunc("/api/v1/links/{code}", s.handleLink)
+	s.mux.HandleFunc("/api/v1/links/{code}/sign", s.handleSign)
+	s.mux.HandleFunc("/api/v1/links/{code}/locales", s.handleLocales)
+	s.mux.HandleFunc("/api/v1/links/{code}/stats", s.handleStats)
+	s.mux.HandleFunc("/api/v1/links/{code}/schedule", s.handleSchedule)
+	s.mux.HandleFunc("/api/v1/links/{code}/schedule/{id}", s.handleScheduleEntry)
+	s.mux.HandleFunc("/api/v1/links/{code}/qr", s.handleQR)
+	s.mux.HandleFunc("/api/v1/links/{code}/history", s.handleHistory)
+	s.mux.HandleFunc("/api/v1/links/{code}/rollback", s.handleRollback)
+	s.mux.HandleFunc("/api/v1/links/{code}/tags", s.handleTags)
+	s.mux.HandleFunc("/api/v1/links/{code}/tags/{tag...}", s.handleTag)
+	s.mux.HandleFunc("/api/v1/tags/{tag...}", s.handleTagStats)
+	s.mux.HandleFunc("/api/v1/campaigns/{campaign...}", s.handleCampaignStats)
+	s.mux.HandleFunc("/api/v1/admin/audit", s.handleAudit)
+	s.mux.HandleFunc("/api/v1/admin/export", s.handleExport)
+	s.mux.HandleFunc("/api/v1/admin/import", s.handleImport)
+	s.mux.HandleFunc("/api/v1/events", s.handleEvents)
+	s.mux.HandleFunc("/api/v1/webhooks", s.handleWebhooks)
+	s.mux.HandleFunc("/api/v1/webhooks/dead-letters", s.handleDeadLetters)
+	s.mux.HandleFunc("/api/v1/webhooks/{id}", s.handleWebhook)
+	s.mux.HandleFunc("/readyz", s.handleReady)
 	s.mux.HandleFunc("/", s.handleResolve)
 }
 
+// ServeHTTP scopes the request to a tenant and short domain before routing
+// it. Visits use the Host they arrive on, and the tenant owning it; unknown
+// hosts fall back to the default domain. API calls are scoped to the tenant
+// of their API key when keys are configured, and may name one of its
+// domains with ?domain=. Every request gets an ID, taken from X-Request-ID
+// when the client sends a usable one, and echoed back in that header.
 func (s *Server) ServeHTTP(w stdhttp.ResponseWriter, r *stdhttp.Request) {
-	s.mux.ServeHTTP(w, r)
+	requestID := r.Header.Get("X-Request-ID")
+	if !validRequestID(requestID) {
+		requestID = newRequestID()
+	}
+	w.Header().Set("X-Request-ID", requestID)
+	domain, _ := s.domainFor(r.Host)
+	tenantID := s.tenants.Owner(domain)
+	var actor string
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		if s.tenants.RequireKeys() {
+			key := apiKey(r)
+			id, err := s.tenants.ForKey(key)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+				stdhttp.Error(w, "invalid api key", stdhttp.StatusUnauthorized)
+				return
+			}
+			tenantID, actor = id, "key:"+tenant.KeyID(key)
+		}
+		if s.tenants.Owner(domain) != tenantID {
+			domain = s.tenants.PrimaryDomain(tenantID)
+		}
+		if d := r.URL.Query().Get("domain"); d != "" {
+			var ok bool
+			if domain, ok = s.tenantDomain(tenantID, d); !ok {
+				stdhttp.Error(w, "unknown domain", stdhttp.StatusBadRequest)
+				return
+			}
+		}
+	}
+	ctx := service.WithDomain(service.WithTenant(r.Context(), tenantID), domain)
+	ctx = service.WithRequestID(ctx, requestID)
+	if actor != "" {
+		ctx = service.WithActor(ctx, actor)
+	}
+	s.mux.ServeHTTP(w, r.WithContext(ctx))
 }
 
-type shortenRequest struct {
-	URL string `json:"url"`
+// validRequestID accepts client-supplied IDs of up to 128 printable ASCII
+// characters, so they cannot inject anything into logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatalf("generate request id: %v", err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// apiKey returns the key from an "Authorization: Bearer" or X-API-Key
+// header.
+func apiKey(r *stdhttp.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// tenantDomain maps a host named in an API call to a domain, failing for
+// unknown hosts and for domains owned by another tenant.
+func (s *Server) tenantDomain(tenantID, host string) (string, bool) {
+	domain, ok := s.domainFor(host)
+	if !ok || s.tenants.Owner(domain) != tenantID {
+		return "", false
+	}
+	return domain, true
+}
+
+// domainFor maps a host to the domain its links are stored under: "" for
+// the default domain, the host itself for a branded one. It reports false
+// for hosts that are neither.
+func (s *Server) domainFor(host string) (string, bool) {
+	host = strings.ToLower(host)
+	for _, h := range []string{host, stripPort(host)} {
+		if h == s.defaultHost || h == stripPort(s.defaultHost) {
+			return "", true
+		}
+		if s.domains[h] {
+			return h, true
+		}
+	}
+	return "", false
 }
 
-type shortenResponse struct {
+// shortURL is the public URL of code on domain.
+func (s *Server) shortURL(domain, code string) string {
+	if domain == "" {
+		return s.cfg.BaseURL + "/" + code
+	}
+	scheme, _, _ := strings.Cut(s.cfg.BaseURL, "://")
+	return scheme + "://" + domain + "/" + code
+}
+
+func stripPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// ShortenRequest is the body of POST /api/v1/shorten.
+type ShortenRequest struct {
+	URL              string       `json:"url"`
+	Password         string       `json:"password,omitempty"`
+	MaxClicks        int          `json:"max_clicks,omitempty"`
+	RequireSignature bool         `json:"require_signature,omitempty"`
+	ForwardQuery     bool         `json:"forward_query,omitempty"`
+	ForwardPath      bool         `json:"forward_path,omitempty"`
+	QueryConflict    string       `json:"query_conflict,omitempty"`
+	UTM              *utm         `json:"utm,omitempty"`
+	DeviceRules      []deviceRule `json:"device_rules,omitempty"`
+	LocaleRules      []localeRule `json:"locale_rules,omitempty"`
+	Variants         []variant    `json:"variants,omitempty"`
+	Interstitial     bool         `json:"interstitial,omitempty"`
+	Tags             []string     `json:"tags,omitempty"`
+	Campaign         string       `json:"campaign,omitempty"`
+	// Domain is one of the configured short domains; empty for the default.
+	Domain string `json:"domain,omitempty"`
+}
+
+type deviceRule struct {
+	Platform string `json:"platform"`
+	URL      string `json:"url"`
+}
+
+type utm struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// ShortenResponse is returned by POST /api/v1/shorten.
+type ShortenResponse struct {
 	ShortURL string `json:"short_url"`
 	Code     string `json:"code"`
+	Domain   string `json:"domain,omitempty"`
 }
 
-type metricsResponse struct {
-	TopDomains []domainStat `json:"top_domains"`
+// MetricsResponse is returned by GET /api/v1/metrics.
+type MetricsResponse struct {
+	TopDomains []DomainStat `json:"top_domains"`
 }
 
-type domainStat struct {
+// DomainStat counts the links shortened to one destination domain.
+type DomainStat struct {
 	Domain string `json:"domain"`
 	Count  int    `json:"count"`
 }
@@ -59,24 +324,65 @@ func (s *Server) handleShorten(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
 		return
 	}
-	var req shortenRequest
+	var req ShortenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		stdhttp.Error(w, "invalid json", stdhttp.StatusBadRequest)
 		return
 	}
-	code, err := s.shortener.Shorten(r.Context(), req.URL)
+	ctx := r.Context()
+	if req.Domain != "" {
+		domain, ok := s.tenantDomain(service.TenantFrom(ctx), req.Domain)
+		if !ok {
+			stdhttp.Error(w, "unknown domain", stdhttp.StatusBadRequest)
+			return
+		}
+		ctx = service.WithDomain(ctx, domain)
+	}
+	if req.RequireSignature && (s.keyring == nil || !s.tenants.RequireKeys()) {
+		stdhttp.Error(w, "signed links need SIGNING_KEYS and API_KEYS", stdhttp.StatusBadRequest)
+		return
+	}
+	opts := service.Options{
+		Password:         req.Password,
+		MaxClicks:        req.MaxClicks,
+		RequireSignature: req.RequireSignature,
+		Interstitial:     req.Interstitial,
+		Tags:             req.Tags,
+		Campaign:         req.Campaign,
+		Passthrough: storage.Passthrough{
+			Query:         req.ForwardQuery,
+			Path:          req.ForwardPath,
+			QueryConflict: req.QueryConflict,
+		},
+	}
+	if req.UTM != nil {
+		opts.UTM = storage.UTM(*req.UTM)
+	}
+	for _, rule := range req.DeviceRules {
+		opts.DeviceRules = append(opts.DeviceRules, storage.DeviceRule(rule))
+	}
+	opts.LocaleRules = toLocaleRules(req.LocaleRules)
+	for _, v := range req.Variants {
+		opts.Variants = append(opts.Variants, storage.Variant{Name: v.Name, URL: v.URL, Weight: v.Weight})
+	}
+	code, err := s.shortener.ShortenWithOptions(ctx, req.URL, opts)
 	if err != nil {
-		if err == service.ErrInvalidURL {
-			stdhttp.Error(w, "invalid url", stdhttp.StatusBadRequest)
+		if err == service.ErrInvalidURL || err == service.ErrInvalidMaxClicks ||
+			err == service.ErrInvalidConflict || err == service.ErrInvalidRule ||
+			err == service.ErrInvalidLocale || err == service.ErrInvalidVariant ||
+			err == service.ErrInvalidTag || err == service.ErrInvalidCampaign {
+			stdhttp.Error(w, err.Error(), stdhttp.StatusBadRequest)
 			return
 		}
 		log.Printf("shorten error: %v", err)
 		stdhttp.Error(w, "internal error", stdhttp.StatusInternalServerError)
 		return
 	}
-	resp := shortenResponse{
-		ShortURL: s.cfg.BaseURL + "/" + code,
+	domain := service.DomainFrom(ctx)
+	resp := ShortenResponse{
+		ShortURL: s.shortURL(domain, code),
 		Code:     code,
+		Domain:   domain,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
@@ -91,15 +397,15 @@ func (s *Server) handleMetrics(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 	domainStats := s.shortener.GetTopDomains(r.Context(), 3)
 
 	// Convert to response format
-	topDomains := make([]domainStat, len(domainStats))
+	topDomains := make([]DomainStat, len(domainStats))
 	for i, stat := range domainStats {
-		topDomains[i] = domainStat{
+		topDomains[i] = DomainStat{
 			Domain: stat.Domain,
 			Count:  stat.Count,
 		}
 	}
 
-	resp := metricsResponse{
+	resp := MetricsResponse{
 		TopDomains: topDomains,
 	}
 
@@ -113,11 +419,56 @@ func (s *Server) handleResolve(w stdhttp.ResponseWriter, r *stdhttp.Request) {
 		w.Write([]byte("URL Shortener Service Healthcheck service is running"))
 		return
 	}
-	code := r.URL.Path[1:]
-	longURL, err := s.shortener.Resolve(r.Context(), code)
+	code, suffix, hasSuffix := strings.Cut(r.URL.Path[1:], "/")
+	code, preview := wantsPreview(r, code)
+	link, err := s.shortener.Lookup(r.Context(), code)
+	if err != nil || (hasSuffix && !link.Passthrough.Path) {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	params, err := s.verifySigned(r, link)
+	if err != nil {
+		stdhttp.Error(w, "invalid signature", stdhttp.StatusForbidden)
+		return
+	}
+	if link.PasswordHash != "" && !s.unlocked(r, link) {
+		s.handleUnlock(w, r, link)
+		return
+	}
+	if preview {
+		if remaining, limited := link.Remaining(); limited && remaining == 0 {
+			stdhttp.Error(w, "link no longer available", stdhttp.StatusGone)
+			return
+		}
+		s.renderPreview(w, link)
+		return
+	}
+	longURL, scheduled, err := s.shortener.Resolve(r.Context(), code)
+	if errors.Is(err, service.ErrExhausted) {
+		stdhttp.Error(w, "link no longer available", stdhttp.StatusGone)
+		return
+	}
 	if err != nil {
 		stdhttp.NotFound(w, r)
 		return
 	}
-	stdhttp.Redirect(w, r, longURL, 301)
+	longURL, variant := s.target(w, r, link, longURL, scheduled)
+	if variant != "" {
+		s.shortener.RecordVariant(r.Context(), code, variant)
+	}
+	if params == nil && link.Passthrough.Query {
+		params = r.URL.Query()
+	}
+	if longURL, err = redirectTarget(link, longURL, suffix, params, s.shortener.Now()); err != nil {
+		log.Printf("resolve %s: %v", code, err)
+		stdhttp.Error(w, "internal error", stdhttp.StatusInternalServerError)
+		return
+	}
+	if link.Interstitial {
+		renderInterstitial(w, longURL)
+		return
+	}
+	// Temporary redirect: a cached 301 would let browsers skip any check
+	// done here, such as the password gate.
+	stdhttp.Redirect(w, r, longURL, stdhttp.StatusFound)
 }
@@ -43,7 +43,7 @@ func TestServer_HandleShorten_ValidRequest(t *testing.T) {
 	}
 	server := NewServer(context.Background(), shortener, cfg)
 
-	reqBody := shortenRequest{URL: "https://example.com/test"}
+	reqBody := ShortenRequest{URL: "https://example.com/test"}
 	reqJSON, _ := json.Marshal(reqBody)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", bytes.NewBuffer(reqJSON))
@@ -56,7 +56,7 @@ func TestServer_HandleShorten_ValidRequest(t *testing.T) {
 		t.Errorf("handleShorten() status = %d, want %d", w.Code, http.StatusOK)
 	}
 
-	var resp shortenResponse
+	var resp ShortenResponse
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
@@ -117,7 +117,7 @@ func TestServer_HandleShorten_InvalidURL(t *testing.T) {
 	cfg := config.Config{HTTPPort: "8080", BaseURL: "http://localhost:8080"}
 	server := NewServer(context.Background(), shortener, cfg)
 
-	reqBody := shortenRequest{URL: "not-a-valid-url"}
+	reqBody := ShortenRequest{URL: "not-a-valid-url"}
 	reqJSON, _ := json.Marshal(reqBody)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", bytes.NewBuffer(reqJSON))
@@ -138,7 +138,7 @@ func TestServer_HandleShorten_DuplicateURL(t *testing.T) {
 	server := NewServer(context.Background(), shortener, cfg)
 
 	url := "https://example.com/duplicate"
-	reqBody := shortenRequest{URL: url}
+	reqBody := ShortenRequest{URL: url}
 	reqJSON, _ := json.Marshal(reqBody)
 
 	// First request
@@ -147,7 +147,7 @@ func TestServer_HandleShorten_DuplicateURL(t *testing.T) {
 	w1 := httptest.NewRecorder()
 	server.handleShorten(w1, req1)
 
-	var resp1 shortenResponse
+	var resp1 ShortenResponse
 	json.NewDecoder(w1.Body).Decode(&resp1)
 
 	// Second request with same URL
@@ -156,7 +156,7 @@ func TestServer_HandleShorten_DuplicateURL(t *testing.T) {
 	w2 := httptest.NewRecorder()
 	server.handleShorten(w2, req2)
 
-	var resp2 shortenResponse
+	var resp2 ShortenResponse
 	json.NewDecoder(w2.Body).Decode(&resp2)
 
 	// Should return same code
@@ -197,7 +197,7 @@ func TestServer_HandleMetrics_ValidRequest(t *testing.T) {
 		t.Errorf("handleMetrics() status = %d, want %d", w.Code, http.StatusOK)
 	}
 
-	var resp metricsResponse
+	var resp MetricsResponse
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
@@ -245,7 +245,7 @@ func TestServer_HandleMetrics_EmptyStats(t *testing.T) {
 		t.Errorf("handleMetrics() status = %d, want %d", w.Code, http.StatusOK)
 	}
 
-	var resp metricsResponse
+	var resp MetricsResponse
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
@@ -311,7 +311,7 @@ func TestServer_ServeHTTP(t *testing.T) {
 	server := NewServer(context.Background(), shortener, cfg)
 
 	// Test shorten endpoint
-	reqBody := shortenRequest{URL: "https://example.com/test"}
+	reqBody := ShortenRequest{URL: "https://example.com/test"}
 	reqJSON, _ := json.Marshal(reqBody)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", bytes.NewBuffer(reqJSON))
@@ -352,7 +352,7 @@ func BenchmarkServer_HandleShorten(b *testing.B) {
 	cfg := config.Config{HTTPPort: "8080", BaseURL: "http://localhost:8080"}
 	server := NewServer(context.Background(), shortener, cfg)
 
-	reqBody := shortenRequest{URL: "https://example.com/benchmark"}
+	reqBody := ShortenRequest{URL: "https://example.com/benchmark"}
 	reqJSON, _ := json.Marshal(reqBody)
 	buf := bytes.NewBuffer(reqJSON)
 	b.ResetTimer()
@@ -384,3 +384,231 @@ func BenchmarkServer_HandleMetrics(b *testing.B) {
 		server.handleMetrics(w, req)
 	}
 }
+
+func TestServer_HandleResolve_Passthrough(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := service.NewInMemoryShortener(store)
+	cfg := config.Config{HTTPPort: "8080", BaseURL: "http://localhost:8080"}
+	server := NewServer(context.Background(), shortener, cfg)
+	ctx := context.Background()
+
+	plain, _ := shortener.Shorten(ctx, "https://example.com/docs?lang=en")
+	forwarding, _ := shortener.ShortenWithOptions(ctx, "https://example.com/docs?lang=en", service.Options{
+		Passthrough: storage.Passthrough{Query: true, Path: true, QueryConflict: storage.ConflictKeep},
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		want   string
+	}{
+		{"plain ignores query", "/" + plain + "?utm_source=x", http.StatusFound, "https://example.com/docs?lang=en"},
+		{"plain rejects suffix", "/" + plain + "/extra", http.StatusNotFound, ""},
+		{"forward query", "/" + forwarding + "?utm_source=x", http.StatusFound, "https://example.com/docs?lang=en&utm_source=x"},
+		{"conflict keeps destination", "/" + forwarding + "?lang=de", http.StatusFound, "https://example.com/docs?lang=en"},
+		{"forward path", "/" + forwarding + "/guide/intro", http.StatusFound, "https://example.com/docs/guide/intro?lang=en"},
+		{"forward path and query", "/" + forwarding + "/guide?utm_source=x", http.StatusFound, "https://example.com/docs/guide?lang=en&utm_source=x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if loc := w.Header().Get("Location"); loc != tt.want {
+				t.Errorf("Location = %s, want %s", loc, tt.want)
+			}
+		})
+	}
+}
+
+func TestServer_HandleShorten_InvalidConflict(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := service.NewInMemoryShortener(store)
+	cfg := config.Config{HTTPPort: "8080", BaseURL: "http://localhost:8080"}
+	server := NewServer(context.Background(), shortener, cfg)
+
+	body := bytes.NewBufferString(`{"url":"https://example.com","forward_query":true,"query_conflict":"merge"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shorten", body)
+	w := httptest.NewRecorder()
+	server.handleShorten(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("handleShorten() status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServer_Domains(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := service.NewInMemoryShortener(store)
+	server := NewServer(context.Background(), shortener, config.Config{
+		BaseURL:      "https://sho.rt",
+		ShortDomains: "go.acme.com,acme.link",
+	})
+
+	shorten := func(body string) (int, ShortenResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/shorten", bytes.NewBufferString(body)))
+		var resp ShortenResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		return w.Code, resp
+	}
+
+	_, plain := shorten(`{"url": "https://example.com/a"}`)
+	status, branded := shorten(`{"url": "https://example.com/b", "domain": "acme.link"}`)
+	if status != http.StatusOK {
+		t.Fatalf("shorten on acme.link status = %d, want %d", status, http.StatusOK)
+	}
+	if plain.ShortURL != "https://sho.rt/"+plain.Code || plain.Domain != "" {
+		t.Errorf("default short_url = %q (domain %q), want https://sho.rt/%s", plain.ShortURL, plain.Domain, plain.Code)
+	}
+	if branded.ShortURL != "https://acme.link/"+branded.Code || branded.Domain != "acme.link" {
+		t.Errorf("branded short_url = %q, want https://acme.link/%s", branded.ShortURL, branded.Code)
+	}
+	if status, _ := shorten(`{"url": "https://example.com/c", "domain": "evil.example"}`); status != http.StatusBadRequest {
+		t.Errorf("shorten on unknown domain status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	// The same code can exist independently on another domain.
+	store.SaveLink(storage.Link{Code: branded.Code, Domain: "go.acme.com", URL: "https://example.com/go"})
+
+	resolve := func(host, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+	if w := resolve("acme.link", "/"+branded.Code); w.Code != http.StatusFound || w.Header().Get("Location") != "https://example.com/b" {
+		t.Errorf("acme.link resolve = %d %q, want 302 to /b", w.Code, w.Header().Get("Location"))
+	}
+	if w := resolve("ACME.link:443", "/"+branded.Code); w.Code != http.StatusFound {
+		t.Errorf("acme.link with port resolve = %d, want 302", w.Code)
+	}
+	if w := resolve("go.acme.com", "/"+branded.Code); w.Header().Get("Location") != "https://example.com/go" {
+		t.Errorf("go.acme.com resolve = %d %q, want its own link", w.Code, w.Header().Get("Location"))
+	}
+	if w := resolve("sho.rt", "/"+branded.Code); w.Code != http.StatusNotFound {
+		t.Errorf("default domain resolve of an acme.link code = %d, want 404", w.Code)
+	}
+	if w := resolve("sho.rt", "/"+plain.Code); w.Code != http.StatusFound {
+		t.Errorf("default domain resolve = %d, want 302", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/"+branded.Code+"?domain=acme.link", nil))
+	var link LinkResponse
+	json.NewDecoder(w.Body).Decode(&link)
+	if w.Code != http.StatusOK || link.ShortURL != branded.ShortURL {
+		t.Errorf("GET link on acme.link = %d %q, want 200 %q", w.Code, link.ShortURL, branded.ShortURL)
+	}
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links?domain=nope.example", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("GET links on unknown domain = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServer_TenantIsolation(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{
+		BaseURL:      "https://sho.rt",
+		ShortDomains: "go.acme.com,acme.link,glbx.io",
+		Tenants:      "acme:go.acme.com acme.link,globex:glbx.io",
+		APIKeys:      "acme:acme-key,globex:globex-key",
+	})
+
+	call := func(method, path, key, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	w := call(http.MethodPost, "/api/v1/shorten", "acme-key", `{"url": "https://acme.example.com/launch"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("acme shorten status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var acme ShortenResponse
+	json.NewDecoder(w.Body).Decode(&acme)
+	if acme.ShortURL != "https://go.acme.com/"+acme.Code {
+		t.Errorf("acme short_url = %q, want it on acme's primary domain", acme.ShortURL)
+	}
+
+	t.Run("api requires a key", func(t *testing.T) {
+		if w := call(http.MethodGet, "/api/v1/metrics", "", ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("no key status = %d, want %d", w.Code, http.StatusUnauthorized)
+		}
+		if w := call(http.MethodGet, "/api/v1/metrics", "wrong", ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("bad key status = %d, want %d", w.Code, http.StatusUnauthorized)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links", nil)
+		req.Header.Set("X-API-Key", "acme-key")
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("X-API-Key status = %d, want %d", w.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("no cross-tenant reads", func(t *testing.T) {
+		for _, path := range []string{
+			"/api/v1/links/" + acme.Code,
+			"/api/v1/links/" + acme.Code + "/stats",
+			"/api/v1/links/" + acme.Code + "/qr",
+			"/api/v1/links/" + acme.Code + "/schedule",
+		} {
+			if w := call(http.MethodGet, path, "globex-key", ""); w.Code != http.StatusNotFound {
+				t.Errorf("globex GET %s status = %d, want %d", path, w.Code, http.StatusNotFound)
+			}
+			if w := call(http.MethodGet, path, "acme-key", ""); w.Code != http.StatusOK {
+				t.Errorf("acme GET %s status = %d, want %d", path, w.Code, http.StatusOK)
+			}
+		}
+		if w := call(http.MethodGet, "/api/v1/links/"+acme.Code+"?domain=go.acme.com", "globex-key", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("globex naming acme's domain status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+
+		var list LinksResponse
+		json.NewDecoder(call(http.MethodGet, "/api/v1/links", "globex-key", "").Body).Decode(&list)
+		if len(list.Links) != 0 {
+			t.Errorf("globex lists %d links, want 0", len(list.Links))
+		}
+		var metrics MetricsResponse
+		json.NewDecoder(call(http.MethodGet, "/api/v1/metrics", "globex-key", "").Body).Decode(&metrics)
+		if len(metrics.TopDomains) != 0 {
+			t.Errorf("globex metrics = %v, want none", metrics.TopDomains)
+		}
+		json.NewDecoder(call(http.MethodGet, "/api/v1/metrics", "acme-key", "").Body).Decode(&metrics)
+		if len(metrics.TopDomains) != 1 || metrics.TopDomains[0].Domain != "acme.example.com" {
+			t.Errorf("acme metrics = %v, want acme.example.com", metrics.TopDomains)
+		}
+	})
+
+	t.Run("shorten cannot target another tenant's domain", func(t *testing.T) {
+		w := call(http.MethodPost, "/api/v1/shorten", "globex-key", `{"url": "https://globex.example.com", "domain": "acme.link"}`)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("visits resolve by host", func(t *testing.T) {
+		for host, want := range map[string]int{"go.acme.com": http.StatusFound, "glbx.io": http.StatusNotFound, "sho.rt": http.StatusNotFound} {
+			req := httptest.NewRequest(http.MethodGet, "/"+acme.Code, nil)
+			req.Host = host
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("visit on %s status = %d, want %d", host, w.Code, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	stdhttp "net/http"
+	"time"
+
+	"assignment_infracloud/internal/storage"
+)
+
+type historyResponse struct {
+	Versions []versionResponse `json:"versions"`
+}
+
+type versionResponse struct {
+	Version      int           `json:"version"`
+	Actor        string        `json:"actor,omitempty"`
+	At           time.Time     `json:"at"`
+	RestoredFrom int           `json:"restored_from,omitempty"`
+	Deleted      bool          `json:"deleted,omitempty"`
+	Changes      []fieldChange `json:"changes"`
+}
+
+// fieldChange is one setting's old and new value. From is left out for the
+// link's first version.
+type fieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from,omitempty"`
+	To    any    `json:"to"`
+}
+
+type passthroughSetting struct {
+	ForwardQuery  bool   `json:"forward_query"`
+	ForwardPath   bool   `json:"forward_path"`
+	QueryConflict string `json:"query_conflict,omitempty"`
+}
+
+type rollbackRequest struct {
+	Version int `json:"version"`
+}
+
+// settingValues renders a link's versioned settings the way the API shows
+// them, keyed by the names storage.Version.Changed uses. Passwords are only
+// shown as set or not.
+func settingValues(link storage.Link) map[string]any {
+	var u *utm
+	if link.UTM != (storage.UTM{}) {
+		t := utm(link.UTM)
+		u = &t
+	}
+	var rules []deviceRule
+	for _, rule := range link.DeviceRules {
+		rules = append(rules, deviceRule(rule))
+	}
+	return map[string]any{
+		"url":               link.URL,
+		"password":          link.PasswordHash != "",
+		"max_clicks":        link.MaxClicks,
+		"require_signature": link.RequireSignature,
+		"passthrough": passthroughSetting{
+			ForwardQuery:  link.Passthrough.Query,
+			ForwardPath:   link.Passthrough.Path,
+			QueryConflict: link.Passthrough.QueryConflict,
+		},
+		"utm":          u,
+		"device_rules": rules,
+		"locale_rules": fromLocaleRules(link.LocaleRules),
+		"variants":     fromVariants(link.Variants),
+		"schedule":     fromSchedule(link.Schedule),
+		"interstitial": link.Interstitial,
+		"tags":         link.Tags,
+		"campaign":     link.Campaign,
+	}
+}
+
+func newHistoryResponse(versions []storage.Version) historyResponse {
+	resp := historyResponse{Versions: make([]versionResponse, 0, len(versions))}
+	var prev map[string]any
+	for _, v := range versions {
+		cur := settingValues(v.Link)
+		out := versionResponse{
+			Version:      v.Number,
+			Actor:        v.Actor,
+			At:           v.At,
+			RestoredFrom: v.RestoredFrom,
+			Deleted:      v.Deleted,
+			Changes:      []fieldChange{},
+		}
+		for _, field := range v.Changed {
+			change := fieldChange{Field: field, To: cur[field]}
+			if prev != nil {
+				change.From = prev[field]
+			}
+			out.Changes = append(out.Changes, change)
+		}
+		resp.Versions = append(resp.Versions, out)
+		prev = cur
+	}
+	return resp
+}
+
+// handleHistory lists every version of a link with what changed, who changed
+// it and when.
+func (s *Server) handleHistory(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	versions, err := s.shortener.History(r.Context(), r.PathValue("code"))
+	if err != nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newHistoryResponse(versions))
+}
+
+// handleRollback restores the settings of an earlier version of a link.
+func (s *Server) handleRollback(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodPost {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	code := r.PathValue("code")
+	var req rollbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Version < 1 {
+		stdhttp.Error(w, "invalid version", stdhttp.StatusBadRequest)
+		return
+	}
+	link, err := s.shortener.Rollback(r.Context(), code, req.Version)
+	if errors.Is(err, storage.ErrNotFound) {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("rollback %s: %v", code, err)
+		stdhttp.Error(w, "internal error", stdhttp.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.newLinkResponse(link))
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+	"assignment_infracloud/internal/tenant"
+)
+
+func TestServer_HistoryAndRollback(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080", APIKeys: "alice-key,bob-key"})
+
+	call := func(method, path, key, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	w := call(http.MethodPost, "/api/v1/shorten", "alice-key", `{"url": "https://example.com/v1", "tags": ["launch"]}`)
+	var created ShortenResponse
+	json.NewDecoder(w.Body).Decode(&created)
+	code := created.Code
+	call(http.MethodPost, "/api/v1/links/"+code+"/tags", "bob-key", `{"tags": ["q3"]}`)
+
+	var history historyResponse
+	json.NewDecoder(call(http.MethodGet, "/api/v1/links/"+code+"/history", "alice-key", "").Body).Decode(&history)
+	if len(history.Versions) != 2 {
+		t.Fatalf("history = %+v, want 2 versions", history.Versions)
+	}
+	if v := history.Versions[0]; v.Actor != "key:"+tenant.KeyID("alice-key") || len(v.Changes) != 2 || v.Changes[0].From != nil {
+		t.Errorf("version 1 = %+v, want alice creating the link with url and tags", v)
+	}
+	v := history.Versions[1]
+	if v.Actor != "key:"+tenant.KeyID("bob-key") || len(v.Changes) != 1 || v.Changes[0].Field != "tags" {
+		t.Fatalf("version 2 = %+v, want bob changing tags", v)
+	}
+	from, _ := v.Changes[0].From.([]any)
+	to, _ := v.Changes[0].To.([]any)
+	if len(from) != 1 || len(to) != 2 {
+		t.Errorf("version 2 tags = %v -> %v, want [launch] -> [launch q3]", from, to)
+	}
+
+	w = call(http.MethodPost, "/api/v1/links/"+code+"/rollback", "alice-key", `{"version": 1}`)
+	var link LinkResponse
+	json.NewDecoder(w.Body).Decode(&link)
+	if w.Code != http.StatusOK || strings.Join(link.Tags, ",") != "launch" {
+		t.Errorf("rollback = %d %v, want 200 [launch]", w.Code, link.Tags)
+	}
+	json.NewDecoder(call(http.MethodGet, "/api/v1/links/"+code+"/history", "alice-key", "").Body).Decode(&history)
+	if v := history.Versions[len(history.Versions)-1]; v.Version != 3 || v.RestoredFrom != 1 {
+		t.Errorf("last version = %+v, want 3 restoring 1", v)
+	}
+
+	if w := call(http.MethodPost, "/api/v1/links/"+code+"/rollback", "alice-key", `{"version": 7}`); w.Code != http.StatusNotFound {
+		t.Errorf("rollback to unknown version = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := call(http.MethodPost, "/api/v1/links/"+code+"/rollback", "alice-key", `{}`); w.Code != http.StatusBadRequest {
+		t.Errorf("rollback without version = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,35 @@
+package http
+
+import (
+	stdhttp "net/http"
+)
+
+// SetReady sets what /readyz reports. A server shutting down reports itself
+// not ready first, so load balancers stop sending it traffic while it
+// finishes what it has.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// CloseStreams ends every open event stream, and any opened later, so that
+// their clients reconnect elsewhere; they can resume with Last-Event-ID.
+// Register it with http.Server.RegisterOnShutdown, as Shutdown otherwise
+// waits for the streams until its context expires.
+func (s *Server) CloseStreams() {
+	s.closeOnce.Do(func() { close(s.closing) })
+}
+
+// handleReady answers readiness probes: 200 while the server takes traffic,
+// 503 once it is shutting down.
+func (s *Server) handleReady(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodGet && r.Method != stdhttp.MethodHead {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if !s.ready.Load() {
+		stdhttp.Error(w, "shutting down", stdhttp.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/events"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+func TestServer_Ready(t *testing.T) {
+	server := NewServer(context.Background(), service.NewInMemoryShortener(storage.NewInMemoryStore()),
+		config.Config{BaseURL: "http://localhost:8080"})
+	for _, tt := range []struct {
+		ready bool
+		want  int
+	}{
+		{true, http.StatusOK},
+		{false, http.StatusServiceUnavailable},
+	} {
+		server.SetReady(tt.ready)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if w.Code != tt.want {
+			t.Errorf("GET /readyz with ready=%v = %d, want %d", tt.ready, w.Code, tt.want)
+		}
+	}
+}
+
+func TestServer_CloseStreams(t *testing.T) {
+	server := NewServerWithOptions(context.Background(), service.NewInMemoryShortener(storage.NewInMemoryStore()),
+		config.Config{BaseURL: "http://localhost:8080"}, Options{Events: events.NewBroker(1, 1)})
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/v1/events")
+	if err != nil {
+		t.Fatalf("open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, resp.Body)
+		done <- err
+	}()
+
+	server.CloseStreams()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("stream ended with %v, want a clean end", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still open after CloseStreams")
+	}
+	server.CloseStreams()
+}
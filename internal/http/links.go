@@ -0,0 +1,282 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	stdhttp "net/http"
+	"strings"
+	"time"
+
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+// LinkResponse describes one link, as returned by GET
+// /api/v1/links/{code} and the other calls returning a link.
+type LinkResponse struct {
+	Code            string          `json:"code"`
+	Domain          string          `json:"domain,omitempty"`
+	ShortURL        string          `json:"short_url"`
+	URL             string          `json:"url"`
+	CreatedAt       time.Time       `json:"created_at"`
+	Protected       bool            `json:"protected"`
+	Clicks          int             `json:"clicks"`
+	MaxClicks       int             `json:"max_clicks,omitempty"`
+	RemainingClicks *int            `json:"remaining_clicks,omitempty"`
+	ForwardQuery    bool            `json:"forward_query"`
+	ForwardPath     bool            `json:"forward_path"`
+	QueryConflict   string          `json:"query_conflict,omitempty"`
+	UTM             *utm            `json:"utm,omitempty"`
+	DeviceRules     []deviceRule    `json:"device_rules,omitempty"`
+	LocaleRules     []localeRule    `json:"locale_rules,omitempty"`
+	Variants        []variant       `json:"variants,omitempty"`
+	Schedule        []scheduleEntry `json:"schedule,omitempty"`
+	Interstitial    bool            `json:"interstitial"`
+	Tags            []string        `json:"tags,omitempty"`
+	Campaign        string          `json:"campaign,omitempty"`
+	Health          *linkHealth     `json:"health,omitempty"`
+	Metadata        *linkMetadata   `json:"metadata,omitempty"`
+}
+
+type linkMetadata struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+type linkHealth struct {
+	Status     string    `json:"status"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// LinksResponse is returned by GET /api/v1/links.
+type LinksResponse struct {
+	Links []LinkResponse `json:"links"`
+}
+
+type variant struct {
+	Name   string `json:"name,omitempty"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+	Clicks int    `json:"clicks"`
+}
+
+// StatsResponse is returned by GET /api/v1/links/{code}/stats.
+type StatsResponse struct {
+	Code     string    `json:"code"`
+	Clicks   int       `json:"clicks"`
+	Variants []variant `json:"variants,omitempty"`
+}
+
+func fromVariants(variants []storage.Variant) []variant {
+	var out []variant
+	for _, v := range variants {
+		out = append(out, variant(v))
+	}
+	return out
+}
+
+type localeRule struct {
+	Language string `json:"language,omitempty"`
+	Country  string `json:"country,omitempty"`
+	URL      string `json:"url"`
+}
+
+type localeRulesRequest struct {
+	Rules []localeRule `json:"rules"`
+}
+
+type localeRulesResponse struct {
+	Rules []localeRule `json:"rules"`
+}
+
+func toLocaleRules(rules []localeRule) []storage.LocaleRule {
+	var out []storage.LocaleRule
+	for _, rule := range rules {
+		out = append(out, storage.LocaleRule(rule))
+	}
+	return out
+}
+
+func fromLocaleRules(rules []storage.LocaleRule) []localeRule {
+	out := make([]localeRule, 0, len(rules))
+	for _, rule := range rules {
+		out = append(out, localeRule(rule))
+	}
+	return out
+}
+
+func (s *Server) newLinkResponse(link storage.Link) LinkResponse {
+	resp := LinkResponse{
+		Code:      link.Code,
+		Domain:    link.Domain,
+		ShortURL:  s.shortURL(link.Domain, link.Code),
+		URL:       link.URL,
+		CreatedAt: link.CreatedAt,
+		Protected: link.PasswordHash != "",
+		Clicks:    link.Clicks,
+		MaxClicks: link.MaxClicks,
+
+		Interstitial:  link.Interstitial,
+		Tags:          link.Tags,
+		Campaign:      link.Campaign,
+		ForwardQuery:  link.Passthrough.Query,
+		ForwardPath:   link.Passthrough.Path,
+		QueryConflict: link.Passthrough.QueryConflict,
+	}
+	if link.UTM != (storage.UTM{}) {
+		t := utm(link.UTM)
+		resp.UTM = &t
+	}
+	for _, rule := range link.DeviceRules {
+		resp.DeviceRules = append(resp.DeviceRules, deviceRule(rule))
+	}
+	if len(link.LocaleRules) > 0 {
+		resp.LocaleRules = fromLocaleRules(link.LocaleRules)
+	}
+	resp.Variants = fromVariants(link.Variants)
+	if len(link.Schedule) > 0 {
+		resp.Schedule = fromSchedule(link.Schedule)
+	}
+	if remaining, limited := link.Remaining(); limited {
+		resp.RemainingClicks = &remaining
+	}
+	if !link.Health.CheckedAt.IsZero() {
+		h := linkHealth(link.Health)
+		resp.Health = &h
+	}
+	if !link.Metadata.FetchedAt.IsZero() {
+		m := linkMetadata(link.Metadata)
+		resp.Metadata = &m
+	}
+	return resp
+}
+
+// handleLinks lists links, optionally filtered by tag, campaign, short domain
+// and destination health: ok, broken or unchecked.
+func (s *Server) handleLinks(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	filter := q.Get("health")
+	byDomain := q.Has("domain")
+	tag, campaign := strings.ToLower(q.Get("tag")), strings.ToLower(q.Get("campaign"))
+	domain := service.DomainFrom(r.Context())
+	switch filter {
+	case "", storage.HealthOK, storage.HealthBroken, "unchecked":
+	default:
+		stdhttp.Error(w, "invalid health filter", stdhttp.StatusBadRequest)
+		return
+	}
+	// Tag and campaign filters are served from the store's indexes rather
+	// than by scanning every link.
+	var links []storage.Link
+	switch {
+	case tag != "":
+		links = s.shortener.LinksByTag(r.Context(), tag)
+	case campaign != "":
+		links = s.shortener.LinksByCampaign(r.Context(), campaign)
+	default:
+		links = s.shortener.Links(r.Context())
+	}
+	resp := LinksResponse{Links: []LinkResponse{}}
+	for _, link := range links {
+		status := link.Health.Status
+		if link.Health.CheckedAt.IsZero() {
+			status = "unchecked"
+		}
+		if filter != "" && status != filter || byDomain && link.Domain != domain ||
+			campaign != "" && link.Campaign != campaign {
+			continue
+		}
+		resp.Links = append(resp.Links, s.newLinkResponse(link))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleLink returns (GET) or deletes (DELETE) a link.
+func (s *Server) handleLink(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	code := r.PathValue("code")
+	switch r.Method {
+	case stdhttp.MethodGet:
+		link, err := s.shortener.Lookup(r.Context(), code)
+		if err != nil {
+			stdhttp.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.newLinkResponse(link))
+	case stdhttp.MethodDelete:
+		if err := s.shortener.Delete(r.Context(), code); err != nil {
+			stdhttp.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(stdhttp.StatusNoContent)
+	default:
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+	}
+}
+
+// handleLocales reads (GET) or replaces (PUT) a link's locale rules.
+func (s *Server) handleLocales(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	code := r.PathValue("code")
+	var (
+		link storage.Link
+		err  error
+	)
+	switch r.Method {
+	case stdhttp.MethodGet:
+		link, err = s.shortener.Lookup(r.Context(), code)
+	case stdhttp.MethodPut:
+		var req localeRulesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			stdhttp.Error(w, "invalid json", stdhttp.StatusBadRequest)
+			return
+		}
+		link, err = s.shortener.SetLocaleRules(r.Context(), code, toLocaleRules(req.Rules))
+	default:
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	if err == service.ErrInvalidLocale {
+		stdhttp.Error(w, err.Error(), stdhttp.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("locale rules %s: %v", code, err)
+		stdhttp.Error(w, "internal error", stdhttp.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(localeRulesResponse{Rules: fromLocaleRules(link.LocaleRules)})
+}
+
+// handleStats reports click counts for a link, broken down by A/B variant.
+func (s *Server) handleStats(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	link, err := s.shortener.Stats(r.Context(), r.PathValue("code"))
+	if err != nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StatsResponse{
+		Code:     link.Code,
+		Clicks:   link.Clicks,
+		Variants: fromVariants(link.Variants),
+	})
+}
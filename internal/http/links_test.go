@@ -0,0 +1,178 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+func TestServer_HandleLink_RemainingClicks(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	cfg := config.Config{HTTPPort: "8080", BaseURL: "http://localhost:8080"}
+	server := NewServer(context.Background(), shortener, cfg)
+	ctx := context.Background()
+
+	code, _ := shortener.ShortenWithOptions(ctx, "https://example.com/invite", service.Options{MaxClicks: 1})
+
+	getLink := func() LinkResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/links/"+code, nil)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET link status = %d, want %d", w.Code, http.StatusOK)
+		}
+		var resp LinkResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+
+	if resp := getLink(); resp.RemainingClicks == nil || *resp.RemainingClicks != 1 {
+		t.Errorf("remaining_clicks = %v, want 1", resp.RemainingClicks)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Errorf("first resolve status = %d, want %d", w.Code, http.StatusFound)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if w.Code != http.StatusGone {
+		t.Errorf("second resolve status = %d, want %d", w.Code, http.StatusGone)
+	}
+
+	resp := getLink()
+	if resp.RemainingClicks == nil || *resp.RemainingClicks != 0 {
+		t.Errorf("remaining_clicks = %v, want 0", resp.RemainingClicks)
+	}
+	if resp.Clicks != 1 {
+		t.Errorf("clicks = %d, want 1", resp.Clicks)
+	}
+}
+
+func TestServer_HandleLink_NotFound(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestServer_HandleLink_Delete(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.ShortenWithOptions(context.Background(), "https://example.com/old", service.Options{Tags: []string{"launch"}})
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/links/"+code, nil))
+		if w.Code != want {
+			t.Errorf("DELETE status = %d, want %d", w.Code, want)
+		}
+	}
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("resolve after delete: status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if links := shortener.LinksByTag(context.Background(), "launch"); len(links) != 0 {
+		t.Errorf("LinksByTag after delete = %v, want none", links)
+	}
+}
+
+func TestServer_HandleLinks_HealthFilter(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := service.NewInMemoryShortener(store)
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	ctx := context.Background()
+
+	okCode, _ := shortener.Shorten(ctx, "https://example.com/fine")
+	brokenCode, _ := shortener.Shorten(ctx, "https://example.com/gone")
+	uncheckedCode, _ := shortener.Shorten(ctx, "https://example.com/new")
+	now := time.Now()
+	store.SetHealth(okCode, storage.Health{Status: storage.HealthOK, StatusCode: 200, CheckedAt: now})
+	store.SetHealth(brokenCode, storage.Health{Status: storage.HealthBroken, StatusCode: 404, CheckedAt: now})
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{okCode, brokenCode, uncheckedCode}},
+		{"?health=broken", []string{brokenCode}},
+		{"?health=ok", []string{okCode}},
+		{"?health=unchecked", []string{uncheckedCode}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links"+tt.query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET links%s status = %d, want %d", tt.query, w.Code, http.StatusOK)
+		}
+		var resp LinksResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var got []string
+		for _, l := range resp.Links {
+			got = append(got, l.Code)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("GET links%s = %v, want %v", tt.query, got, tt.want)
+		}
+		if tt.query == "?health=unchecked" && resp.Links[0].Health != nil {
+			t.Errorf("unchecked link health = %+v, want omitted", resp.Links[0].Health)
+		}
+		if tt.query == "?health=broken" && (resp.Links[0].Health == nil || resp.Links[0].Health.StatusCode != 404) {
+			t.Errorf("broken link health = %+v, want status_code 404", resp.Links[0].Health)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links?health=sick", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid filter status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServer_HandleLink_Metadata(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := service.NewInMemoryShortener(store)
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.Shorten(context.Background(), "https://example.com/post")
+
+	get := func() LinkResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/"+code, nil))
+		var resp LinkResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return resp
+	}
+	if resp := get(); resp.Metadata != nil {
+		t.Errorf("metadata = %+v before fetching, want omitted", resp.Metadata)
+	}
+
+	store.SetMetadata(code, storage.Metadata{Title: "A post", Image: "https://example.com/i.png", FetchedAt: time.Now()})
+	resp := get()
+	if resp.Metadata == nil || resp.Metadata.Title != "A post" || resp.Metadata.Image != "https://example.com/i.png" {
+		t.Errorf("metadata = %+v, want title and image", resp.Metadata)
+	}
+}
@@ -0,0 +1,129 @@
+package http
+
+import (
+	"html/template"
+	"log"
+	stdhttp "net/http"
+	"net/url"
+	"time"
+
+	"assignment_infracloud/internal/storage"
+)
+
+// interstitialDelay is how long the "you're leaving" page waits before
+// sending the visitor on.
+const interstitialDelay = 5
+
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Link preview</title></head>
+<body>
+<h1>Where does this link go?</h1>
+{{with .Metadata}}{{if or .Title .Description .Image}}<figure>
+{{if .Image}}<img src="{{.Image}}" alt="" width="300" referrerpolicy="no-referrer">{{end}}
+<figcaption>{{if .Title}}<strong>{{.Title}}</strong>{{end}}{{if .Description}}<p>{{.Description}}</p>{{end}}</figcaption>
+</figure>{{end}}{{end}}
+<dl>
+<dt>Short link</dt><dd>{{.ShortURL}}</dd>
+{{if .Destination}}<dt>Destination</dt><dd><a href="{{.Destination}}" rel="noopener noreferrer">{{.Destination}}</a></dd>
+<dt>Domain</dt><dd>{{.Domain}}</dd>
+{{else}}<dt>Destination</dt><dd>Hidden: this link can only be followed a limited number of times.</dd>
+{{end}}<dt>Created</dt><dd>{{.CreatedAt.Format "2 January 2006"}}</dd>
+<dt>Clicks</dt><dd>{{.Clicks}}</dd>
+</dl>
+</body>
+</html>
+`))
+
+var interstitialPage = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{.Delay}};url={{.Destination}}">
+<title>You are leaving</title>
+</head>
+<body>
+<h1>You are leaving for {{.Domain}}</h1>
+<p>You will be redirected to <a href="{{.Destination}}" rel="noopener noreferrer">{{.Destination}}</a> in <span id="countdown">{{.Delay}}</span> seconds.</p>
+<script>
+(function () {
+  var left = {{.Delay}}, el = document.getElementById("countdown");
+  var timer = setInterval(function () {
+    left--;
+    if (left <= 0) { clearInterval(timer); }
+    el.textContent = Math.max(left, 0);
+  }, 1000);
+})();
+</script>
+</body>
+</html>
+`))
+
+type previewView struct {
+	ShortURL    string
+	Destination string
+	Domain      string
+	CreatedAt   time.Time
+	Clicks      int
+	Metadata    storage.Metadata
+}
+
+type interstitialView struct {
+	Destination string
+	Domain      string
+	Delay       int
+}
+
+// wantsPreview reports whether the visit asks for the preview page, either
+// with a trailing "+" on the code or with ?preview=1, and returns the bare
+// code.
+func wantsPreview(r *stdhttp.Request, code string) (string, bool) {
+	if len(code) > 1 && code[len(code)-1] == '+' {
+		return code[:len(code)-1], true
+	}
+	return code, r.URL.Query().Get("preview") == "1"
+}
+
+// renderPreview shows where the link goes without following it. The
+// destination of a click-limited link is left out, since reading it would
+// otherwise not use up a click.
+func (s *Server) renderPreview(w stdhttp.ResponseWriter, link storage.Link) {
+	view := previewView{
+		ShortURL:  s.shortURL(link.Domain, link.Code),
+		CreatedAt: link.CreatedAt,
+		Clicks:    link.Clicks,
+	}
+	if link.MaxClicks <= 0 {
+		dest := link.DestinationAt(s.shortener.Now())
+		view.Destination, view.Domain = dest, hostname(dest)
+		// Metadata describes the link's own URL, not a scheduled replacement.
+		if dest == link.URL {
+			view.Metadata = link.Metadata
+		}
+	}
+	renderPage(w, previewPage, view)
+}
+
+func renderInterstitial(w stdhttp.ResponseWriter, dest string) {
+	renderPage(w, interstitialPage, interstitialView{
+		Destination: dest,
+		Domain:      hostname(dest),
+		Delay:       interstitialDelay,
+	})
+}
+
+func renderPage(w stdhttp.ResponseWriter, page *template.Template, view any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := page.Execute(w, view); err != nil {
+		log.Printf("render %s page: %v", page.Name(), err)
+	}
+}
+
+func hostname(dest string) string {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
@@ -0,0 +1,138 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+func TestServer_HandleResolve_Preview(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.Shorten(context.Background(), "https://docs.example.com/guide?a=<b>")
+
+	for _, path := range []string{"/" + code + "+", "/" + code + "?preview=1"} {
+		t.Run(path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+			}
+			body := w.Body.String()
+			for _, want := range []string{"docs.example.com", "https://docs.example.com/guide?a=%3cb%3e", "Clicks"} {
+				if !strings.Contains(body, want) {
+					t.Errorf("preview page missing %q", want)
+				}
+			}
+			if strings.Contains(body, "<b>") {
+				t.Error("preview page does not escape the destination")
+			}
+		})
+	}
+
+	link, _ := shortener.Lookup(context.Background(), code)
+	if link.Clicks != 0 {
+		t.Errorf("previews counted %d clicks, want 0", link.Clicks)
+	}
+}
+
+func TestServer_HandleResolve_PreviewProtected(t *testing.T) {
+	server, code := newProtectedServer(t)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code+"+", nil))
+	if strings.Contains(w.Body.String(), "example.com/secret") {
+		t.Error("preview revealed the destination of a protected link")
+	}
+}
+
+func TestServer_HandleResolve_PreviewClickLimited(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.ShortenWithOptions(context.Background(), "https://example.com/invite", service.Options{MaxClicks: 1})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code+"+", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if strings.Contains(w.Body.String(), "example.com") {
+		t.Error("preview revealed the destination of a click-limited link")
+	}
+	if link, _ := shortener.Lookup(context.Background(), code); link.Clicks != 0 {
+		t.Errorf("preview counted %d clicks, want 0", link.Clicks)
+	}
+}
+
+func TestServer_HandleResolve_PreviewExhausted(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.ShortenWithOptions(context.Background(), "https://example.com/invite", service.Options{MaxClicks: 1})
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/"+code, nil))
+
+	for _, path := range []string{"/" + code + "+", "/" + code + "?preview=1"} {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusGone {
+			t.Errorf("GET %s status = %d, want %d", path, w.Code, http.StatusGone)
+		}
+		if strings.Contains(w.Body.String(), "example.com") {
+			t.Errorf("GET %s revealed the destination of a used-up link", path)
+		}
+	}
+}
+
+func TestServer_HandleResolve_Interstitial(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.ShortenWithOptions(context.Background(), "https://external.example.org/page", service.Options{Interstitial: true})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if w.Header().Get("Location") != "" {
+		t.Error("interstitial link redirected immediately")
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, `http-equiv="refresh"`) || !strings.Contains(body, "external.example.org") {
+		t.Errorf("interstitial page missing refresh or destination:\n%s", body)
+	}
+
+	link, _ := shortener.Lookup(context.Background(), code)
+	if link.Clicks != 1 {
+		t.Errorf("interstitial visit counted %d clicks, want 1", link.Clicks)
+	}
+}
+
+func TestServer_HandleResolve_PreviewMetadata(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := service.NewInMemoryShortener(store)
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.Shorten(context.Background(), "https://shop.example.com/sale")
+	store.SetMetadata(code, storage.Metadata{
+		Title:       "Summer <Sale>",
+		Description: "Everything must go",
+		Image:       "https://cdn.example.com/card.png",
+		FetchedAt:   time.Now(),
+	})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code+"+", nil))
+	body := w.Body.String()
+	for _, want := range []string{"Summer &lt;Sale&gt;", "Everything must go", `src="https://cdn.example.com/card.png"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("preview page missing %q", want)
+		}
+	}
+}
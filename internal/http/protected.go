@@ -0,0 +1,193 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"html/template"
+	"log"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"assignment_infracloud/internal/password"
+	"assignment_infracloud/internal/storage"
+)
+
+const (
+	unlockCookiePrefix = "unlock_"
+	maxUnlockFailures  = 5
+	unlockWindow       = 15 * time.Minute
+)
+
+var unlockPage = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Protected link</title></head>
+<body>
+<h1>This link is password protected</h1>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<form method="post" action="{{.Action}}">
+<label>Password <input type="password" name="password" autofocus required></label>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+type unlockView struct {
+	Action string
+	Error  string
+}
+
+// handleUnlock serves the password form for a protected link and checks
+// submitted passwords. A correct password sets a signed cookie scoped to the
+// link and redirects back to it, so the visit itself goes through the normal
+// resolve path.
+func (s *Server) handleUnlock(w stdhttp.ResponseWriter, r *stdhttp.Request, link storage.Link) {
+	// Post back to, and return to, the full request URI so signed parameters
+	// survive the detour through the form.
+	action := r.URL.RequestURI()
+	switch r.Method {
+	case stdhttp.MethodGet, stdhttp.MethodHead:
+		s.renderUnlock(w, stdhttp.StatusOK, unlockView{Action: action})
+		return
+	case stdhttp.MethodPost:
+	default:
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+
+	if wait := s.unlockAttempts.reserve(link.Key()); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		stdhttp.Error(w, "too many attempts", stdhttp.StatusTooManyRequests)
+		return
+	}
+	ok, err := password.Verify(link.PasswordHash, r.PostFormValue("password"))
+	if err != nil {
+		log.Printf("unlock %s: %v", link.Code, err)
+		stdhttp.Error(w, "internal error", stdhttp.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		s.renderUnlock(w, stdhttp.StatusUnauthorized, unlockView{Action: action, Error: "Incorrect password."})
+		return
+	}
+
+	s.unlockAttempts.succeed(link.Key())
+	expires := time.Now().Add(s.unlockTTL)
+	stdhttp.SetCookie(w, &stdhttp.Cookie{
+		Name:     unlockCookiePrefix + link.Code,
+		Value:    s.signUnlock(link, expires),
+		Path:     "/" + link.Code,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.HasPrefix(s.cfg.BaseURL, "https://"),
+		SameSite: stdhttp.SameSiteLaxMode,
+	})
+	stdhttp.Redirect(w, r, action, stdhttp.StatusSeeOther)
+}
+
+func (s *Server) renderUnlock(w stdhttp.ResponseWriter, status int, view unlockView) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := unlockPage.Execute(w, view); err != nil {
+		log.Printf("render unlock page: %v", err)
+	}
+}
+
+// unlocked reports whether the request carries a valid, unexpired unlock
+// cookie for link.
+func (s *Server) unlocked(r *stdhttp.Request, link storage.Link) bool {
+	c, err := r.Cookie(unlockCookiePrefix + link.Code)
+	if err != nil {
+		return false
+	}
+	exp, _, ok := strings.Cut(c.Value, ".")
+	if !ok {
+		return false
+	}
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return false
+	}
+	return hmac.Equal([]byte(c.Value), []byte(s.signUnlock(link, time.Unix(unix, 0))))
+}
+
+// signUnlock binds the cookie to the link's key, its expiry and the current password
+// hash, so changing a link's password invalidates cookies already handed out.
+func (s *Server) signUnlock(link storage.Link, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	mac := hmac.New(sha256.New, s.cookieKey)
+	mac.Write([]byte(link.Key() + "\x00" + exp + "\x00" + link.PasswordHash))
+	return exp + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// attemptSweepMin is how many windows the attempt limiter holds before it
+// first sweeps out expired ones.
+const attemptSweepMin = 1024
+
+// attemptLimiter counts unlock attempts per code in fixed windows until one
+// succeeds. Expired windows are swept whenever the map has doubled since the
+// last sweep, so codes that stop failing do not accumulate.
+type attemptLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	attempts map[string]*attemptWindow
+	sweepAt  int
+}
+
+type attemptWindow struct {
+	start    time.Time
+	failures int
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{
+		max:      max,
+		window:   window,
+		attempts: make(map[string]*attemptWindow),
+		sweepAt:  attemptSweepMin,
+	}
+}
+
+// reserve counts an attempt against code before its password is checked, so
+// concurrent guesses cannot all slip past the limit. It returns how long code
+// stays locked out, or zero if the attempt may go ahead.
+func (l *attemptLimiter) reserve(code string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.attempts[code]
+	if !ok || time.Since(a.start) >= l.window {
+		if !ok && len(l.attempts) >= l.sweepAt {
+			l.sweep()
+		}
+		a = &attemptWindow{start: time.Now()}
+		l.attempts[code] = a
+	}
+	if a.failures >= l.max {
+		return l.window - time.Since(a.start)
+	}
+	a.failures++
+	return 0
+}
+
+// succeed clears the attempts counted against code.
+func (l *attemptLimiter) succeed(code string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, code)
+}
+
+// sweep drops expired windows; callers hold l.mu.
+func (l *attemptLimiter) sweep() {
+	for code, a := range l.attempts {
+		if time.Since(a.start) >= l.window {
+			delete(l.attempts, code)
+		}
+	}
+	l.sweepAt = max(2*len(l.attempts), attemptSweepMin)
+}
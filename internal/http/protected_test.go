@@ -0,0 +1,171 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+func newProtectedServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	cfg := config.Config{HTTPPort: "8080", BaseURL: "http://localhost:8080", CookieSecret: "test-secret"}
+	server := NewServer(context.Background(), shortener, cfg)
+	code, err := shortener.ShortenWithOptions(context.Background(), "https://example.com/secret", service.Options{Password: "hunter2"})
+	if err != nil {
+		t.Fatalf("ShortenWithOptions() error = %v", err)
+	}
+	return server, code
+}
+
+func postPassword(server *Server, code, pw string) *httptest.ResponseRecorder {
+	form := url.Values{"password": {pw}}
+	req := httptest.NewRequest(http.MethodPost, "/"+code, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	return w
+}
+
+func TestServer_HandleResolve_ProtectedShowsForm(t *testing.T) {
+	server, code := newProtectedServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	if loc := w.Header().Get("Location"); loc != "" {
+		t.Errorf("protected link redirected to %s before unlock", loc)
+	}
+	if !strings.Contains(w.Body.String(), `name="password"`) {
+		t.Error("response does not contain the password form")
+	}
+}
+
+func TestServer_HandleResolve_ProtectedWrongPassword(t *testing.T) {
+	server, code := newProtectedServer(t)
+
+	w := postPassword(server, code, "wrong")
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("wrong password set a cookie")
+	}
+}
+
+func TestServer_HandleResolve_ProtectedThrottle(t *testing.T) {
+	server, code := newProtectedServer(t)
+
+	for i := 0; i < maxUnlockFailures; i++ {
+		postPassword(server, code, "wrong")
+	}
+	w := postPassword(server, code, "hunter2")
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header not set")
+	}
+}
+
+func TestServer_HandleResolve_ProtectedConcurrentGuesses(t *testing.T) {
+	server, code := newProtectedServer(t)
+
+	const guesses = 4 * maxUnlockFailures
+	statuses := make(chan int, guesses)
+	var wg sync.WaitGroup
+	for i := 0; i < guesses; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- postPassword(server, code, "wrong").Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	checked := 0
+	for status := range statuses {
+		if status == http.StatusUnauthorized {
+			checked++
+		}
+	}
+	if checked != maxUnlockFailures {
+		t.Errorf("%d concurrent guesses were checked, want %d", checked, maxUnlockFailures)
+	}
+}
+
+func TestServer_HandleResolve_ProtectedUnlock(t *testing.T) {
+	server, code := newProtectedServer(t)
+
+	w := postPassword(server, code, "hunter2")
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	if !cookies[0].HttpOnly || cookies[0].Path != "/"+code {
+		t.Errorf("cookie = %+v, want HttpOnly scoped to /%s", cookies[0], code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusFound)
+	}
+	if loc := w.Header().Get("Location"); loc != "https://example.com/secret" {
+		t.Errorf("Location = %s, want https://example.com/secret", loc)
+	}
+}
+
+func TestServer_HandleResolve_ProtectedForgedCookie(t *testing.T) {
+	server, code := newProtectedServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+	req.AddCookie(&http.Cookie{Name: unlockCookiePrefix + code, Value: "9999999999.forged"})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Location") != "" {
+		t.Errorf("forged cookie passed the gate: status = %d", w.Code)
+	}
+}
+
+func TestAttemptLimiter_SweepsExpiredWindows(t *testing.T) {
+	l := newAttemptLimiter(3, time.Minute)
+	for i := 0; i < attemptSweepMin; i++ {
+		l.reserve(fmt.Sprintf("old%d", i))
+	}
+	for _, a := range l.attempts {
+		a.start = a.start.Add(-time.Hour)
+	}
+	for i := 0; i < 3; i++ {
+		l.reserve("locked")
+	}
+
+	if len(l.attempts) != 1 {
+		t.Errorf("limiter holds %d windows after a sweep, want 1", len(l.attempts))
+	}
+	if l.reserve("locked") <= 0 {
+		t.Error("reserve(locked) = 0, want the live window kept")
+	}
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"bytes"
+	"log"
+	stdhttp "net/http"
+	"strconv"
+
+	"assignment_infracloud/internal/qrcode"
+)
+
+const (
+	defaultQRSize   = 256
+	maxQRSize       = 4096
+	defaultQRMargin = 4 // the quiet zone the spec asks for
+	maxQRMargin     = 32
+)
+
+// handleQR renders the short URL of a link as a QR code. Query parameters:
+// format (png or svg), size in pixels, margin in modules and ecc (L, M, Q
+// or H).
+func (s *Server) handleQR(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	link, err := s.shortener.Lookup(r.Context(), r.PathValue("code"))
+	if err != nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+
+	q := r.URL.Query()
+	size, ok := intParam(q.Get("size"), defaultQRSize, 1, maxQRSize)
+	if !ok {
+		stdhttp.Error(w, "invalid size", stdhttp.StatusBadRequest)
+		return
+	}
+	margin, ok := intParam(q.Get("margin"), defaultQRMargin, 0, maxQRMargin)
+	if !ok {
+		stdhttp.Error(w, "invalid margin", stdhttp.StatusBadRequest)
+		return
+	}
+	level := qrcode.Medium
+	if ecc := q.Get("ecc"); ecc != "" {
+		if level, err = qrcode.ParseLevel(ecc); err != nil {
+			stdhttp.Error(w, "invalid ecc", stdhttp.StatusBadRequest)
+			return
+		}
+	}
+
+	code, err := qrcode.Encode([]byte(s.shortURL(link.Domain, link.Code)), level)
+	if err != nil {
+		log.Printf("qr %s: %v", link.Code, err)
+		stdhttp.Error(w, "internal error", stdhttp.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch q.Get("format") {
+	case "", "png":
+		contentType = "image/png"
+		err = code.WritePNG(&buf, size, margin)
+	case "svg":
+		contentType = "image/svg+xml"
+		err = code.WriteSVG(&buf, size, margin)
+	default:
+		stdhttp.Error(w, "invalid format", stdhttp.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("qr %s: %v", link.Code, err)
+		stdhttp.Error(w, "internal error", stdhttp.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(buf.Bytes())
+}
+
+// intParam parses an optional integer query parameter, returning def when it
+// is empty and false when it is malformed or outside [lo, hi].
+func intParam(s string, def, lo, hi int) (int, bool) {
+	if s == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < lo || n > hi {
+		return 0, false
+	}
+	return n, true
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+func TestServer_HandleQR(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.Shorten(context.Background(), "https://example.com/print")
+
+	t.Run("png", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/"+code+"/qr?size=200&ecc=H", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+		}
+		if got := w.Header().Get("Content-Type"); got != "image/png" {
+			t.Errorf("Content-Type = %q, want image/png", got)
+		}
+		img, err := png.Decode(w.Body)
+		if err != nil {
+			t.Fatalf("png.Decode() error = %v", err)
+		}
+		if got := img.Bounds().Dx(); got > 200 || got < 100 {
+			t.Errorf("width = %d, want close to 200", got)
+		}
+	})
+
+	t.Run("svg", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/"+code+"/qr?format=svg&size=300&margin=0", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+		}
+		if got := w.Header().Get("Content-Type"); got != "image/svg+xml" {
+			t.Errorf("Content-Type = %q, want image/svg+xml", got)
+		}
+		if !strings.Contains(w.Body.String(), `width="300"`) {
+			t.Error("svg does not honour size")
+		}
+	})
+
+	link, _ := shortener.Lookup(context.Background(), code)
+	if link.Clicks != 0 {
+		t.Errorf("rendering QR codes counted %d clicks, want 0", link.Clicks)
+	}
+}
+
+func TestServer_HandleQR_Errors(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.Shorten(context.Background(), "https://example.com/print")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"unknown code", http.MethodGet, "/api/v1/links/nope/qr", http.StatusNotFound},
+		{"bad format", http.MethodGet, "/api/v1/links/" + code + "/qr?format=gif", http.StatusBadRequest},
+		{"bad size", http.MethodGet, "/api/v1/links/" + code + "/qr?size=0", http.StatusBadRequest},
+		{"huge size", http.MethodGet, "/api/v1/links/" + code + "/qr?size=100000", http.StatusBadRequest},
+		{"bad margin", http.MethodGet, "/api/v1/links/" + code + "/qr?margin=-1", http.StatusBadRequest},
+		{"bad ecc", http.MethodGet, "/api/v1/links/" + code + "/qr?ecc=Z", http.StatusBadRequest},
+		{"wrong method", http.MethodPost, "/api/v1/links/" + code + "/qr", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
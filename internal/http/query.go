@@ -0,0 +1,69 @@
+package http
+
+import (
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"assignment_infracloud/internal/storage"
+)
+
+// redirectTarget applies the link's UTM template and then the visit's
+// forwarded suffix and parameters to dest. The template always wins over the
+// destination's own utm_* values; visitor parameters follow the link's
+// conflict policy.
+func redirectTarget(link storage.Link, dest, suffix string, params url.Values, now time.Time) (string, error) {
+	dest, err := destination(dest, "", utmParams(link, now), storage.ConflictOverride)
+	if err != nil {
+		return "", err
+	}
+	return destination(dest, suffix, params, link.Passthrough.QueryConflict)
+}
+
+// utmParams expands the {code} and {date} placeholders in the link's UTM
+// template.
+func utmParams(link storage.Link, now time.Time) url.Values {
+	params := link.UTM.Params()
+	if len(params) == 0 {
+		return nil
+	}
+	r := strings.NewReplacer("{code}", link.Code, "{date}", now.UTC().Format("2006-01-02"))
+	for _, vals := range params {
+		for i := range vals {
+			vals[i] = r.Replace(vals[i])
+		}
+	}
+	return params
+}
+
+// destination builds the redirect target from the stored URL: suffix is
+// appended to its path and params are merged into its query according to
+// conflict (one of the storage.Conflict* policies, override by default).
+func destination(dest, suffix string, params url.Values, conflict string) (string, error) {
+	if suffix == "" && len(params) == 0 {
+		return dest, nil
+	}
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "", err
+	}
+	if suffix != "" {
+		// Clean the suffix on its own first so dot segments cannot climb
+		// above the destination's path; JoinPath takes care of escaping.
+		u = u.JoinPath(path.Clean("/" + suffix))
+	}
+	if len(params) > 0 {
+		q := u.Query()
+		for k, vals := range params {
+			switch {
+			case !q.Has(k), conflict == storage.ConflictOverride, conflict == "":
+				q[k] = vals
+			case conflict == storage.ConflictAppend:
+				q[k] = append(q[k], vals...)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
+}
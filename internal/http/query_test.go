@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/storage"
+)
+
+func TestDestination(t *testing.T) {
+	tests := []struct {
+		name     string
+		dest     string
+		suffix   string
+		params   url.Values
+		conflict string
+		want     string
+	}{
+		{"unchanged", "https://example.com/a?x=1", "", nil, "", "https://example.com/a?x=1"},
+		{"add", "https://example.com/a", "", url.Values{"r": {"alice"}}, "", "https://example.com/a?r=alice"},
+		{"override by default", "https://example.com/a?r=bob&x=1", "", url.Values{"r": {"alice"}}, "", "https://example.com/a?r=alice&x=1"},
+		{"override", "https://example.com/a?r=bob", "", url.Values{"r": {"alice"}}, storage.ConflictOverride, "https://example.com/a?r=alice"},
+		{"keep", "https://example.com/a?r=bob", "", url.Values{"r": {"alice"}, "y": {"2"}}, storage.ConflictKeep, "https://example.com/a?r=bob&y=2"},
+		{"append", "https://example.com/a?r=bob", "", url.Values{"r": {"alice"}}, storage.ConflictAppend, "https://example.com/a?r=bob&r=alice"},
+		{"query escaping", "https://example.com/a", "", url.Values{"q": {"a b&c"}}, "", "https://example.com/a?q=a+b%26c"},
+		{"path suffix", "https://example.com/docs", "guide/intro", nil, "", "https://example.com/docs/guide/intro"},
+		{"path suffix root", "https://example.com", "x", nil, "", "https://example.com/x"},
+		{"path keeps query", "https://example.com/docs?v=2", "intro", nil, "", "https://example.com/docs/intro?v=2"},
+		{"path escaping", "https://example.com/docs", "a b/c?d", nil, "", "https://example.com/docs/a%20b/c%3Fd"},
+		{"path dot segments", "https://example.com/docs", "../../etc", nil, "", "https://example.com/docs/etc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := destination(tt.dest, tt.suffix, tt.params, tt.conflict)
+			if err != nil {
+				t.Fatalf("destination() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("destination() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedirectTarget_UTM(t *testing.T) {
+	now := time.Date(2026, 3, 14, 23, 0, 0, 0, time.UTC)
+	link := storage.Link{
+		Code:        "aB9",
+		UTM:         storage.UTM{Source: "newsletter", Medium: "email", Campaign: "launch-{date}", Content: "{code}"},
+		Passthrough: storage.Passthrough{Query: true, QueryConflict: storage.ConflictKeep},
+	}
+
+	got, err := redirectTarget(link, "https://example.com/a?utm_source=old&x=1", "", url.Values{"utm_medium": {"sms"}, "ref": {"y"}}, now)
+	if err != nil {
+		t.Fatalf("redirectTarget() error = %v", err)
+	}
+	want := "https://example.com/a?ref=y&utm_campaign=launch-2026-03-14&utm_content=aB9&utm_medium=email&utm_source=newsletter&x=1"
+	if got != want {
+		t.Errorf("redirectTarget() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	stdhttp "net/http"
+	"time"
+
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+type scheduleEntry struct {
+	ID    string     `json:"id,omitempty"`
+	URL   string     `json:"url"`
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+}
+
+type scheduleResponse struct {
+	Entries []scheduleEntry `json:"entries"`
+}
+
+func fromScheduleEntry(e storage.ScheduleEntry) scheduleEntry {
+	out := scheduleEntry{ID: e.ID, URL: e.URL}
+	if !e.Start.IsZero() {
+		out.Start = &e.Start
+	}
+	if !e.End.IsZero() {
+		out.End = &e.End
+	}
+	return out
+}
+
+func fromSchedule(entries []storage.ScheduleEntry) []scheduleEntry {
+	out := make([]scheduleEntry, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, fromScheduleEntry(e))
+	}
+	return out
+}
+
+// handleSchedule lists (GET) or adds to (POST) a link's scheduled
+// destinations.
+func (s *Server) handleSchedule(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	code := r.PathValue("code")
+	switch r.Method {
+	case stdhttp.MethodGet:
+		link, err := s.shortener.Lookup(r.Context(), code)
+		if err != nil {
+			stdhttp.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(scheduleResponse{Entries: fromSchedule(link.Schedule)})
+	case stdhttp.MethodPost:
+		var req scheduleEntry
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			stdhttp.Error(w, "invalid json", stdhttp.StatusBadRequest)
+			return
+		}
+		entry := storage.ScheduleEntry{URL: req.URL}
+		if req.Start != nil {
+			entry.Start = *req.Start
+		}
+		if req.End != nil {
+			entry.End = *req.End
+		}
+		entry, err := s.shortener.AddScheduleEntry(r.Context(), code, entry)
+		if errors.Is(err, storage.ErrNotFound) {
+			stdhttp.NotFound(w, r)
+			return
+		}
+		if err == service.ErrInvalidSchedule {
+			stdhttp.Error(w, err.Error(), stdhttp.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("schedule %s: %v", code, err)
+			stdhttp.Error(w, "internal error", stdhttp.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(stdhttp.StatusCreated)
+		json.NewEncoder(w).Encode(fromScheduleEntry(entry))
+	default:
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleScheduleEntry(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodDelete {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	err := s.shortener.RemoveScheduleEntry(r.Context(), r.PathValue("code"), r.PathValue("id"))
+	if err != nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+func TestServer_HandleSchedule(t *testing.T) {
+	now := time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC)
+	shortener := service.NewInMemoryShortenerWithClock(storage.NewInMemoryStore(), func() time.Time { return now })
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	code, _ := shortener.Shorten(context.Background(), "https://example.com/teaser")
+
+	launch := now.Add(time.Hour)
+	body, _ := json.Marshal(scheduleEntry{URL: "https://example.com/live", Start: &launch})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/links/"+code+"/schedule", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	var created scheduleEntry
+	json.NewDecoder(w.Body).Decode(&created)
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/"+code+"/schedule", nil))
+	var list scheduleResponse
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.Entries) != 1 || list.Entries[0].ID != created.ID || list.Entries[0].End != nil {
+		t.Errorf("GET entries = %+v, want the created open-ended entry", list.Entries)
+	}
+
+	resolve := func() string {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+		return w.Header().Get("Location")
+	}
+	if got := resolve(); got != "https://example.com/teaser" {
+		t.Errorf("before launch Location = %s, want teaser", got)
+	}
+	now = launch.Add(time.Second)
+	if got := resolve(); got != "https://example.com/live" {
+		t.Errorf("after launch Location = %s, want live", got)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/links/"+code+"/schedule/"+created.ID, nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("DELETE status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if got := resolve(); got != "https://example.com/teaser" {
+		t.Errorf("after removal Location = %s, want teaser", got)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/links/"+code+"/schedule/"+created.ID, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("second DELETE status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/links/"+code+"/schedule", bytes.NewBufferString(`{"url":"https://example.com/x"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("POST without range status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"encoding/json"
+	stdhttp "net/http"
+	"net/url"
+
+	"assignment_infracloud/internal/signing"
+	"assignment_infracloud/internal/storage"
+)
+
+type signRequest struct {
+	Params map[string]string `json:"params"`
+}
+
+type signResponse struct {
+	ShortURL string `json:"short_url"`
+}
+
+// verifySigned checks the signature on a visit. It returns the verified
+// parameters, or nil if the visit is unsigned and the link does not require
+// a signature.
+func (s *Server) verifySigned(r *stdhttp.Request, link storage.Link) (url.Values, error) {
+	query := r.URL.Query()
+	if !query.Has(signing.SigParam) && !link.RequireSignature {
+		return nil, nil
+	}
+	if s.keyring == nil {
+		return nil, signing.ErrUnknownKey
+	}
+	return s.keyring.Verify(link.Key(), query)
+}
+
+// handleSign issues a signed short URL carrying the given parameters, e.g.
+// one per email recipient. Only the link's tenant may sign it, so signing
+// is refused while API_KEYS is unset and anyone could call it.
+func (s *Server) handleSign(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodPost {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	if s.keyring == nil {
+		stdhttp.Error(w, "signing keys not configured", stdhttp.StatusNotImplemented)
+		return
+	}
+	if !s.tenants.RequireKeys() {
+		stdhttp.Error(w, "signing needs API_KEYS to be configured", stdhttp.StatusForbidden)
+		return
+	}
+	var req signRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		stdhttp.Error(w, "invalid json", stdhttp.StatusBadRequest)
+		return
+	}
+	// Lookup is scoped to the caller's tenant, so other tenants' links are
+	// not found.
+	link, err := s.shortener.Lookup(r.Context(), r.PathValue("code"))
+	if err != nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	params := url.Values{}
+	for k, v := range req.Params {
+		params.Set(k, v)
+	}
+	signed := s.keyring.Sign(link.Key(), params)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(signResponse{
+		ShortURL: s.shortURL(link.Domain, link.Code) + "?" + signed.Encode(),
+	})
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+func newSigningServer(t *testing.T, keys string) (*Server, service.Shortener) {
+	t.Helper()
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	cfg := config.Config{
+		HTTPPort:     "8080",
+		BaseURL:      "http://localhost:8080",
+		SigningKeys:  keys,
+		ShortDomains: "acme.link",
+		Tenants:      "acme:acme.link",
+		APIKeys:      "sign-key,acme:acme-key",
+	}
+	return NewServer(context.Background(), shortener, cfg), shortener
+}
+
+func signURL(t *testing.T, server *Server, code string, params map[string]string) string {
+	t.Helper()
+	body, _ := json.Marshal(signRequest{Params: params})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links/"+code+"/sign", bytes.NewReader(body))
+	req.Header.Set("X-API-Key", "sign-key")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("sign status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var resp signResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	return strings.TrimPrefix(resp.ShortURL, "http://localhost:8080")
+}
+
+func TestServer_HandleResolve_SignedForwardsParams(t *testing.T) {
+	server, shortener := newSigningServer(t, "k1:secret")
+	code, _ := shortener.Shorten(context.Background(), "https://example.com/landing?src=mail")
+
+	path := signURL(t, server, code, map[string]string{"r": "alice"})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusFound)
+	}
+	loc, _ := url.Parse(w.Header().Get("Location"))
+	if loc.Query().Get("r") != "alice" || loc.Query().Get("src") != "mail" {
+		t.Errorf("Location = %s, want r=alice and src=mail", loc)
+	}
+	if loc.Query().Has("sig") || loc.Query().Has("kid") {
+		t.Errorf("Location = %s leaks signature params", loc)
+	}
+}
+
+func TestServer_HandleResolve_SignedTampered(t *testing.T) {
+	server, shortener := newSigningServer(t, "k1:secret")
+	code, _ := shortener.Shorten(context.Background(), "https://example.com/landing")
+
+	path := strings.Replace(signURL(t, server, code, map[string]string{"r": "alice"}), "alice", "mallory", 1)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestServer_HandleResolve_RequireSignature(t *testing.T) {
+	server, shortener := newSigningServer(t, "k1:secret")
+	code, _ := shortener.ShortenWithOptions(context.Background(), "https://example.com/landing", service.Options{RequireSignature: true})
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("unsigned status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, signURL(t, server, code, nil), nil))
+	if w.Code != http.StatusFound {
+		t.Errorf("signed status = %d, want %d", w.Code, http.StatusFound)
+	}
+}
+
+func TestServer_HandleSign_NoKeys(t *testing.T) {
+	server, shortener := newSigningServer(t, "")
+	code, _ := shortener.Shorten(context.Background(), "https://example.com/landing")
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/links/"+code+"/sign", strings.NewReader(`{}`))
+	req.Header.Set("X-API-Key", "sign-key")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/shorten", strings.NewReader(`{"url":"https://example.com/x","require_signature":true}`))
+	req.Header.Set("X-API-Key", "sign-key")
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("shorten status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServer_HandleSign_Authorization(t *testing.T) {
+	server, shortener := newSigningServer(t, "k1:secret")
+	code, _ := shortener.Shorten(context.Background(), "https://example.com/landing")
+	sign := func(server *Server, key string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/links/"+code+"/sign", strings.NewReader(`{}`))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w.Code
+	}
+	if got := sign(server, ""); got != http.StatusUnauthorized {
+		t.Errorf("sign without a key = %d, want %d", got, http.StatusUnauthorized)
+	}
+	if got := sign(server, "acme-key"); got != http.StatusNotFound {
+		t.Errorf("sign another tenant's link = %d, want %d", got, http.StatusNotFound)
+	}
+
+	open := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080", SigningKeys: "k1:secret"})
+	if got := sign(open, ""); got != http.StatusForbidden {
+		t.Errorf("sign without API_KEYS = %d, want %d", got, http.StatusForbidden)
+	}
+	w := httptest.NewRecorder()
+	open.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/shorten", strings.NewReader(`{"url":"https://example.com/x","require_signature":true}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("shorten require_signature without API_KEYS = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
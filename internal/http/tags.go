@@ -0,0 +1,106 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	stdhttp "net/http"
+
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+type tagsRequest struct {
+	Tags []string `json:"tags"`
+}
+
+type tagsResponse struct {
+	Tags []string `json:"tags"`
+}
+
+type groupStatsResponse struct {
+	Tag      string `json:"tag,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Links    int    `json:"links"`
+	Clicks   int    `json:"clicks"`
+}
+
+// handleTags reads (GET) a link's tags or adds (POST) to them.
+func (s *Server) handleTags(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	code := r.PathValue("code")
+	var (
+		link storage.Link
+		err  error
+	)
+	switch r.Method {
+	case stdhttp.MethodGet:
+		link, err = s.shortener.Lookup(r.Context(), code)
+	case stdhttp.MethodPost:
+		var req tagsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			stdhttp.Error(w, "invalid json", stdhttp.StatusBadRequest)
+			return
+		}
+		link, err = s.shortener.AddTags(r.Context(), code, req.Tags)
+	default:
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	if err == service.ErrInvalidTag {
+		stdhttp.Error(w, err.Error(), stdhttp.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("tags %s: %v", code, err)
+		stdhttp.Error(w, "internal error", stdhttp.StatusInternalServerError)
+		return
+	}
+	resp := tagsResponse{Tags: link.Tags}
+	if resp.Tags == nil {
+		resp.Tags = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleTag removes one tag from a link.
+func (s *Server) handleTag(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodDelete {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	if _, err := s.shortener.RemoveTag(r.Context(), r.PathValue("code"), r.PathValue("tag")); err != nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
+
+// handleTagStats reports how many links carry a tag and their total clicks.
+func (s *Server) handleTagStats(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	tag := r.PathValue("tag")
+	stats := s.shortener.TagStats(r.Context(), tag)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groupStatsResponse{Tag: tag, Links: stats.Links, Clicks: stats.Clicks})
+}
+
+// handleCampaignStats reports how many links a campaign has and their total
+// clicks.
+func (s *Server) handleCampaignStats(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	campaign := r.PathValue("campaign")
+	stats := s.shortener.CampaignStats(r.Context(), campaign)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groupStatsResponse{Campaign: campaign, Links: stats.Links, Clicks: stats.Clicks})
+}
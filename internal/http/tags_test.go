@@ -0,0 +1,80 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+func TestServer_HandleTags(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+	ctx := context.Background()
+	a, _ := shortener.ShortenWithOptions(ctx, "https://example.com/a", service.Options{Campaign: "spring"})
+	b, _ := shortener.ShortenWithOptions(ctx, "https://example.com/b", service.Options{Tags: []string{"launch"}, Campaign: "spring"})
+	shortener.Resolve(ctx, b)
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/links/"+a+"/tags", strings.NewReader(`{"tags":["Launch","docs/guides"]}`)))
+	var tags tagsResponse
+	json.NewDecoder(w.Body).Decode(&tags)
+	if w.Code != http.StatusOK || strings.Join(tags.Tags, ",") != "docs/guides,launch" {
+		t.Errorf("POST tags = %d %v, want 200 [docs/guides launch]", w.Code, tags.Tags)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/links/"+a+"/tags", strings.NewReader(`{"tags":["no spaces"]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("POST invalid tag status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	list := func(query string) []string {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links?"+query, nil))
+		var resp LinksResponse
+		json.NewDecoder(w.Body).Decode(&resp)
+		var codes []string
+		for _, l := range resp.Links {
+			codes = append(codes, l.Code)
+		}
+		return codes
+	}
+	if got := list("tag=launch"); strings.Join(got, ",") != a+","+b {
+		t.Errorf("links?tag=launch = %v, want [%s %s]", got, a, b)
+	}
+	if got := list("tag=docs/guides&campaign=spring"); strings.Join(got, ",") != a {
+		t.Errorf("links?tag=docs/guides&campaign=spring = %v, want [%s]", got, a)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/tags/launch", nil))
+	var stats groupStatsResponse
+	json.NewDecoder(w.Body).Decode(&stats)
+	if stats != (groupStatsResponse{Tag: "launch", Links: 2, Clicks: 1}) {
+		t.Errorf("tag stats = %+v, want 2 links, 1 click", stats)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/links/"+a+"/tags/docs/guides", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("DELETE tag status = %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if got := list("tag=docs/guides"); len(got) != 0 {
+		t.Errorf("links?tag=docs/guides after delete = %v, want none", got)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/campaigns/spring", nil))
+	stats = groupStatsResponse{}
+	json.NewDecoder(w.Body).Decode(&stats)
+	if stats != (groupStatsResponse{Campaign: "spring", Links: 2, Clicks: 1}) {
+		t.Errorf("campaign stats = %+v, want 2 links, 1 click", stats)
+	}
+}
@@ -0,0 +1,156 @@
+package http
+
+import (
+	"hash/fnv"
+	"net"
+	stdhttp "net/http"
+	"strings"
+
+	"assignment_infracloud/internal/locale"
+	"assignment_infracloud/internal/storage"
+	"assignment_infracloud/internal/useragent"
+)
+
+const (
+	variantCookiePrefix = "ab_"
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+)
+
+// target picks the destination for a visit: device rules first, then locale
+// rules, then fallback if scheduled says a schedule entry supplied it, then
+// an A/B variant, then fallback. It marks the response as varying on
+// whichever request headers were consulted and returns the name of the
+// variant served, if any.
+func (s *Server) target(w stdhttp.ResponseWriter, r *stdhttp.Request, link storage.Link, fallback string, scheduled bool) (string, string) {
+	if len(link.DeviceRules) > 0 {
+		w.Header().Add("Vary", "User-Agent")
+		if dest, ok := matchDevice(link.DeviceRules, r.UserAgent()); ok {
+			return dest, ""
+		}
+	}
+	if len(link.LocaleRules) > 0 {
+		w.Header().Add("Vary", "Accept-Language")
+		w.Header().Add("Vary", s.regionHeader)
+		if dest, ok := matchLocale(link.LocaleRules, r.Header.Get("Accept-Language"), r.Header.Get(s.regionHeader)); ok {
+			return dest, ""
+		}
+	}
+	if len(link.Variants) > 0 && !scheduled {
+		v := pickVariant(w, r, link)
+		return v.URL, v.Name
+	}
+	return fallback, ""
+}
+
+// pickVariant keeps a visitor on the variant named in their cookie. New
+// visitors are bucketed by a hash of the code and their client key, so the
+// choice is stable even for clients that drop cookies, and the result is
+// remembered in a cookie scoped to the link.
+func pickVariant(w stdhttp.ResponseWriter, r *stdhttp.Request, link storage.Link) storage.Variant {
+	name := variantCookiePrefix + link.Code
+	if c, err := r.Cookie(name); err == nil {
+		for _, v := range link.Variants {
+			if v.Name == c.Value {
+				return v
+			}
+		}
+	}
+	v := weightedVariant(link.Variants, link.Code+"\x00"+clientKey(r))
+	stdhttp.SetCookie(w, &stdhttp.Cookie{
+		Name:     name,
+		Value:    v.Name,
+		Path:     "/" + link.Code,
+		MaxAge:   variantCookieMaxAge,
+		HttpOnly: true,
+		SameSite: stdhttp.SameSiteLaxMode,
+	})
+	return v
+}
+
+// weightedVariant maps key onto the variants in proportion to their weights.
+// Variants without a positive weight are never picked, unless none has one,
+// in which case the first is.
+func weightedVariant(variants []storage.Variant, key string) storage.Variant {
+	total := 0
+	for _, v := range variants {
+		total += max(v.Weight, 0)
+	}
+	if total <= 0 {
+		return variants[0]
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	n := int(h.Sum64() % uint64(total))
+	for _, v := range variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if n < v.Weight {
+			return v
+		}
+		n -= v.Weight
+	}
+	return variants[len(variants)-1]
+}
+
+// clientKey identifies a visitor for bucketing: the originating address
+// (first X-Forwarded-For hop when present) plus the User-Agent. It is not
+// used for anything security relevant, so trusting the header is fine.
+func clientKey(r *stdhttp.Request) string {
+	addr := r.RemoteAddr
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		addr, _, _ = strings.Cut(fwd, ",")
+		addr = strings.TrimSpace(addr)
+	} else if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return addr + "\x00" + r.UserAgent()
+}
+
+// matchDevice returns the URL of the first rule matching the visitor's
+// User-Agent.
+func matchDevice(rules []storage.DeviceRule, ua string) (string, bool) {
+	info := useragent.Parse(ua)
+	for _, rule := range rules {
+		if info.Matches(rule.Platform) {
+			return rule.URL, true
+		}
+	}
+	return "", false
+}
+
+// matchLocale walks the visitor's languages in preference order, each along
+// its fallback chain (pt-BR, then pt). At every step a rule that also names
+// the visitor's country beats one that does not. Country-only rules are
+// tried last.
+func matchLocale(rules []storage.LocaleRule, acceptLanguage, country string) (string, bool) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	for _, pref := range locale.ParseAcceptLanguage(acceptLanguage) {
+		for _, tag := range locale.Fallbacks(pref.Tag) {
+			generic := ""
+			for _, rule := range rules {
+				if !strings.EqualFold(rule.Language, tag) {
+					continue
+				}
+				if rule.Country == "" {
+					if generic == "" {
+						generic = rule.URL
+					}
+				} else if rule.Country == country {
+					return rule.URL, true
+				}
+			}
+			if generic != "" {
+				return generic, true
+			}
+		}
+	}
+	if country != "" {
+		for _, rule := range rules {
+			if rule.Language == "" && rule.Country == country {
+				return rule.URL, true
+			}
+		}
+	}
+	return "", false
+}
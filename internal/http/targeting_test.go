@@ -0,0 +1,321 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+const (
+	iphoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36"
+	windowsUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36"
+)
+
+func TestServer_HandleResolve_DeviceRules(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	cfg := config.Config{HTTPPort: "8080", BaseURL: "http://localhost:8080"}
+	server := NewServer(context.Background(), shortener, cfg)
+
+	body, _ := json.Marshal(ShortenRequest{
+		URL: "https://example.com/app",
+		DeviceRules: []deviceRule{
+			{Platform: "ios", URL: "https://apps.apple.com/app/id123"},
+			{Platform: "android", URL: "https://play.google.com/store/apps/details?id=com.example"},
+		},
+	})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/shorten", bytes.NewReader(body)))
+	var created ShortenResponse
+	json.NewDecoder(w.Body).Decode(&created)
+
+	tests := []struct {
+		name string
+		ua   string
+		want string
+	}{
+		{"iphone", iphoneUA, "https://apps.apple.com/app/id123"},
+		{"android", androidUA, "https://play.google.com/store/apps/details?id=com.example"},
+		{"desktop", windowsUA, "https://example.com/app"},
+		{"no user agent", "", "https://example.com/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/"+created.Code, nil)
+			req.Header.Set("User-Agent", tt.ua)
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+
+			if w.Code != http.StatusFound {
+				t.Fatalf("status = %d, want %d", w.Code, http.StatusFound)
+			}
+			if loc := w.Header().Get("Location"); loc != tt.want {
+				t.Errorf("Location = %s, want %s", loc, tt.want)
+			}
+			if w.Header().Get("Vary") != "User-Agent" {
+				t.Errorf("Vary = %q, want User-Agent", w.Header().Get("Vary"))
+			}
+		})
+	}
+}
+
+func TestServer_HandleShorten_InvalidDeviceRule(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+
+	for _, rule := range []deviceRule{
+		{Platform: "blackberry", URL: "https://example.com"},
+		{Platform: "ios", URL: "itms://apps.apple.com"},
+	} {
+		body, _ := json.Marshal(ShortenRequest{URL: "https://example.com/app", DeviceRules: []deviceRule{rule}})
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/shorten", bytes.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("rule %+v status = %d, want %d", rule, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestMatchLocale(t *testing.T) {
+	rules := []storage.LocaleRule{
+		{Language: "pt", URL: "https://example.com/pt"},
+		{Language: "pt-BR", URL: "https://example.com/pt-br"},
+		{Language: "en", Country: "GB", URL: "https://example.com/en-gb"},
+		{Language: "en", URL: "https://example.com/en"},
+		{Country: "DE", URL: "https://example.com/de"},
+	}
+	tests := []struct {
+		name    string
+		accept  string
+		country string
+		want    string
+		ok      bool
+	}{
+		{"exact tag", "pt-BR", "", "https://example.com/pt-br", true},
+		{"fallback to primary", "pt-PT", "", "https://example.com/pt", true},
+		{"quality order", "fr, pt;q=0.4, en;q=0.9", "", "https://example.com/en", true},
+		{"country specific wins", "en-US", "gb", "https://example.com/en-gb", true},
+		{"country only rule", "fr", "DE", "https://example.com/de", true},
+		{"language beats country only", "en", "DE", "https://example.com/en", true},
+		{"no match", "ja", "JP", "", false},
+		{"no headers", "", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := matchLocale(rules, tt.accept, tt.country)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("matchLocale() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestServer_HandleLocales(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	cfg := config.Config{HTTPPort: "8080", BaseURL: "http://localhost:8080", RegionHeader: "X-Country"}
+	server := NewServer(context.Background(), shortener, cfg)
+	code, _ := shortener.Shorten(context.Background(), "https://example.com/home")
+
+	body, _ := json.Marshal(localeRulesRequest{Rules: []localeRule{
+		{Language: "pt", URL: "https://example.com/pt"},
+		{Country: "br", URL: "https://example.com/br"},
+	}})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/links/"+code+"/locales", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var resp localeRulesResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Rules) != 2 || resp.Rules[1].Country != "BR" {
+		t.Errorf("PUT rules = %+v, want 2 rules with country normalized to BR", resp.Rules)
+	}
+
+	resolve := func(accept, country string) string {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.Header.Set("Accept-Language", accept)
+		req.Header.Set("X-Country", country)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w.Header().Get("Location")
+	}
+	if got := resolve("pt-BR,pt;q=0.9", ""); got != "https://example.com/pt" {
+		t.Errorf("pt-BR visitor went to %s, want https://example.com/pt", got)
+	}
+	if got := resolve("es", "BR"); got != "https://example.com/br" {
+		t.Errorf("es visitor in BR went to %s, want https://example.com/br", got)
+	}
+	if got := resolve("en", "US"); got != "https://example.com/home" {
+		t.Errorf("en visitor went to %s, want the default", got)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/v1/links/"+code+"/locales", bytes.NewBufferString(`{"rules":[{"url":"https://example.com"}]}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("PUT invalid rule status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/"+code+"/locales", nil))
+	json.NewDecoder(w.Body).Decode(&resp)
+	if len(resp.Rules) != 2 {
+		t.Errorf("GET returned %d rules, want 2 (invalid PUT must not change them)", len(resp.Rules))
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/missing/locales", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET missing status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestWeightedVariant_Distribution(t *testing.T) {
+	variants := []storage.Variant{{Name: "a", Weight: 1}, {Name: "b", Weight: 3}}
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[weightedVariant(variants, fmt.Sprintf("visitor-%d", i)).Name]++
+	}
+	if counts["a"] < 2200 || counts["a"] > 2800 {
+		t.Errorf("variant a served %d of 10000, want about 2500", counts["a"])
+	}
+	if weightedVariant(variants, "same") != weightedVariant(variants, "same") {
+		t.Error("weightedVariant() is not deterministic")
+	}
+
+	zero := []storage.Variant{{Name: "a"}, {Name: "b", Weight: -1}}
+	if v := weightedVariant(zero, "visitor"); v.Name != "a" {
+		t.Errorf("weightedVariant(no positive weights) = %q, want the first", v.Name)
+	}
+	zero = append(zero, storage.Variant{Name: "c", Weight: 2})
+	for i := 0; i < 100; i++ {
+		if v := weightedVariant(zero, fmt.Sprintf("visitor-%d", i)); v.Name != "c" {
+			t.Fatalf("weightedVariant() = %q, want only the weighted variant", v.Name)
+		}
+	}
+}
+
+func TestServer_HandleResolve_Variants(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+
+	body, _ := json.Marshal(ShortenRequest{
+		URL: "https://example.com/landing",
+		Variants: []variant{
+			{Name: "control", URL: "https://example.com/landing-a", Weight: 1},
+			{Name: "new", URL: "https://example.com/landing-b", Weight: 1},
+		},
+	})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/shorten", bytes.NewReader(body)))
+	var created ShortenResponse
+	json.NewDecoder(w.Body).Decode(&created)
+
+	// Same client without cookies lands on the same variant every time.
+	first := ""
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/"+created.Code, nil)
+		req.RemoteAddr = "203.0.113.7:5555"
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		loc := w.Header().Get("Location")
+		if first == "" {
+			first = loc
+		} else if loc != first {
+			t.Fatalf("visit %d went to %s, earlier visits went to %s", i, loc, first)
+		}
+	}
+
+	// A variant cookie overrides the hash.
+	other := "new"
+	if first == "https://example.com/landing-b" {
+		other = "control"
+	}
+	req := httptest.NewRequest(http.MethodGet, "/"+created.Code, nil)
+	req.RemoteAddr = "203.0.113.7:5555"
+	req.AddCookie(&http.Cookie{Name: variantCookiePrefix + created.Code, Value: other})
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if loc := w.Header().Get("Location"); loc == first {
+		t.Errorf("cookie for variant %s ignored, went to %s", other, loc)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/"+created.Code+"/stats", nil))
+	var stats StatsResponse
+	json.NewDecoder(w.Body).Decode(&stats)
+	if stats.Clicks != 6 {
+		t.Errorf("stats clicks = %d, want 6", stats.Clicks)
+	}
+	perVariant := map[string]int{}
+	for _, v := range stats.Variants {
+		perVariant[v.Name] = v.Clicks
+	}
+	if perVariant["control"]+perVariant["new"] != 6 || perVariant[other] != 1 {
+		t.Errorf("variant clicks = %v, want 6 total with %s=1", perVariant, other)
+	}
+}
+
+func TestServer_HandleResolve_ScheduleOverridesVariants(t *testing.T) {
+	// The service clock, not the wall clock, decides whether the entry is
+	// active.
+	now := time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC)
+	shortener := service.NewInMemoryShortenerWithClock(storage.NewInMemoryStore(), func() time.Time { return now })
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"})
+
+	body, _ := json.Marshal(ShortenRequest{
+		URL: "https://example.com/landing",
+		Variants: []variant{
+			{Name: "control", URL: "https://example.com/landing-a", Weight: 1},
+			{Name: "new", URL: "https://example.com/landing-b", Weight: 1},
+		},
+	})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/shorten", bytes.NewReader(body)))
+	var created ShortenResponse
+	json.NewDecoder(w.Body).Decode(&created)
+
+	start, end := now.Add(-time.Hour), now.Add(time.Hour)
+	body, _ = json.Marshal(scheduleEntry{URL: "https://example.com/live", Start: &start, End: &end})
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/links/"+created.Code+"/schedule", bytes.NewReader(body)))
+	var entry scheduleEntry
+	json.NewDecoder(w.Body).Decode(&entry)
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+created.Code, nil))
+	if loc := w.Header().Get("Location"); loc != "https://example.com/live" {
+		t.Errorf("while scheduled Location = %s, want the scheduled destination", loc)
+	}
+	if c := w.Header().Get("Set-Cookie"); c != "" {
+		t.Errorf("while scheduled Set-Cookie = %q, want no variant cookie", c)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/links/"+created.Code+"/schedule/"+entry.ID, nil))
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+created.Code, nil))
+	if loc := w.Header().Get("Location"); loc != "https://example.com/landing-a" && loc != "https://example.com/landing-b" {
+		t.Errorf("after the schedule Location = %s, want a variant", loc)
+	}
+
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/links/"+created.Code+"/stats", nil))
+	var stats StatsResponse
+	json.NewDecoder(w.Body).Decode(&stats)
+	total := 0
+	for _, v := range stats.Variants {
+		total += v.Clicks
+	}
+	if stats.Clicks != 2 || total != 1 {
+		t.Errorf("stats = %d clicks, %d variant clicks, want 2 and 1", stats.Clicks, total)
+	}
+}
@@ -0,0 +1,430 @@
+package http
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	stdhttp "net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+// maxImportLine bounds one NDJSON record on import.
+const maxImportLine = 1 << 20
+
+// exportRecord is one link in an export, everything needed to recreate it
+// elsewhere: its key, settings, counters and destination metadata. Health is
+// left out; the target environment checks destinations itself.
+type exportRecord struct {
+	Code             string    `json:"code"`
+	Tenant           string    `json:"tenant,omitempty"`
+	Domain           string    `json:"domain,omitempty"`
+	URL              string    `json:"url"`
+	CreatedAt        time.Time `json:"created_at"`
+	CreatedBy        string    `json:"created_by,omitempty"`
+	PasswordHash     string    `json:"password_hash,omitempty"`
+	MaxClicks        int       `json:"max_clicks,omitempty"`
+	Clicks           int       `json:"clicks"`
+	RequireSignature bool      `json:"require_signature,omitempty"`
+	Interstitial     bool      `json:"interstitial,omitempty"`
+	Tags             []string  `json:"tags,omitempty"`
+	Campaign         string    `json:"campaign,omitempty"`
+	exportRules
+	Metadata *linkMetadata `json:"metadata,omitempty"`
+}
+
+// exportRules are the structured settings, inlined in NDJSON records and
+// carried as one JSON column in CSV.
+type exportRules struct {
+	Passthrough *passthroughSetting `json:"passthrough,omitempty"`
+	UTM         *utm                `json:"utm,omitempty"`
+	DeviceRules []deviceRule        `json:"device_rules,omitempty"`
+	LocaleRules []localeRule        `json:"locale_rules,omitempty"`
+	Variants    []variant           `json:"variants,omitempty"`
+	Schedule    []scheduleEntry     `json:"schedule,omitempty"`
+}
+
+func (r exportRules) empty() bool {
+	return r.Passthrough == nil && r.UTM == nil && len(r.DeviceRules) == 0 &&
+		len(r.LocaleRules) == 0 && len(r.Variants) == 0 && len(r.Schedule) == 0
+}
+
+// csvColumns are the columns of a CSV export, in order. Tags are separated
+// by spaces.
+var csvColumns = []string{
+	"code", "tenant", "domain", "url", "created_at", "created_by", "password_hash",
+	"max_clicks", "clicks", "require_signature", "interstitial", "tags", "campaign",
+	"title", "description", "image", "metadata_fetched_at", "rules",
+}
+
+type importResponse struct {
+	Imported  int              `json:"imported"`
+	Conflicts []importConflict `json:"conflicts"`
+	Errors    []importError    `json:"errors"`
+	// Aborted is where the body stopped being readable, when it did. The
+	// records before it have been processed as reported.
+	Aborted *importError `json:"aborted,omitempty"`
+}
+
+// importConflict is a record left out because its code is already in use.
+type importConflict struct {
+	Line   int    `json:"line"`
+	Code   string `json:"code"`
+	Tenant string `json:"tenant,omitempty"`
+	Domain string `json:"domain,omitempty"`
+}
+
+type importError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+func newExportRecord(link storage.Link) exportRecord {
+	rec := exportRecord{
+		Code:             link.Code,
+		Tenant:           link.Tenant,
+		Domain:           link.Domain,
+		URL:              link.URL,
+		CreatedAt:        link.CreatedAt,
+		CreatedBy:        link.CreatedBy,
+		PasswordHash:     link.PasswordHash,
+		MaxClicks:        link.MaxClicks,
+		Clicks:           link.Clicks,
+		RequireSignature: link.RequireSignature,
+		Interstitial:     link.Interstitial,
+		Tags:             link.Tags,
+		Campaign:         link.Campaign,
+	}
+	if link.Passthrough != (storage.Passthrough{}) {
+		rec.Passthrough = &passthroughSetting{
+			ForwardQuery:  link.Passthrough.Query,
+			ForwardPath:   link.Passthrough.Path,
+			QueryConflict: link.Passthrough.QueryConflict,
+		}
+	}
+	if link.UTM != (storage.UTM{}) {
+		t := utm(link.UTM)
+		rec.UTM = &t
+	}
+	for _, rule := range link.DeviceRules {
+		rec.DeviceRules = append(rec.DeviceRules, deviceRule(rule))
+	}
+	if len(link.LocaleRules) > 0 {
+		rec.LocaleRules = fromLocaleRules(link.LocaleRules)
+	}
+	rec.Variants = fromVariants(link.Variants)
+	if len(link.Schedule) > 0 {
+		rec.Schedule = fromSchedule(link.Schedule)
+	}
+	if !link.Metadata.FetchedAt.IsZero() {
+		m := linkMetadata(link.Metadata)
+		rec.Metadata = &m
+	}
+	return rec
+}
+
+func (rec exportRecord) link() storage.Link {
+	link := storage.Link{
+		Code:             rec.Code,
+		Tenant:           rec.Tenant,
+		Domain:           rec.Domain,
+		URL:              rec.URL,
+		CreatedAt:        rec.CreatedAt,
+		CreatedBy:        rec.CreatedBy,
+		PasswordHash:     rec.PasswordHash,
+		MaxClicks:        rec.MaxClicks,
+		Clicks:           rec.Clicks,
+		RequireSignature: rec.RequireSignature,
+		Interstitial:     rec.Interstitial,
+		Tags:             rec.Tags,
+		Campaign:         rec.Campaign,
+		LocaleRules:      toLocaleRules(rec.LocaleRules),
+	}
+	if p := rec.Passthrough; p != nil {
+		link.Passthrough = storage.Passthrough{Query: p.ForwardQuery, Path: p.ForwardPath, QueryConflict: p.QueryConflict}
+	}
+	if rec.UTM != nil {
+		link.UTM = storage.UTM(*rec.UTM)
+	}
+	for _, rule := range rec.DeviceRules {
+		link.DeviceRules = append(link.DeviceRules, storage.DeviceRule(rule))
+	}
+	for _, v := range rec.Variants {
+		link.Variants = append(link.Variants, storage.Variant(v))
+	}
+	for _, e := range rec.Schedule {
+		entry := storage.ScheduleEntry{ID: e.ID, URL: e.URL}
+		if e.Start != nil {
+			entry.Start = *e.Start
+		}
+		if e.End != nil {
+			entry.End = *e.End
+		}
+		link.Schedule = append(link.Schedule, entry)
+	}
+	if rec.Metadata != nil {
+		link.Metadata = storage.Metadata(*rec.Metadata)
+	}
+	return link
+}
+
+func (rec exportRecord) csvRow() []string {
+	var rules string
+	if !rec.exportRules.empty() {
+		b, _ := json.Marshal(rec.exportRules)
+		rules = string(b)
+	}
+	var m linkMetadata
+	var fetchedAt string
+	if rec.Metadata != nil {
+		m = *rec.Metadata
+		fetchedAt = m.FetchedAt.Format(time.RFC3339Nano)
+	}
+	return []string{
+		rec.Code, rec.Tenant, rec.Domain, rec.URL, rec.CreatedAt.Format(time.RFC3339Nano),
+		rec.CreatedBy, rec.PasswordHash, strconv.Itoa(rec.MaxClicks), strconv.Itoa(rec.Clicks),
+		strconv.FormatBool(rec.RequireSignature), strconv.FormatBool(rec.Interstitial),
+		strings.Join(rec.Tags, " "), rec.Campaign, m.Title, m.Description, m.Image, fetchedAt, rules,
+	}
+}
+
+// parseCSVRecord builds a record from a CSV row whose columns are named by
+// header; missing columns are left zero.
+func parseCSVRecord(header map[string]int, row []string) (exportRecord, error) {
+	get := func(name string) string {
+		if i, ok := header[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	rec := exportRecord{
+		Code:         get("code"),
+		Tenant:       get("tenant"),
+		Domain:       get("domain"),
+		URL:          get("url"),
+		CreatedBy:    get("created_by"),
+		PasswordHash: get("password_hash"),
+		Tags:         strings.Fields(get("tags")),
+		Campaign:     get("campaign"),
+	}
+	var err error
+	if v := get("created_at"); v != "" {
+		if rec.CreatedAt, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return rec, errors.New("invalid created_at")
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"max_clicks", &rec.MaxClicks}, {"clicks", &rec.Clicks}} {
+		if v := get(p.name); v != "" {
+			if *p.dst, err = strconv.Atoi(v); err != nil {
+				return rec, errors.New("invalid " + p.name)
+			}
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *bool
+	}{{"require_signature", &rec.RequireSignature}, {"interstitial", &rec.Interstitial}} {
+		if v := get(p.name); v != "" {
+			if *p.dst, err = strconv.ParseBool(v); err != nil {
+				return rec, errors.New("invalid " + p.name)
+			}
+		}
+	}
+	if v := get("metadata_fetched_at"); v != "" {
+		m := linkMetadata{Title: get("title"), Description: get("description"), Image: get("image")}
+		if m.FetchedAt, err = time.Parse(time.RFC3339Nano, v); err != nil {
+			return rec, errors.New("invalid metadata_fetched_at")
+		}
+		rec.Metadata = &m
+	}
+	if v := get("rules"); v != "" {
+		if err := json.Unmarshal([]byte(v), &rec.exportRules); err != nil {
+			return rec, errors.New("invalid rules")
+		}
+	}
+	return rec, nil
+}
+
+// transferFormat picks ndjson or csv from ?format=, falling back to the
+// request's Content-Type.
+func transferFormat(r *stdhttp.Request) (string, bool) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ndjson"
+		if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "text/csv" {
+			format = "csv"
+		}
+	}
+	return format, format == "ndjson" || format == "csv"
+}
+
+// handleExport streams every link of every tenant, oldest first, as NDJSON
+// (one exportRecord per line) or, with ?format=csv, as CSV. Only admins, the
+// default tenant's keys, may export. Password hashes are left out unless
+// ?password_hashes=true asks for them, as a full backup needs.
+func (s *Server) handleExport(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	if !s.admin(w, r) {
+		return
+	}
+	format, ok := transferFormat(r)
+	if !ok {
+		stdhttp.Error(w, "invalid format", stdhttp.StatusBadRequest)
+		return
+	}
+	var hashes bool
+	if v := r.URL.Query().Get("password_hashes"); v != "" {
+		var err error
+		if hashes, err = strconv.ParseBool(v); err != nil {
+			stdhttp.Error(w, "invalid password_hashes", stdhttp.StatusBadRequest)
+			return
+		}
+	}
+	links := s.shortener.Export(r.Context())
+	if !hashes {
+		for i := range links {
+			links[i].PasswordHash = ""
+		}
+	}
+	// Large stores take longer to stream than any server-wide write timeout.
+	stdhttp.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="links.%s"`, format))
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		cw := csv.NewWriter(w)
+		cw.Write(csvColumns)
+		for _, link := range links {
+			cw.Write(newExportRecord(link).csvRow())
+		}
+		cw.Flush()
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	for _, link := range links {
+		if err := enc.Encode(newExportRecord(link)); err != nil {
+			return
+		}
+	}
+}
+
+// handleImport ingests an export in either format, keeping every link's
+// code. Records whose code is taken are reported as conflicts and invalid
+// ones as errors, by line; the rest are imported.
+func (s *Server) handleImport(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Method != stdhttp.MethodPost {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	if !s.admin(w, r) {
+		return
+	}
+	format, ok := transferFormat(r)
+	if !ok {
+		stdhttp.Error(w, "invalid format", stdhttp.StatusBadRequest)
+		return
+	}
+	resp := importResponse{Conflicts: []importConflict{}, Errors: []importError{}}
+	add := func(line int, rec exportRecord, err error) {
+		if err == nil {
+			_, err = s.shortener.Import(r.Context(), rec.link())
+		}
+		switch {
+		case err == nil:
+			resp.Imported++
+		case errors.Is(err, service.ErrConflict):
+			resp.Conflicts = append(resp.Conflicts, importConflict{Line: line, Code: rec.Code, Tenant: rec.Tenant, Domain: rec.Domain})
+		default:
+			resp.Errors = append(resp.Errors, importError{Line: line, Error: err.Error()})
+		}
+	}
+	var line int
+	var err error
+	if format == "csv" {
+		line, err = importCSV(r.Body, add)
+	} else {
+		line, err = importNDJSON(r.Body, add)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		// Earlier records are already stored; report them with the error.
+		resp.Aborted = &importError{Line: line, Error: "invalid " + format + ": " + err.Error()}
+		w.WriteHeader(stdhttp.StatusBadRequest)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// importNDJSON hands every non-blank line to add. If the body cannot be
+// read to the end it returns the error and the line it stopped at.
+func importNDJSON(body io.Reader, add func(int, exportRecord, error)) (int, error) {
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 0, 64<<10), maxImportLine)
+	line := 1
+	for ; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var rec exportRecord
+		err := json.Unmarshal(sc.Bytes(), &rec)
+		if err != nil {
+			err = errors.New("invalid json")
+		}
+		add(line, rec, err)
+	}
+	return line, sc.Err()
+}
+
+// importCSV reads rows after a header naming their columns, which must
+// include code and url. Rows that cannot be parsed are reported and skipped.
+// Like importNDJSON it returns where reading stopped, if it did.
+func importCSV(body io.Reader, add func(int, exportRecord, error)) (int, error) {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	names, err := cr.Read()
+	if err != nil {
+		return 1, err
+	}
+	header := make(map[string]int, len(names))
+	for i, name := range names {
+		header[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := header["code"]; !ok {
+		return 1, errors.New("missing code column")
+	}
+	if _, ok := header["url"]; !ok {
+		return 1, errors.New("missing url column")
+	}
+	line := 1
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return 0, nil
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			add(perr.StartLine, exportRecord{}, errors.New("invalid csv row"))
+			line = perr.Line
+			continue
+		}
+		if err != nil {
+			return line + 1, err
+		}
+		line, _ = cr.FieldPos(0)
+		rec, err := parseCSVRecord(header, row)
+		add(line, rec, err)
+	}
+}
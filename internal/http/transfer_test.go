@@ -0,0 +1,193 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+)
+
+func TestServer_ExportImport(t *testing.T) {
+	cfg := config.Config{
+		BaseURL:      "https://sho.rt",
+		ShortDomains: "acme.link",
+		Tenants:      "acme:acme.link",
+		APIKeys:      "admin-key,acme:acme-key",
+	}
+	newServer := func() *Server {
+		return NewServer(context.Background(), service.NewInMemoryShortener(storage.NewInMemoryStore()), cfg)
+	}
+	call := func(server *Server, method, path, key, contentType, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	src := newServer()
+	call(src, http.MethodPost, "/api/v1/shorten", "admin-key", "", `{"url": "https://example.com/a", "tags": ["launch", "q3"], "utm": {"source": "news"}}`)
+	call(src, http.MethodPost, "/api/v1/shorten", "acme-key", "", `{"url": "https://example.com/b", "domain": "acme.link", "max_clicks": 5}`)
+
+	if w := call(src, http.MethodGet, "/api/v1/admin/export", "acme-key", "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("export as tenant: status = %d, want 403", w.Code)
+	}
+	if w := call(src, http.MethodGet, "/api/v1/admin/export?format=xml", "admin-key", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("export as xml: status = %d, want 400", w.Code)
+	}
+
+	for _, format := range []string{"ndjson", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			w := call(src, http.MethodGet, "/api/v1/admin/export?format="+format, "admin-key", "", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("export: status = %d, body = %s", w.Code, w.Body)
+			}
+			dump := w.Body.String()
+
+			dst := newServer()
+			// A link already on the target takes code 1 of the default tenant.
+			call(dst, http.MethodPost, "/api/v1/shorten", "admin-key", "", `{"url": "https://example.com/other"}`)
+			contentType := "application/x-ndjson"
+			if format == "csv" {
+				contentType = "text/csv"
+			}
+			w = call(dst, http.MethodPost, "/api/v1/admin/import", "admin-key", contentType, dump)
+			if w.Code != http.StatusOK {
+				t.Fatalf("import: status = %d, body = %s", w.Code, w.Body)
+			}
+			var resp importResponse
+			json.NewDecoder(w.Body).Decode(&resp)
+			if resp.Imported != 1 || len(resp.Conflicts) != 1 || resp.Conflicts[0].Code != "1" || len(resp.Errors) != 0 {
+				t.Errorf("import = %+v, want acme's link imported and code 1 in conflict", resp)
+			}
+
+			w = call(dst, http.MethodGet, "/api/v1/links/2?domain=acme.link", "acme-key", "", "")
+			var link LinkResponse
+			json.NewDecoder(w.Body).Decode(&link)
+			if link.URL != "https://example.com/b" || link.MaxClicks != 5 {
+				t.Errorf("imported link = %+v, want acme's link with max_clicks 5", link)
+			}
+			w = call(dst, http.MethodPost, "/api/v1/shorten", "admin-key", "", `{"url": "https://example.com/c"}`)
+			var created ShortenResponse
+			json.NewDecoder(w.Body).Decode(&created)
+			if created.Code != "3" {
+				t.Errorf("code after import = %q, want 3", created.Code)
+			}
+		})
+	}
+
+	dst := newServer()
+	w := call(dst, http.MethodPost, "/api/v1/admin/import", "admin-key", "", "{\"code\": \"x\", \"url\": \"https://example.com\", \"tags\": [\"launch\"], \"utm\": {\"source\": \"news\"}}\n\nnot json\n{\"code\": \"y\", \"url\": \"nope\"}\n")
+	var resp importResponse
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.Imported != 1 || len(resp.Errors) != 2 || resp.Errors[0].Line != 3 || resp.Errors[1].Line != 4 {
+		t.Errorf("import with bad lines = %+v, want 1 imported and errors on lines 3 and 4", resp)
+	}
+	w = call(dst, http.MethodGet, "/api/v1/links/x", "admin-key", "", "")
+	var link LinkResponse
+	json.NewDecoder(w.Body).Decode(&link)
+	if link.UTM == nil || link.UTM.Source != "news" || len(link.Tags) != 1 {
+		t.Errorf("imported link = %+v, want its utm and tags", link)
+	}
+	if w := call(dst, http.MethodPost, "/api/v1/admin/import?format=csv", "admin-key", "", "code,tenant\nx,\n"); w.Code != http.StatusBadRequest {
+		t.Errorf("csv without url column: status = %d, want 400", w.Code)
+	}
+}
+
+func TestServer_Export_PasswordHashes(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	shortener.ShortenWithOptions(context.Background(), "https://example.com/secret", service.Options{Password: "hunter2"})
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "https://sho.rt", APIKeys: "admin-key"})
+	export := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/export"+query, nil)
+		req.Header.Set("X-API-Key", "admin-key")
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+	if w := export(""); w.Code != http.StatusOK || strings.Contains(w.Body.String(), "password_hash") {
+		t.Errorf("export = %d %s, want no password hashes", w.Code, w.Body)
+	}
+	if w := export("?password_hashes=true"); !strings.Contains(w.Body.String(), `"password_hash":"`) {
+		t.Errorf("export?password_hashes=true = %s, want the hash", w.Body)
+	}
+	if w := export("?format=csv"); strings.Contains(w.Body.String(), "$") {
+		t.Errorf("csv export = %s, want no password hashes", w.Body)
+	}
+	if w := export("?password_hashes=maybe"); w.Code != http.StatusBadRequest {
+		t.Errorf("export?password_hashes=maybe status = %d, want 400", w.Code)
+	}
+
+	open := NewServer(context.Background(), shortener, config.Config{BaseURL: "https://sho.rt"})
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/api/v1/admin/export", nil),
+		httptest.NewRequest(http.MethodPost, "/api/v1/admin/import", strings.NewReader(`{"code": "x", "url": "https://example.com"}`)),
+	} {
+		w := httptest.NewRecorder()
+		open.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s %s without API_KEYS status = %d, want 403", req.Method, req.URL, w.Code)
+		}
+	}
+}
+
+func TestServer_Import_Aborted(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "https://sho.rt", APIKeys: "admin-key"})
+	body := `{"code": "x", "url": "https://example.com/x"}` + "\n" +
+		`{"code": "y", "url": "nope"}` + "\n" +
+		`{"code": "z", "url": "https://example.com/` + strings.Repeat("z", maxImportLine) + `"}` + "\n" +
+		`{"code": "w", "url": "https://example.com/w"}` + "\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/import", strings.NewReader(body))
+	req.Header.Set("X-API-Key", "admin-key")
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("import with an oversized line: status = %d, want 400", w.Code)
+	}
+	var resp importResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("response is not an import report: %v", err)
+	}
+	if resp.Imported != 1 || len(resp.Errors) != 1 || resp.Errors[0].Line != 2 || resp.Aborted == nil || resp.Aborted.Line != 3 {
+		t.Errorf("import report = %+v, want x imported, y failed and the abort at line 3", resp)
+	}
+	if _, err := shortener.Lookup(context.Background(), "x"); err != nil {
+		t.Errorf("Lookup(x) error = %v, want the record before the abort stored", err)
+	}
+}
+
+func TestServer_Export_OutlivesWriteTimeout(t *testing.T) {
+	shortener := service.NewInMemoryShortener(storage.NewInMemoryStore())
+	shortener.Shorten(context.Background(), "https://example.com/a")
+	server := NewServer(context.Background(), shortener, config.Config{BaseURL: "https://sho.rt", APIKeys: "admin-key"})
+	ts := httptest.NewUnstartedServer(server)
+	// The deadline has passed before the handler writes anything.
+	ts.Config.WriteTimeout = time.Nanosecond
+	ts.Start()
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/admin/export", nil)
+	req.Header.Set("X-API-Key", "admin-key")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("export error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "https://example.com/a") {
+		t.Errorf("export = %d %q, %v, want the link", resp.StatusCode, body, err)
+	}
+}
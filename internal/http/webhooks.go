@@ -0,0 +1,130 @@
+package http
+
+import (
+	"encoding/json"
+	stdhttp "net/http"
+	"time"
+
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/webhook"
+)
+
+type webhookRequest struct {
+	URL string `json:"url"`
+	// Secret signs deliveries; one is generated when empty.
+	Secret string   `json:"secret,omitempty"`
+	Events []string `json:"events,omitempty"`
+}
+
+// webhookResponse shows a subscription. The secret is only included in the
+// response to its creation.
+type webhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type webhooksResponse struct {
+	Webhooks []webhookResponse `json:"webhooks"`
+}
+
+type deadLetterResponse struct {
+	Webhook  string        `json:"webhook"`
+	Event    webhook.Event `json:"event"`
+	Attempts int           `json:"attempts"`
+	Error    string        `json:"error"`
+	FailedAt time.Time     `json:"failed_at"`
+}
+
+type deadLettersResponse struct {
+	DeadLetters []deadLetterResponse `json:"dead_letters"`
+}
+
+func newWebhookResponse(sub webhook.Subscription) webhookResponse {
+	resp := webhookResponse{ID: sub.ID, URL: sub.URL, Events: sub.Events, CreatedAt: sub.CreatedAt}
+	if resp.Events == nil {
+		resp.Events = []string{}
+	}
+	return resp
+}
+
+// handleWebhooks lists (GET) or adds (POST) the tenant's webhook
+// subscriptions.
+func (s *Server) handleWebhooks(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if s.webhooks == nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	tenantID := service.TenantFrom(r.Context())
+	switch r.Method {
+	case stdhttp.MethodGet:
+		resp := webhooksResponse{Webhooks: []webhookResponse{}}
+		for _, sub := range s.webhooks.Subscriptions(tenantID) {
+			resp.Webhooks = append(resp.Webhooks, newWebhookResponse(sub))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	case stdhttp.MethodPost:
+		var req webhookRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			stdhttp.Error(w, "invalid json", stdhttp.StatusBadRequest)
+			return
+		}
+		sub, err := s.webhooks.Subscribe(tenantID, req.URL, req.Secret, req.Events)
+		if err != nil {
+			stdhttp.Error(w, err.Error(), stdhttp.StatusBadRequest)
+			return
+		}
+		resp := newWebhookResponse(sub)
+		resp.Secret = sub.Secret
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(stdhttp.StatusCreated)
+		json.NewEncoder(w).Encode(resp)
+	default:
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+	}
+}
+
+// handleWebhook removes a subscription.
+func (s *Server) handleWebhook(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if s.webhooks == nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	if r.Method != stdhttp.MethodDelete {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.webhooks.Unsubscribe(service.TenantFrom(r.Context()), r.PathValue("id")); err != nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(stdhttp.StatusNoContent)
+}
+
+// handleDeadLetters lists the tenant's events that ran out of delivery
+// attempts.
+func (s *Server) handleDeadLetters(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if s.webhooks == nil {
+		stdhttp.NotFound(w, r)
+		return
+	}
+	if r.Method != stdhttp.MethodGet {
+		stdhttp.Error(w, "method not allowed", stdhttp.StatusMethodNotAllowed)
+		return
+	}
+	resp := deadLettersResponse{DeadLetters: []deadLetterResponse{}}
+	for _, dl := range s.webhooks.DeadLetters(service.TenantFrom(r.Context())) {
+		resp.DeadLetters = append(resp.DeadLetters, deadLetterResponse{
+			Webhook:  dl.Subscription,
+			Event:    dl.Event,
+			Attempts: dl.Attempts,
+			Error:    dl.Error,
+			FailedAt: dl.FailedAt,
+		})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/config"
+	"assignment_infracloud/internal/service"
+	"assignment_infracloud/internal/storage"
+	"assignment_infracloud/internal/webhook"
+)
+
+func TestServer_Webhooks(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	var secret string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if err := webhook.Verify(secret, r.Header.Get(webhook.SignatureHeader), body, time.Now(), time.Minute); err != nil {
+			t.Errorf("delivery signature: %v", err)
+		}
+		var e webhook.Event
+		json.Unmarshal(body, &e)
+		received = append(received, e.Type+" "+e.Link.Code)
+	}))
+	defer receiver.Close()
+
+	dispatcher := webhook.NewDispatcher(receiver.Client(), 1, 16, webhook.Backoff{Attempts: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+	publish := func(typ string) func(storage.Link) {
+		return func(link storage.Link) { dispatcher.Publish(webhook.NewEvent(typ, link)) }
+	}
+	shortener := service.NewInMemoryShortenerWithHooks(storage.NewInMemoryStore(), service.Hooks{
+		Created: publish(webhook.EventCreated),
+		Clicked: publish(webhook.EventClicked),
+	})
+	server := NewServerWithOptions(context.Background(), shortener, config.Config{BaseURL: "http://localhost:8080"}, Options{Webhooks: dispatcher})
+
+	call := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	w := call(http.MethodPost, "/api/v1/webhooks", `{"url": "`+receiver.URL+`", "events": ["link.clicked"]}`)
+	var sub webhookResponse
+	json.NewDecoder(w.Body).Decode(&sub)
+	if w.Code != http.StatusCreated || sub.Secret == "" {
+		t.Fatalf("POST webhooks = %d %+v, want 201 with a secret", w.Code, sub)
+	}
+	mu.Lock()
+	secret = sub.Secret
+	mu.Unlock()
+	if w := call(http.MethodPost, "/api/v1/webhooks", `{"url": "`+receiver.URL+`", "events": ["link.renamed"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("POST webhooks with unknown event = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	code, _ := shortener.Shorten(context.Background(), "https://example.com")
+	call(http.MethodGet, "/"+code, "")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		got := strings.Join(received, ",")
+		mu.Unlock()
+		if got == "link.clicked "+code {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("received %q, want only the click on %s", got, code)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	var list webhooksResponse
+	json.NewDecoder(call(http.MethodGet, "/api/v1/webhooks", "").Body).Decode(&list)
+	if len(list.Webhooks) != 1 || list.Webhooks[0].ID != sub.ID || list.Webhooks[0].Secret != "" {
+		t.Errorf("GET webhooks = %+v, want the subscription without its secret", list.Webhooks)
+	}
+	if w := call(http.MethodGet, "/api/v1/webhooks/dead-letters", ""); !strings.Contains(w.Body.String(), `"dead_letters":[]`) {
+		t.Errorf("GET dead-letters = %s, want none", w.Body)
+	}
+	if w := call(http.MethodDelete, "/api/v1/webhooks/"+sub.ID, ""); w.Code != http.StatusNoContent {
+		t.Errorf("DELETE webhook = %d, want %d", w.Code, http.StatusNoContent)
+	}
+}
+
+func TestServer_WebhooksDisabled(t *testing.T) {
+	server := NewServer(context.Background(), service.NewInMemoryShortener(storage.NewInMemoryStore()), config.Config{BaseURL: "http://localhost:8080"})
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/webhooks", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET webhooks without a dispatcher = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
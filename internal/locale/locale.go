@@ -0,0 +1,86 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Preference is one language range from an Accept-Language header.
+type Preference struct {
+	Tag string
+	Q   float64
+}
+
+// ParseAcceptLanguage returns the language ranges in header ordered by
+// descending quality, keeping header order among equal weights. Tags are
+// lower-cased; ranges with q=0, the "*" wildcard and malformed entries are
+// dropped.
+func ParseAcceptLanguage(header string) []Preference {
+	var prefs []Preference
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" || !validTag(tag) {
+			continue
+		}
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(name) != "q" {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || v < 0 || v > 1 {
+				q = 0
+			} else {
+				q = v
+			}
+		}
+		if q == 0 {
+			continue
+		}
+		prefs = append(prefs, Preference{Tag: tag, Q: q})
+	}
+	sort.SliceStable(prefs, func(i, j int) bool {
+		return prefs[i].Q > prefs[j].Q
+	})
+	return prefs
+}
+
+// Fallbacks returns tag followed by its progressively shorter prefixes, e.g.
+// "zh-hant-tw" -> ["zh-hant-tw", "zh-hant", "zh"], as in RFC 4647 lookup.
+func Fallbacks(tag string) []string {
+	tag = strings.ToLower(tag)
+	chain := []string{tag}
+	for {
+		i := strings.LastIndex(tag, "-")
+		if i <= 0 {
+			return chain
+		}
+		tag = tag[:i]
+		chain = append(chain, tag)
+	}
+}
+
+// validTag accepts the RFC 4646 shape: 1-8 alphanumeric subtags separated by
+// hyphens, the first alphabetic.
+func validTag(tag string) bool {
+	for i, sub := range strings.Split(tag, "-") {
+		if len(sub) == 0 || len(sub) > 8 {
+			return false
+		}
+		for _, c := range sub {
+			alpha := c >= 'a' && c <= 'z'
+			if !alpha && (i == 0 || c < '0' || c > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ValidTag reports whether tag is a well-formed language tag.
+func ValidTag(tag string) bool {
+	return validTag(strings.ToLower(tag))
+}
@@ -0,0 +1,60 @@
+package locale
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []Preference
+	}{
+		{"empty", "", nil},
+		{"single", "pt-BR", []Preference{{"pt-br", 1}}},
+		{"quality order", "en;q=0.5, pt-BR, fr;q=0.8", []Preference{{"pt-br", 1}, {"fr", 0.8}, {"en", 0.5}}},
+		{"stable for equal q", "de, en, fr", []Preference{{"de", 1}, {"en", 1}, {"fr", 1}}},
+		{"drops q zero and wildcard", "fr;q=0, *;q=0.1, es", []Preference{{"es", 1}}},
+		{"whitespace", "  en-US ; q=0.9 ,de ", []Preference{{"de", 1}, {"en-us", 0.9}}},
+		{"invalid q dropped", "en;q=2, de;q=abc, it", []Preference{{"it", 1}}},
+		{"malformed tags dropped", "en_US, 12, toolongsubtag, es-419", []Preference{{"es-419", 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseAcceptLanguage(tt.header)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want []string
+	}{
+		{"pt-BR", []string{"pt-br", "pt"}},
+		{"zh-Hant-TW", []string{"zh-hant-tw", "zh-hant", "zh"}},
+		{"en", []string{"en"}},
+	}
+	for _, tt := range tests {
+		if got := Fallbacks(tt.tag); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Fallbacks(%q) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestValidTag(t *testing.T) {
+	for _, tag := range []string{"en", "pt-BR", "es-419", "zh-Hant-TW"} {
+		if !ValidTag(tag) {
+			t.Errorf("ValidTag(%q) = false, want true", tag)
+		}
+	}
+	for _, tag := range []string{"", "-en", "en-", "en_US", "419", "*"} {
+		if ValidTag(tag) {
+			t.Errorf("ValidTag(%q) = true, want false", tag)
+		}
+	}
+}
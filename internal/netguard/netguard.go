@@ -0,0 +1,41 @@
+// Package netguard keeps requests the service makes on users' behalf, to
+// destinations they chose, away from internal networks.
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+var ErrPrivateAddress = errors.New("destination resolves to a non-public address")
+
+// Control is a net.Dialer Control function rejecting connections to
+// addresses that are not publicly routable: loopback, private, link-local
+// (cloud metadata services included) and unspecified ones. It runs after DNS
+// resolution, so hostnames pointing at internal addresses are caught too.
+func Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// Transport returns an HTTP transport that only connects to public
+// addresses. Every connection is checked, including those made to follow
+// redirects. Proxies from the environment are not used, as the check would
+// apply to the proxy rather than the destination.
+func Transport(timeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{Timeout: timeout, Control: Control}
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+	}
+}
@@ -0,0 +1,39 @@
+package netguard
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestControl(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34:443":       true,
+		"[2606:2800:220:1::]:443": true,
+		"127.0.0.1:80":            false,
+		"[::1]:80":                false,
+		"10.1.2.3:80":             false,
+		"172.16.0.1:80":           false,
+		"192.168.1.1:80":          false,
+		"169.254.169.254:80":      false,
+		"0.0.0.0:80":              false,
+		"[fd00::1]:80":            false,
+		"[fe80::1]:80":            false,
+	} {
+		err := Control("tcp", addr, nil)
+		if public && err != nil || !public && !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("Control(%s) = %v, want public %v", addr, err, public)
+		}
+	}
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	client := &http.Client{Transport: Transport(time.Second)}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Get(loopback) error = %v, want %v", err, ErrPrivateAddress)
+	}
+}
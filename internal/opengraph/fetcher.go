@@ -0,0 +1,151 @@
+package opengraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"assignment_infracloud/internal/netguard"
+	"assignment_infracloud/internal/storage"
+)
+
+var (
+	ErrNotHTML        = errors.New("destination is not an html page")
+	ErrPrivateAddress = netguard.ErrPrivateAddress
+)
+
+const userAgent = "shortener-link-preview/1.0"
+
+// Fetcher downloads at most maxBytes of a destination page, within timeout,
+// and parses its metadata.
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+	timeout  time.Duration
+	now      func() time.Time
+}
+
+// NewFetcher returns a fetcher using client, or, when client is nil, one
+// that refuses to connect to loopback, private and link-local addresses so
+// users cannot make the service read internal pages for them.
+func NewFetcher(client *http.Client, maxBytes int64, timeout time.Duration) *Fetcher {
+	if client == nil {
+		client = &http.Client{Transport: netguard.Transport(timeout)}
+	}
+	return &Fetcher{client: client, maxBytes: maxBytes, timeout: timeout, now: time.Now}
+}
+
+// Fetch retrieves dest and returns its metadata. Relative image URLs are
+// resolved against the final URL after redirects.
+func (f *Fetcher) Fetch(ctx context.Context, dest string) (storage.Metadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dest, nil)
+	if err != nil {
+		return storage.Metadata{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return storage.Metadata{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return storage.Metadata{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		mt, _, _ := mime.ParseMediaType(ct)
+		if mt != "text/html" && mt != "application/xhtml+xml" {
+			return storage.Metadata{}, ErrNotHTML
+		}
+	}
+
+	m, err := Parse(io.LimitReader(resp.Body, f.maxBytes))
+	if err != nil {
+		return storage.Metadata{}, err
+	}
+	if img := m.Image; img != "" {
+		m.Image = ""
+		if u, err := resp.Request.URL.Parse(img); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			m.Image = u.String()
+		}
+	}
+	m.FetchedAt = f.now()
+	return m, nil
+}
+
+// Sink stores fetched metadata.
+type Sink interface {
+	SetMetadata(key string, m storage.Metadata)
+}
+
+type job struct {
+	key string
+	url string
+}
+
+// Worker fetches metadata in the background so shortening never waits on
+// the destination.
+type Worker struct {
+	fetcher *Fetcher
+	sink    Sink
+	workers int
+	jobs    chan job
+}
+
+func NewWorker(fetcher *Fetcher, sink Sink, workers, queueSize int) *Worker {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &Worker{
+		fetcher: fetcher,
+		sink:    sink,
+		workers: workers,
+		jobs:    make(chan job, queueSize),
+	}
+}
+
+// Enqueue schedules a fetch for the link stored under key and reports
+// whether it was queued. It never blocks: when the queue is full the link is
+// skipped.
+func (w *Worker) Enqueue(key, url string) bool {
+	select {
+	case w.jobs <- job{key: key, url: url}:
+		return true
+	default:
+		log.Printf("metadata %s: queue full, skipping", key)
+		return false
+	}
+}
+
+// Run processes queued fetches until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-w.jobs:
+					m, err := w.fetcher.Fetch(ctx, j.url)
+					if err != nil {
+						log.Printf("metadata %s: %v", j.key, err)
+						continue
+					}
+					w.sink.SetMetadata(j.key, m)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
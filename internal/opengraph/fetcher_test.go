@@ -0,0 +1,130 @@
+package opengraph
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/storage"
+)
+
+const page = `<html><head><meta property="og:title" content="Launch">
+<meta property="og:image" content="/img/card.png"></head></html>`
+
+func newPageServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(page))
+		case "/moved":
+			http.Redirect(w, r, "/page", http.StatusFound)
+		case "/big":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<head>" + strings.Repeat(" ", 4096) + `<title>Too far</title>`))
+		case "/pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	srv := newPageServer(t)
+	f := NewFetcher(srv.Client(), 1<<20, time.Second)
+
+	m, err := f.Fetch(context.Background(), srv.URL+"/moved")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if m.Title != "Launch" {
+		t.Errorf("Title = %q, want %q", m.Title, "Launch")
+	}
+	if want := srv.URL + "/img/card.png"; m.Image != want {
+		t.Errorf("Image = %q, want %q", m.Image, want)
+	}
+	if m.FetchedAt.IsZero() {
+		t.Error("FetchedAt not set")
+	}
+}
+
+func TestFetcher_Fetch_Limits(t *testing.T) {
+	srv := newPageServer(t)
+
+	m, err := NewFetcher(srv.Client(), 1024, time.Second).Fetch(context.Background(), srv.URL+"/big")
+	if err != nil || m.Title != "" {
+		t.Errorf("Fetch(big) = %+v, %v, want nothing read past the size limit", m, err)
+	}
+	if _, err := NewFetcher(srv.Client(), 1024, 50*time.Millisecond).Fetch(context.Background(), srv.URL+"/slow"); err == nil {
+		t.Error("Fetch(slow) error = nil, want timeout")
+	}
+	if _, err := NewFetcher(srv.Client(), 1024, time.Second).Fetch(context.Background(), srv.URL+"/pdf"); err != ErrNotHTML {
+		t.Errorf("Fetch(pdf) error = %v, want %v", err, ErrNotHTML)
+	}
+	if _, err := NewFetcher(srv.Client(), 1024, time.Second).Fetch(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("Fetch(missing) error = nil, want status error")
+	}
+}
+
+func TestFetcher_DefaultClientRefusesPrivateAddresses(t *testing.T) {
+	srv := newPageServer(t)
+	_, err := NewFetcher(nil, 1024, time.Second).Fetch(context.Background(), srv.URL+"/page")
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("Fetch(loopback) error = %v, want %v", err, ErrPrivateAddress)
+	}
+	// A proxy would be checked in place of the page, so none is used.
+	if tr, ok := NewFetcher(nil, 1024, time.Second).client.Transport.(*http.Transport); !ok || tr.Proxy != nil {
+		t.Error("default client uses a proxy")
+	}
+}
+
+func TestWorker(t *testing.T) {
+	srv := newPageServer(t)
+	store := storage.NewInMemoryStore()
+	store.SaveMapping("abc", srv.URL+"/page")
+
+	w := NewWorker(NewFetcher(srv.Client(), 1<<20, time.Second), store, 2, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx)
+		close(done)
+	}()
+	if !w.Enqueue("abc", srv.URL+"/page") {
+		t.Fatal("Enqueue() = false, want true")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		link, _ := store.GetLink("abc")
+		if link.Metadata.Title == "Launch" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("metadata not stored: %+v", link.Metadata)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestWorker_EnqueueDoesNotBlock(t *testing.T) {
+	w := NewWorker(nil, nil, 1, 1)
+	if !w.Enqueue("a", "https://example.com") {
+		t.Fatal("first Enqueue() = false, want true")
+	}
+	if w.Enqueue("b", "https://example.com") {
+		t.Error("Enqueue() on a full queue = true, want false")
+	}
+}
@@ -0,0 +1,187 @@
+// Package opengraph fetches destination pages and extracts the title,
+// description and image used to render link cards.
+package opengraph
+
+import (
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"assignment_infracloud/internal/storage"
+)
+
+// maxFieldLen caps each extracted field, in bytes.
+const maxFieldLen = 1024
+
+// Parse extracts og:title, og:description and og:image from the head of an
+// HTML document, falling back to <title> and <meta name="description">. It
+// is a forgiving scanner rather than a full HTML parser: it only looks at
+// tags before <body> and ignores anything it does not understand.
+func Parse(r io.Reader) (storage.Metadata, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return storage.Metadata{}, err
+	}
+	doc := string(b)
+
+	var og, fallback storage.Metadata
+	for i := 0; i < len(doc); {
+		lt := strings.IndexByte(doc[i:], '<')
+		if lt < 0 {
+			break
+		}
+		i += lt + 1
+		if strings.HasPrefix(doc[i:], "!--") {
+			end := strings.Index(doc[i:], "-->")
+			if end < 0 {
+				break
+			}
+			i += end + 3
+			continue
+		}
+		name, attrs, n := parseTag(doc[i:])
+		i += n
+		switch name {
+		case "meta":
+			content := clean(attrs["content"])
+			key := attrs["property"]
+			if key == "" {
+				key = attrs["name"]
+			}
+			switch strings.ToLower(key) {
+			case "og:title":
+				setOnce(&og.Title, content)
+			case "og:description":
+				setOnce(&og.Description, content)
+			case "og:image", "og:image:url":
+				setOnce(&og.Image, content)
+			case "description":
+				setOnce(&fallback.Description, content)
+			}
+		case "title":
+			end := indexFold(doc[i:], "</title")
+			if end < 0 {
+				end = len(doc) - i
+			}
+			setOnce(&fallback.Title, clean(html.UnescapeString(doc[i:i+end])))
+			i += end
+		case "script", "style":
+			end := indexFold(doc[i:], "</"+name)
+			if end < 0 {
+				i = len(doc)
+			} else {
+				i += end
+			}
+		case "body", "/head":
+			i = len(doc)
+		}
+	}
+
+	setOnce(&og.Title, fallback.Title)
+	setOnce(&og.Description, fallback.Description)
+	return og, nil
+}
+
+// parseTag reads a tag name and its attributes from s, which starts just
+// after '<'. It returns the lower-cased name (with a leading '/' for end
+// tags), the attributes keyed by lower-cased name, and the number of bytes
+// consumed including the closing '>'.
+func parseTag(s string) (string, map[string]string, int) {
+	i := 0
+	for i < len(s) && (isNameByte(s[i]) || i == 0 && s[i] == '/') {
+		i++
+	}
+	name := strings.ToLower(s[:i])
+	attrs := make(map[string]string)
+	for i < len(s) {
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] == '>' {
+			return name, attrs, i + 1
+		}
+		if s[i] == '/' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && !isSpace(s[i]) && s[i] != '=' && s[i] != '>' && s[i] != '/' {
+			i++
+		}
+		key := strings.ToLower(s[start:i])
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		if i >= len(s) || s[i] != '=' {
+			attrs[key] = ""
+			continue
+		}
+		i++
+		for i < len(s) && isSpace(s[i]) {
+			i++
+		}
+		var val string
+		if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+			q := s[i]
+			end := strings.IndexByte(s[i+1:], q)
+			if end < 0 {
+				return name, attrs, len(s)
+			}
+			val = s[i+1 : i+1+end]
+			i += end + 2
+		} else {
+			start := i
+			for i < len(s) && !isSpace(s[i]) && s[i] != '>' {
+				i++
+			}
+			val = s[start:i]
+		}
+		if _, dup := attrs[key]; !dup {
+			attrs[key] = html.UnescapeString(val)
+		}
+	}
+	return name, attrs, len(s)
+}
+
+// clean collapses whitespace and truncates to maxFieldLen on a rune
+// boundary.
+func clean(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= maxFieldLen {
+		return s
+	}
+	s = s[:maxFieldLen]
+	for len(s) > 0 && !utf8.ValidString(s) {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func setOnce(dst *string, v string) {
+	if *dst == "" {
+		*dst = v
+	}
+}
+
+// indexFold is strings.Index with ASCII case folding; substr must be
+// lower-case ASCII.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isNameByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == ':'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
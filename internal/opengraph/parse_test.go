@@ -0,0 +1,83 @@
+package opengraph
+
+import (
+	"strings"
+	"testing"
+
+	"assignment_infracloud/internal/storage"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want storage.Metadata
+	}{
+		{
+			name: "opengraph tags",
+			doc: `<!DOCTYPE html><html><head>
+<meta property="og:title" content="Spring &amp; Summer Sale">
+<meta property="og:description" content="Up to 50% off">
+<meta property="og:image" content="https://cdn.example.com/sale.png">
+<title>Ignored</title>
+</head><body></body></html>`,
+			want: storage.Metadata{Title: "Spring & Summer Sale", Description: "Up to 50% off", Image: "https://cdn.example.com/sale.png"},
+		},
+		{
+			name: "title and description fallback",
+			doc: `<html><HEAD><TITLE>
+  Plain   page
+</TITLE><meta name="Description" content='A simple page'></head></html>`,
+			want: storage.Metadata{Title: "Plain page", Description: "A simple page"},
+		},
+		{
+			name: "first value wins and unquoted attributes",
+			doc:  `<meta property=og:title content=First><meta property="og:title" content="Second"/>`,
+			want: storage.Metadata{Title: "First"},
+		},
+		{
+			name: "comments and scripts are skipped",
+			doc: `<head><!-- <meta property="og:title" content="commented"> -->
+<script>var s = '<meta property="og:title" content="scripted">';</script>
+<meta property="og:title" content="Real"></head>`,
+			want: storage.Metadata{Title: "Real"},
+		},
+		{
+			name: "stops at body",
+			doc:  `<head><title>Head</title></head><body><meta property="og:image" content="/late.png"></body>`,
+			want: storage.Metadata{Title: "Head"},
+		},
+		{
+			name: "truncated document",
+			doc:  `<head><title>Cut off`,
+			want: storage.Metadata{Title: "Cut off"},
+		},
+		{
+			name: "not html",
+			doc:  `{"json": true}`,
+			want: storage.Metadata{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(strings.NewReader(tt.doc))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_LongFieldsTruncated(t *testing.T) {
+	doc := `<title>` + strings.Repeat("é", maxFieldLen) + `</title>`
+	got, _ := Parse(strings.NewReader(doc))
+	if len(got.Title) > maxFieldLen || !strings.HasPrefix(got.Title, "éé") {
+		t.Errorf("Parse() title length = %d, want <= %d", len(got.Title), maxFieldLen)
+	}
+	if strings.ContainsRune(got.Title, '�') {
+		t.Error("Parse() split a rune when truncating")
+	}
+}
@@ -0,0 +1,117 @@
+package password
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	scheme     = "pbkdf2-sha256"
+	iterations = 100000
+	saltLen    = 16
+	keyLen     = 32
+
+	// maxIterations and maxKeyLen bound the work a stored hash can ask of
+	// Verify, so an imported hash cannot make every guess burn CPU.
+	maxIterations = 10 * iterations
+	maxKeyLen     = 64
+)
+
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// Hash derives a salted PBKDF2-SHA256 hash of pw. The result is
+// self-describing ("pbkdf2-sha256$iter$salt$key") so the parameters can be
+// raised later without invalidating stored hashes.
+func Hash(pw string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("read salt: %w", err)
+	}
+	key := pbkdf2([]byte(pw), salt, iterations, keyLen)
+	enc := base64.RawStdEncoding
+	return strings.Join([]string{
+		scheme,
+		strconv.Itoa(iterations),
+		enc.EncodeToString(salt),
+		enc.EncodeToString(key),
+	}, "$"), nil
+}
+
+// Verify reports whether pw matches the encoded hash. The derived keys are
+// compared in constant time.
+func Verify(encoded, pw string) (bool, error) {
+	iter, salt, want, err := parse(encoded)
+	if err != nil {
+		return false, err
+	}
+	got := pbkdf2([]byte(pw), salt, iter, len(want))
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
+
+// Validate returns ErrMalformedHash unless Verify would accept encoded.
+func Validate(encoded string) error {
+	_, _, _, err := parse(encoded)
+	return err
+}
+
+// parse splits an encoded hash into its parameters, refusing hashes Hash
+// could not have produced or that exceed maxIterations or maxKeyLen.
+func parse(encoded string) (iter int, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != scheme {
+		return 0, nil, nil, ErrMalformedHash
+	}
+	iter, err = strconv.Atoi(parts[1])
+	if err != nil || iter <= 0 || iter > maxIterations {
+		return 0, nil, nil, ErrMalformedHash
+	}
+	enc := base64.RawStdEncoding
+	salt, err = enc.DecodeString(parts[2])
+	if err != nil {
+		return 0, nil, nil, ErrMalformedHash
+	}
+	key, err = enc.DecodeString(parts[3])
+	if err != nil || len(key) == 0 || len(key) > maxKeyLen {
+		return 0, nil, nil, ErrMalformedHash
+	}
+	return iter, salt, key, nil
+}
+
+// pbkdf2 implements RFC 8018 PBKDF2 with HMAC-SHA256 as the PRF.
+func pbkdf2(pw, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(sha256.New, pw)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+	out := make([]byte, 0, blocks*hashLen)
+	buf := make([]byte, 4)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		buf[0] = byte(block >> 24)
+		buf[1] = byte(block >> 16)
+		buf[2] = byte(block >> 8)
+		buf[3] = byte(block)
+		prf.Write(buf)
+		u = prf.Sum(u[:0])
+		t := make([]byte, hashLen)
+		copy(t, u)
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range t {
+				t[i] ^= u[i]
+			}
+		}
+		out = append(out, t...)
+	}
+	return out[:keyLen]
+}
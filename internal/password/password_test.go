@@ -0,0 +1,60 @@
+package password
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestHashAndVerify(t *testing.T) {
+	hash, err := Hash("s3cret")
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	if strings.Contains(hash, "s3cret") {
+		t.Fatal("Hash() leaked the plaintext password")
+	}
+
+	ok, err := Verify(hash, "s3cret")
+	if err != nil || !ok {
+		t.Errorf("Verify(correct) = %v, %v, want true, nil", ok, err)
+	}
+	ok, err = Verify(hash, "wrong")
+	if err != nil || ok {
+		t.Errorf("Verify(wrong) = %v, %v, want false, nil", ok, err)
+	}
+}
+
+func TestHash_Salted(t *testing.T) {
+	h1, _ := Hash("same")
+	h2, _ := Hash("same")
+	if h1 == h2 {
+		t.Error("Hash() produced identical output for two calls, salt is not random")
+	}
+}
+
+func TestVerify_Malformed(t *testing.T) {
+	for _, encoded := range []string{"", "plain", "md5$1$aa$bb", "pbkdf2-sha256$x$aa$bb", "pbkdf2-sha256$10$!!$bb",
+		"pbkdf2-sha256$1000001$aa$bb", "pbkdf2-sha256$10$aa$" + strings.Repeat("A", 88)} {
+		if _, err := Verify(encoded, "pw"); err != ErrMalformedHash {
+			t.Errorf("Verify(%q) error = %v, want %v", encoded, err, ErrMalformedHash)
+		}
+		if err := Validate(encoded); err != ErrMalformedHash {
+			t.Errorf("Validate(%q) error = %v, want %v", encoded, err, ErrMalformedHash)
+		}
+	}
+	hash, _ := Hash("pw")
+	if err := Validate(hash); err != nil {
+		t.Errorf("Validate(Hash()) error = %v, want nil", err)
+	}
+}
+
+func TestPBKDF2_KnownVector(t *testing.T) {
+	// RFC 7914 section 11 PBKDF2-HMAC-SHA256 test vector.
+	got := hex.EncodeToString(pbkdf2([]byte("passwd"), []byte("salt"), 1, 64))
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	if got != want {
+		t.Errorf("pbkdf2() = %s, want %s", got, want)
+	}
+}
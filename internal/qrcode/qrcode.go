@@ -0,0 +1,523 @@
+// Package qrcode encodes byte strings as QR Code symbols (ISO/IEC 18004,
+// model 2, byte mode, versions 1-40). It has no dependencies beyond the
+// standard library.
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrTooLong = errors.New("data too long for a QR code")
+
+// Level is the error correction level: the share of the symbol that can be
+// damaged and still decode.
+type Level int
+
+const (
+	Low      Level = iota // ~7%
+	Medium                // ~15%
+	Quartile              // ~25%
+	High                  // ~30%
+)
+
+// ParseLevel parses "L", "M", "Q" or "H" (case-insensitive).
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return Low, nil
+	case "M":
+		return Medium, nil
+	case "Q":
+		return Quartile, nil
+	case "H":
+		return High, nil
+	}
+	return 0, fmt.Errorf("unknown error correction level %q", s)
+}
+
+// formatBits is the level's two-bit value in the format information.
+func (l Level) formatBits() int {
+	return [...]int{1, 0, 3, 2}[l]
+}
+
+const (
+	minVersion = 1
+	maxVersion = 40
+)
+
+// eccPerBlock and numBlocks are indexed by level then version (index 0
+// unused).
+var eccPerBlock = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+var numBlocks = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+// Code is an encoded QR symbol. Module (x, y) is dark when Dark returns true;
+// the quiet zone is not included.
+type Code struct {
+	version  int
+	level    Level
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func (c *Code) Size() int    { return c.size }
+func (c *Code) Version() int { return c.version }
+func (c *Code) Level() Level { return c.level }
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// Encode encodes data in byte mode using the smallest version that fits at
+// the given level.
+func Encode(data []byte, level Level) (*Code, error) {
+	if level < Low || level > High {
+		return nil, fmt.Errorf("invalid error correction level %d", level)
+	}
+	version := 0
+	for v := minVersion; v <= maxVersion; v++ {
+		if 4+charCountBits(v)+8*len(data) <= 8*dataCodewords(v, level) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrTooLong
+	}
+
+	var bb bitBuffer
+	bb.append(0x4, 4) // byte mode
+	bb.append(len(data), charCountBits(version))
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	capacity := 8 * dataCodewords(version, level)
+	bb.append(0, min(4, capacity-len(bb)))
+	bb.append(0, (8-len(bb)%8)%8)
+	for pad := 0xEC; len(bb) < capacity; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	c := newCode(version, level)
+	c.drawCodewords(c.addECCAndInterleave(bb.bytes()))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormatBits(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // XOR again to undo
+	}
+	c.applyMask(best)
+	c.drawFormatBits(best)
+	return c, nil
+}
+
+// newCode returns a symbol of the given version with all function patterns
+// drawn and format bits reserved.
+func newCode(version int, level Level) *Code {
+	size := version*4 + 17
+	c := &Code{
+		version:  version,
+		level:    level,
+		size:     size,
+		modules:  make([][]bool, size),
+		function: make([][]bool, size),
+	}
+	for i := range c.modules {
+		c.modules[i] = make([]bool, size)
+		c.function[i] = make([]bool, size)
+	}
+
+	for i := 0; i < size; i++ {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(size-4, 3)
+	c.drawFinder(3, size-4)
+
+	pos := alignmentPositions(version)
+	n := len(pos)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			// Skip the three corners occupied by finder patterns.
+			if (i == 0 && j == 0) || (i == 0 && j == n-1) || (i == n-1 && j == 0) {
+				continue
+			}
+			c.drawAlignment(pos[i], pos[j])
+		}
+	}
+
+	c.drawFormatBits(0) // reserve the area; overwritten once the mask is chosen
+	c.drawVersion()
+	return c
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+// drawFinder draws a finder pattern with its separator centred on (x, y).
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.size || yy < 0 || yy >= c.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func (c *Code) drawFormatBits(mask int) {
+	data := c.level.formatBits()<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	for i := 0; i <= 5; i++ {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.setFunction(c.size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.size-15+i, bit(bits, i))
+	}
+	c.setFunction(8, c.size-8, true) // always dark
+}
+
+func (c *Code) drawVersion() {
+	if c.version < 7 {
+		return
+	}
+	rem := c.version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := c.version<<12 | rem
+	for i := 0; i < 18; i++ {
+		a, b := c.size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// addECCAndInterleave splits data into the version's blocks, appends the
+// Reed-Solomon codewords to each and interleaves the result.
+func (c *Code) addECCAndInterleave(data []byte) []byte {
+	blocks := numBlocks[c.level][c.version]
+	eccLen := eccPerBlock[c.level][c.version]
+	raw := rawDataModules(c.version) / 8
+	short := blocks - raw%blocks
+	shortLen := raw / blocks
+
+	divisor := rsDivisor(eccLen)
+	out := make([][]byte, blocks)
+	k := 0
+	for i := range out {
+		n := shortLen - eccLen
+		if i >= short {
+			n++
+		}
+		dat := data[k : k+n]
+		k += n
+		block := make([]byte, 0, shortLen+1)
+		block = append(block, dat...)
+		if i < short {
+			block = append(block, 0) // placeholder, skipped when interleaving
+		}
+		out[i] = append(block, rsRemainder(dat, divisor)...)
+	}
+
+	result := make([]byte, 0, raw)
+	for i := range out[0] {
+		for j, block := range out {
+			if i != shortLen-eccLen || j >= short {
+				result = append(result, block[i])
+			}
+		}
+	}
+	return result
+}
+
+// drawCodewords places data in the zigzag order, skipping function modules.
+func (c *Code) drawCodewords(data []byte) {
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vert
+				}
+				if !c.function[y][x] && i < len(data)*8 {
+					c.modules[y][x] = bit(int(data[i>>3]), 7-(i&7))
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.function[y][x] && maskBit(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// penalty scores the symbol with the four rules of ISO/IEC 18004 section
+// 7.8.3; lower is better.
+func (c *Code) penalty() int {
+	n := c.size
+	score := 0
+	line := make([]bool, n)
+	for pass := 0; pass < 2; pass++ {
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if pass == 0 {
+					line[j] = c.modules[i][j]
+				} else {
+					line[j] = c.modules[j][i]
+				}
+			}
+			score += linePenalty(line)
+		}
+	}
+
+	for y := 0; y < n-1; y++ {
+		for x := 0; x < n-1; x++ {
+			d := c.modules[y][x]
+			if d == c.modules[y][x+1] && d == c.modules[y+1][x] && d == c.modules[y+1][x+1] {
+				score += 3
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			if c.modules[y][x] {
+				dark++
+			}
+		}
+	}
+	total := n * n
+	k := (abs(dark*20-total*10)+total-1)/total - 1
+	score += k * 10
+	return score
+}
+
+var finderLike = []bool{true, false, true, true, true, false, true}
+
+// linePenalty applies rule 1 (runs of five or more) and rule 3 (finder-like
+// 1:1:3:1:1 patterns next to four light modules) to one row or column.
+func linePenalty(line []bool) int {
+	score := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			score += 3 + run - 5
+		}
+		run = 1
+	}
+
+	light := func(from, to int) bool {
+		for i := from; i < to; i++ {
+			if i >= 0 && i < len(line) && line[i] {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i+len(finderLike) <= len(line); i++ {
+		match := true
+		for j, d := range finderLike {
+			if line[i+j] != d {
+				match = false
+				break
+			}
+		}
+		if match && (light(i-4, i) || light(i+7, i+11)) {
+			score += 40
+		}
+	}
+	return score
+}
+
+func alignmentPositions(version int) []int {
+	if version == 1 {
+		return nil
+	}
+	n := version/7 + 2
+	step := (version*8 + n*3 + 5) / (n*4 - 4) * 2
+	pos := make([]int, n)
+	pos[0] = 6
+	for i, p := n-1, version*4+17-7; i >= 1; i, p = i-1, p-step {
+		pos[i] = p
+	}
+	return pos
+}
+
+// rawDataModules is the number of modules available for data and ECC
+// codewords, including remainder bits.
+func rawDataModules(version int) int {
+	result := (16*version+128)*version + 64
+	if version >= 2 {
+		n := version/7 + 2
+		result -= (25*n-10)*n - 55
+		if version >= 7 {
+			result -= 36
+		}
+	}
+	return result
+}
+
+func dataCodewords(version int, level Level) int {
+	return rawDataModules(version)/8 - eccPerBlock[level][version]*numBlocks[level][version]
+}
+
+func charCountBits(version int) int {
+	if version <= 9 {
+		return 8
+	}
+	return 16
+}
+
+// rsDivisor returns the generator polynomial of the given degree, highest
+// coefficient first with the leading 1 omitted.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1.
+func gfMul(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(val, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, (val>>i)&1 != 0)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, v := range b {
+		if v {
+			out[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return out
+}
+
+func bit(x, i int) bool {
+	return (x>>i)&1 != 0
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,249 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// decode reads c back the way a scanner would: format bits, unmasking,
+// de-interleaving, a Reed-Solomon syndrome check per block and finally the
+// byte-mode payload.
+func decode(t *testing.T, c *Code) []byte {
+	t.Helper()
+	version := (c.size - 17) / 4
+
+	var format int
+	for i := 0; i <= 5; i++ {
+		format |= b2i(c.Dark(8, i)) << i
+	}
+	format |= b2i(c.Dark(8, 7)) << 6
+	format |= b2i(c.Dark(8, 8)) << 7
+	format |= b2i(c.Dark(7, 8)) << 8
+	for i := 9; i < 15; i++ {
+		format |= b2i(c.Dark(14-i, 8)) << i
+	}
+	level, mask := Level(-1), -1
+	for l := Low; l <= High; l++ {
+		for m := 0; m < 8; m++ {
+			ref := newCode(version, l)
+			ref.drawFormatBits(m)
+			var want int
+			for i := 0; i <= 5; i++ {
+				want |= b2i(ref.Dark(8, i)) << i
+			}
+			want |= b2i(ref.Dark(8, 7))<<6 | b2i(ref.Dark(8, 8))<<7 | b2i(ref.Dark(7, 8))<<8
+			for i := 9; i < 15; i++ {
+				want |= b2i(ref.Dark(14-i, 8)) << i
+			}
+			if want == format {
+				level, mask = l, m
+			}
+		}
+	}
+	if mask < 0 {
+		t.Fatalf("format bits %015b match no level/mask", format)
+	}
+	if level != c.Level() {
+		t.Fatalf("format level = %v, want %v", level, c.Level())
+	}
+
+	ref := newCode(version, level)
+	raw := rawDataModules(version) / 8
+	codewords := make([]byte, raw)
+	i := 0
+	for right := c.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < c.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = c.size - 1 - vert
+				}
+				if ref.function[y][x] || i >= raw*8 {
+					continue
+				}
+				if c.Dark(x, y) != maskBit(mask, x, y) {
+					codewords[i>>3] |= 1 << (7 - i&7)
+				}
+				i++
+			}
+		}
+	}
+
+	blocks := numBlocks[level][version]
+	eccLen := eccPerBlock[level][version]
+	short := blocks - raw%blocks
+	shortLen := raw / blocks
+	out := make([][]byte, blocks)
+	for j := range out {
+		out[j] = make([]byte, shortLen+1)
+	}
+	k := 0
+	for i := 0; i <= shortLen; i++ {
+		for j := range out {
+			if i != shortLen-eccLen || j >= short {
+				out[j][i] = codewords[k]
+				k++
+			}
+		}
+	}
+
+	var data []byte
+	for j, block := range out {
+		if j < short {
+			block = append(block[:shortLen-eccLen], block[shortLen-eccLen+1:]...)
+		}
+		// A valid codeword is divisible by the generator, so it evaluates
+		// to zero at each of its roots.
+		root := byte(1)
+		for n := 0; n < eccLen; n++ {
+			var sum byte
+			for _, b := range block {
+				sum = gfMul(sum, root) ^ b
+			}
+			if sum != 0 {
+				t.Fatalf("block %d: syndrome %d = %d, want 0", j, n, sum)
+			}
+			root = gfMul(root, 0x02)
+		}
+		data = append(data, block[:len(block)-eccLen]...)
+	}
+
+	var bits bitBuffer
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	read := func(n int) int {
+		v := 0
+		for _, b := range bits[:n] {
+			v = v<<1 | b2i(b)
+		}
+		bits = bits[n:]
+		return v
+	}
+	if mode := read(4); mode != 0x4 {
+		t.Fatalf("mode = %#x, want byte mode", mode)
+	}
+	n := read(charCountBits(version))
+	payload := make([]byte, n)
+	for i := range payload {
+		payload[i] = byte(read(8))
+	}
+	return payload
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestEncode_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		level Level
+	}{
+		{"short url", "https://sho.rt/abc123", Medium},
+		{"empty", "", Low},
+		{"quartile", "https://example.com/some/longer/path?with=query&and=more", Quartile},
+		{"high multi-block", strings.Repeat("x", 100), High},
+		{"version with version bits", strings.Repeat("y", 200), Medium},
+		{"large", strings.Repeat("z", 1500), Low},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Encode([]byte(tt.data), tt.level)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			if got := decode(t, c); string(got) != tt.data {
+				t.Errorf("decode(Encode(%q)) = %q", tt.data, got)
+			}
+		})
+	}
+}
+
+func TestEncode_Version(t *testing.T) {
+	tests := []struct {
+		n     int
+		level Level
+		want  int
+	}{
+		{17, Low, 1}, // 19 data codewords, 2 of them header
+		{18, Low, 2},
+		{14, Medium, 1},
+		{2953, Low, 40},
+		{1273, High, 40},
+	}
+	for _, tt := range tests {
+		c, err := Encode(make([]byte, tt.n), tt.level)
+		if err != nil {
+			t.Fatalf("Encode(%d bytes) error = %v", tt.n, err)
+		}
+		if c.Version() != tt.want {
+			t.Errorf("Encode(%d bytes, %v).Version() = %d, want %d", tt.n, tt.level, c.Version(), tt.want)
+		}
+		if c.Size() != tt.want*4+17 {
+			t.Errorf("Size() = %d, want %d", c.Size(), tt.want*4+17)
+		}
+	}
+}
+
+func TestEncode_TooLong(t *testing.T) {
+	if _, err := Encode(make([]byte, 2954), Low); err != ErrTooLong {
+		t.Errorf("Encode(2954 bytes) error = %v, want %v", err, ErrTooLong)
+	}
+	if _, err := Encode(make([]byte, 1274), High); err != ErrTooLong {
+		t.Errorf("Encode(1274 bytes, High) error = %v, want %v", err, ErrTooLong)
+	}
+}
+
+func TestEncode_FinderPatterns(t *testing.T) {
+	c, err := Encode([]byte("finder"), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"#######",
+		"#.....#",
+		"#.###.#",
+		"#.###.#",
+		"#.###.#",
+		"#.....#",
+		"#######",
+	}
+	for _, corner := range [][2]int{{0, 0}, {c.Size() - 7, 0}, {0, c.Size() - 7}} {
+		for dy, row := range want {
+			for dx, m := range row {
+				if c.Dark(corner[0]+dx, corner[1]+dy) != (m == '#') {
+					t.Fatalf("finder at %v wrong at (%d, %d)", corner, dx, dy)
+				}
+			}
+		}
+	}
+}
+
+func TestRSRemainder_KnownVector(t *testing.T) {
+	// "HELLO WORLD" at 1-M, as worked through in the Thonky QR tutorial.
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder() = %v, want %v", got, want)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]Level{"L": Low, "m": Medium, "Q": Quartile, "h": High} {
+		if got, err := ParseLevel(in); err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseLevel("X"); err == nil {
+		t.Error("ParseLevel(X) error = nil, want error")
+	}
+}
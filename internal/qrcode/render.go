@@ -0,0 +1,66 @@
+package qrcode
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// Image renders the code with margin light modules of quiet zone on each
+// side, scale pixels per module.
+func (c *Code) Image(scale, margin int) image.Image {
+	scale = max(scale, 1)
+	side := (c.size + 2*margin) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			x0, y0 := (x+margin)*scale, (y+margin)*scale
+			for py := y0; py < y0+scale; py++ {
+				for px := x0; px < x0+scale; px++ {
+					img.SetColorIndex(px, py, 1)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// WritePNG writes the code as a PNG at most size pixels wide: the largest
+// whole number of pixels per module that fits. When size is smaller than the
+// code, modules are one pixel each and the image is wider than size.
+func (c *Code) WritePNG(w io.Writer, size, margin int) error {
+	return png.Encode(w, c.Image(size/(c.size+2*margin), margin))
+}
+
+// WriteSVG writes the code as an SVG document size pixels wide. Dark modules
+// are drawn as a single path, one unit per module.
+func (c *Code) WriteSVG(w io.Writer, size, margin int) error {
+	bw := bufio.NewWriter(w)
+	side := c.size + 2*margin
+	fmt.Fprintf(bw, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		size, size, side, side)
+	fmt.Fprintf(bw, `<rect width="100%%" height="100%%" fill="#FFFFFF"/>`+"\n")
+	fmt.Fprint(bw, `<path fill="#000000" d="`)
+	first := true
+	for y := 0; y < c.size; y++ {
+		for x := 0; x < c.size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			if !first {
+				bw.WriteByte(' ')
+			}
+			first = false
+			fmt.Fprintf(bw, "M%d,%dh1v1h-1z", x+margin, y+margin)
+		}
+	}
+	fmt.Fprint(bw, "\"/>\n</svg>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,71 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWritePNG(t *testing.T) {
+	c, err := Encode([]byte("https://sho.rt/abc"), Medium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.WritePNG(&buf, 256, 4); err != nil {
+		t.Fatalf("WritePNG() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	modules := c.Size() + 8
+	scale := 256 / modules
+	if got := img.Bounds().Dx(); got != modules*scale {
+		t.Errorf("width = %d, want %d", got, modules*scale)
+	}
+	// The quiet zone is light and the top-left finder corner dark.
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("quiet zone pixel is dark")
+	}
+	if r, _, _, _ := img.At(4*scale, 4*scale).RGBA(); r != 0 {
+		t.Error("finder corner pixel is light")
+	}
+}
+
+func TestWritePNG_TinySize(t *testing.T) {
+	c, _ := Encode([]byte("x"), Low)
+	var buf bytes.Buffer
+	if err := c.WritePNG(&buf, 1, 0); err != nil {
+		t.Fatalf("WritePNG() error = %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Bounds().Dx(); got != c.Size() {
+		t.Errorf("width = %d, want one pixel per module (%d)", got, c.Size())
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	c, _ := Encode([]byte("https://sho.rt/abc"), Medium)
+	var buf bytes.Buffer
+	if err := c.WriteSVG(&buf, 300, 2); err != nil {
+		t.Fatalf("WriteSVG() error = %v", err)
+	}
+	svg := buf.String()
+	side := c.Size() + 4
+	for _, want := range []string{
+		`width="300" height="300"`,
+		`viewBox="0 0 ` + strconv.Itoa(side) + ` ` + strconv.Itoa(side) + `"`,
+		"M2,2h1v1h-1z", // top-left finder module, offset by the margin
+		"</svg>",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("WriteSVG() output missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+
+	"assignment_infracloud/internal/storage"
+)
+
+type (
+	tenantKey    struct{}
+	domainKey    struct{}
+	actorKey     struct{}
+	requestIDKey struct{}
+)
+
+// WithTenant scopes Shortener calls made with the returned context to a
+// tenant's links. The empty tenant is the default one.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant set by WithTenant, or "" for the default
+// tenant.
+func TenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// WithDomain scopes Shortener calls made with the returned context to the
+// short domain; codes on different domains are independent. The empty
+// domain is the default one.
+func WithDomain(ctx context.Context, domain string) context.Context {
+	return context.WithValue(ctx, domainKey{}, domain)
+}
+
+// DomainFrom returns the short domain set by WithDomain, or "" for the
+// default domain.
+func DomainFrom(ctx context.Context) string {
+	domain, _ := ctx.Value(domainKey{}).(string)
+	return domain
+}
+
+// WithActor records who is making the Shortener calls made with the
+// returned context, for link histories.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set by WithActor, or "" when unknown.
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// WithRequestID tags Shortener calls made with the returned context with the
+// ID of the request that caused them, for the audit log.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFrom returns the ID set by WithRequestID, or "".
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// linkKey is the store key of code in the tenant and domain of ctx.
+func linkKey(ctx context.Context, code string) string {
+	return storage.Key(TenantFrom(ctx), DomainFrom(ctx), code)
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDomainFrom(t *testing.T) {
+	if got := DomainFrom(context.Background()); got != "" {
+		t.Errorf("DomainFrom(background) = %q, want default domain", got)
+	}
+	if got := DomainFrom(WithDomain(context.Background(), "acme.link")); got != "acme.link" {
+		t.Errorf("DomainFrom() = %q, want %q", got, "acme.link")
+	}
+}
+
+func TestActorFrom(t *testing.T) {
+	if got := ActorFrom(context.Background()); got != "" {
+		t.Errorf("ActorFrom(background) = %q, want unknown", got)
+	}
+	if got := ActorFrom(WithActor(context.Background(), "key:1a2b3c4d")); got != "key:1a2b3c4d" {
+		t.Errorf("ActorFrom() = %q, want %q", got, "key:1a2b3c4d")
+	}
+}
+
+func TestRequestIDFrom(t *testing.T) {
+	if got := RequestIDFrom(WithRequestID(context.Background(), "r1")); got != "r1" {
+		t.Errorf("RequestIDFrom() = %q, want %q", got, "r1")
+	}
+}
+
+func TestLinkKey(t *testing.T) {
+	ctx := WithDomain(WithTenant(context.Background(), "acme"), "acme.link")
+	if got := linkKey(ctx, "abc"); got != "acme@acme.link/abc" {
+		t.Errorf("linkKey() = %q, want %q", got, "acme@acme.link/abc")
+	}
+	if got := linkKey(context.Background(), "abc"); got != "abc" {
+		t.Errorf("linkKey(default) = %q, want %q", got, "abc")
+	}
+}
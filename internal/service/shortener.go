@@ -2,48 +2,471 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"net/url"
+	"strings"
+	"time"
 
+	"assignment_infracloud/internal/analytics"
 	"assignment_infracloud/internal/encoding"
+	"assignment_infracloud/internal/locale"
+	"assignment_infracloud/internal/password"
 	"assignment_infracloud/internal/storage"
+	"assignment_infracloud/internal/useragent"
 )
 
-var ErrInvalidURL = errors.New("invalid url")
+var (
+	ErrInvalidURL       = errors.New("invalid url")
+	ErrInvalidMaxClicks = errors.New("invalid max_clicks")
+	ErrInvalidConflict  = errors.New("invalid query_conflict")
+	ErrInvalidRule      = errors.New("invalid device rule")
+	ErrInvalidLocale    = errors.New("invalid locale rule")
+	ErrInvalidVariant   = errors.New("invalid variant")
+	ErrInvalidSchedule  = errors.New("invalid schedule entry")
+	ErrInvalidTag       = errors.New("invalid tag")
+	ErrInvalidCampaign  = errors.New("invalid campaign")
+	ErrExhausted        = storage.ErrExhausted
+)
+
+// Options carries the optional per-link settings accepted at creation time.
+type Options struct {
+	Password string
+	// MaxClicks makes the link stop resolving after that many visits.
+	MaxClicks int
+	// RequireSignature only lets signed visits through.
+	RequireSignature bool
+	Passthrough      storage.Passthrough
+	UTM              storage.UTM
+	DeviceRules      []storage.DeviceRule
+	LocaleRules      []storage.LocaleRule
+	Variants         []storage.Variant
+	Interstitial     bool
+	Tags             []string
+	Campaign         string
+}
 
 type Shortener interface {
 	Shorten(ctx context.Context, longURL string) (string, error)
-	Resolve(ctx context.Context, code string) (string, error)
+	ShortenWithOptions(ctx context.Context, longURL string, opts Options) (string, error)
+	// Resolve counts a visit and returns the destination, and whether an
+	// active schedule entry supplied it.
+	Resolve(ctx context.Context, code string) (string, bool, error)
+	// Now is the clock schedules are evaluated against.
+	Now() time.Time
+	Lookup(ctx context.Context, code string) (storage.Link, error)
+	SetLocaleRules(ctx context.Context, code string, rules []storage.LocaleRule) (storage.Link, error)
+	RecordVariant(ctx context.Context, code, variant string)
+	Stats(ctx context.Context, code string) (storage.Link, error)
+	AddScheduleEntry(ctx context.Context, code string, entry storage.ScheduleEntry) (storage.ScheduleEntry, error)
+	RemoveScheduleEntry(ctx context.Context, code, id string) error
+	// History lists every version of the link, oldest first.
+	History(ctx context.Context, code string) ([]storage.Version, error)
+	// Rollback restores the settings of an earlier version.
+	Rollback(ctx context.Context, code string, version int) (storage.Link, error)
+	// Delete removes the link. Its history is kept and ends in a version
+	// recording the deletion.
+	Delete(ctx context.Context, code string) error
+	// Links returns every link of the tenant, on all its domains, oldest
+	// first.
+	Links(ctx context.Context) []storage.Link
+	AddTags(ctx context.Context, code string, tags []string) (storage.Link, error)
+	RemoveTag(ctx context.Context, code, tag string) (storage.Link, error)
+	LinksByTag(ctx context.Context, tag string) []storage.Link
+	LinksByCampaign(ctx context.Context, campaign string) []storage.Link
+	TagStats(ctx context.Context, tag string) storage.GroupStats
+	CampaignStats(ctx context.Context, campaign string) storage.GroupStats
 	GetTopDomains(ctx context.Context, limit int) []storage.DomainStats
+	// Export returns the links of every tenant, oldest first.
+	Export(ctx context.Context) []storage.Link
+	// Import stores a link from an export under its own tenant, domain and
+	// code.
+	Import(ctx context.Context, link storage.Link) (storage.Link, error)
+	// Flush writes buffered analytics to the store, e.g. before it is saved
+	// at shutdown.
+	Flush()
 }
 
+// Hooks are optional callbacks run after link events. They are called on the
+// request path and must hand slow work off to a goroutine.
+type Hooks struct {
+	// Created is called with every newly stored link; deduplicated shortens
+	// that return an existing code do not trigger it.
+	Created func(link storage.Link)
+	// Clicked is called after every counted visit with the link as
+	// stored, its click count included.
+	Clicked func(link storage.Link)
+	// Expired is called once, after the visit that uses up a click-limited
+	// link.
+	Expired func(link storage.Link)
+	// Changed is called after every change made through the Shortener with
+	// the action (ActionCreate, ActionEdit, ActionRollback, ActionDelete or
+	// ActionImport) and the link as stored, or as it was for deletions. ctx
+	// is the caller's and carries its actor and request ID.
+	Changed func(ctx context.Context, action string, link storage.Link)
+}
+
+// Actions passed to Hooks.Changed.
+const (
+	ActionCreate   = "link.create"
+	ActionEdit     = "link.edit"
+	ActionRollback = "link.rollback"
+	ActionDelete   = "link.delete"
+	ActionImport   = "link.import"
+)
+
+// variantFlushEvery bounds how many variant clicks are buffered before they
+// are written to the store.
+const variantFlushEvery = 256
+
 type InMemoryShortener struct {
-	store *storage.InMemoryStore
+	store    *storage.InMemoryStore
+	variants *analytics.Recorder
+	now      func() time.Time
+	hooks    Hooks
 }
 
 func NewInMemoryShortener(store *storage.InMemoryStore) Shortener {
-	return &InMemoryShortener{store: store}
+	return NewInMemoryShortenerWithClock(store, time.Now)
+}
+
+// NewInMemoryShortenerWithClock is NewInMemoryShortener with the source of
+// the current time replaced, so schedules can be tested.
+func NewInMemoryShortenerWithClock(store *storage.InMemoryStore, now func() time.Time) Shortener {
+	return newInMemoryShortener(store, now, Hooks{})
+}
+
+// NewInMemoryShortenerWithHooks is NewInMemoryShortener with callbacks for
+// link events.
+func NewInMemoryShortenerWithHooks(store *storage.InMemoryStore, hooks Hooks) Shortener {
+	return newInMemoryShortener(store, time.Now, hooks)
+}
+
+func newInMemoryShortener(store *storage.InMemoryStore, now func() time.Time, hooks Hooks) *InMemoryShortener {
+	return &InMemoryShortener{
+		store:    store,
+		variants: analytics.NewRecorder(store, variantFlushEvery),
+		now:      now,
+		hooks:    hooks,
+	}
 }
 
 func (s *InMemoryShortener) Shorten(ctx context.Context, longURL string) (string, error) {
+	return s.ShortenWithOptions(ctx, longURL, Options{})
+}
+
+func (s *InMemoryShortener) ShortenWithOptions(ctx context.Context, longURL string, opts Options) (string, error) {
 	if !isValidURL(longURL) {
 		return "", ErrInvalidURL
 	}
-	if code, err := s.store.GetCode(longURL); err == nil {
-		return code, nil
+	if opts.MaxClicks < 0 {
+		return "", ErrInvalidMaxClicks
+	}
+	if !validConflict(opts.Passthrough.QueryConflict) {
+		return "", ErrInvalidConflict
+	}
+	if !validDeviceRules(opts.DeviceRules) {
+		return "", ErrInvalidRule
+	}
+	localeRules, err := normalizeLocaleRules(opts.LocaleRules)
+	if err != nil {
+		return "", err
+	}
+	variants, err := normalizeVariants(opts.Variants)
+	if err != nil {
+		return "", err
+	}
+	tags, err := normalizeTags(opts.Tags)
+	if err != nil {
+		return "", err
+	}
+	campaign := strings.ToLower(strings.TrimSpace(opts.Campaign))
+	if campaign != "" && !validGroupName(campaign) {
+		return "", ErrInvalidCampaign
+	}
+	link := storage.Link{
+		Tenant:           TenantFrom(ctx),
+		Domain:           DomainFrom(ctx),
+		URL:              longURL,
+		CreatedAt:        s.now(),
+		CreatedBy:        ActorFrom(ctx),
+		MaxClicks:        opts.MaxClicks,
+		RequireSignature: opts.RequireSignature,
+		Passthrough:      opts.Passthrough,
+		UTM:              opts.UTM,
+		DeviceRules:      opts.DeviceRules,
+		LocaleRules:      localeRules,
+		Variants:         variants,
+		Interstitial:     opts.Interstitial,
+		Tags:             tags,
+		Campaign:         campaign,
+	}
+	if opts.Password != "" {
+		hash, err := password.Hash(opts.Password)
+		if err != nil {
+			return "", fmt.Errorf("hash password: %w", err)
+		}
+		link.PasswordHash = hash
+	}
+	if key, ok := link.DedupKey(); ok {
+		if code, err := s.store.GetCode(key); err == nil {
+			return code, nil
+		}
 	}
-	id := s.store.NextID()
-	code := encoding.Base62Encode(id)
-	s.store.SaveMapping(code, longURL)
-	return code, nil
+	// Imported codes may sit anywhere in the generated range; skip them.
+	for {
+		id, err := s.store.NextID()
+		if err != nil {
+			return "", err
+		}
+		link.Code = encoding.Base62Encode(id)
+		if s.store.InsertLink(link) == nil {
+			break
+		}
+	}
+	if s.hooks.Created != nil {
+		s.hooks.Created(link)
+	}
+	s.changed(ctx, ActionCreate, link)
+	return link.Code, nil
 }
 
-func (s *InMemoryShortener) Resolve(ctx context.Context, code string) (string, error) {
-	return s.store.GetURL(code)
+// Resolve counts a visit to code and returns its current destination, taking
+// the link's schedule into account, and whether a schedule entry supplied it.
+// Click-limited links return ErrExhausted once used up.
+func (s *InMemoryShortener) Resolve(ctx context.Context, code string) (string, bool, error) {
+	link, err := s.store.Visit(linkKey(ctx, code))
+	if err != nil {
+		return "", false, err
+	}
+	if s.hooks.Clicked != nil {
+		s.hooks.Clicked(link)
+	}
+	if remaining, limited := link.Remaining(); limited && remaining == 0 && s.hooks.Expired != nil {
+		s.hooks.Expired(link)
+	}
+	now := s.now()
+	return link.DestinationAt(now), link.ScheduledAt(now), nil
+}
+
+// Now returns the current time as the service sees it.
+func (s *InMemoryShortener) Now() time.Time {
+	return s.now()
+}
+
+// Lookup returns the stored record for code without counting it as a visit.
+func (s *InMemoryShortener) Lookup(ctx context.Context, code string) (storage.Link, error) {
+	return s.store.GetLink(linkKey(ctx, code))
+}
+
+// SetLocaleRules replaces the locale rules of the link stored under code.
+func (s *InMemoryShortener) SetLocaleRules(ctx context.Context, code string, rules []storage.LocaleRule) (storage.Link, error) {
+	rules, err := normalizeLocaleRules(rules)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	return s.edit(ctx, code, func(link *storage.Link) error {
+		link.LocaleRules = rules
+		return nil
+	})
+}
+
+// RecordVariant counts a visit to code that was served variant.
+func (s *InMemoryShortener) RecordVariant(ctx context.Context, code, variant string) {
+	s.variants.Record(linkKey(ctx, code), variant)
+}
+
+func (s *InMemoryShortener) Flush() {
+	s.variants.Flush()
+}
+
+// Stats returns the link with all buffered analytics applied.
+func (s *InMemoryShortener) Stats(ctx context.Context, code string) (storage.Link, error) {
+	s.variants.Flush()
+	return s.store.GetLink(linkKey(ctx, code))
+}
+
+// AddScheduleEntry appends entry to the link's schedule and returns it with
+// its generated ID.
+func (s *InMemoryShortener) AddScheduleEntry(ctx context.Context, code string, entry storage.ScheduleEntry) (storage.ScheduleEntry, error) {
+	if !validScheduleEntry(entry) {
+		return storage.ScheduleEntry{}, ErrInvalidSchedule
+	}
+	id, err := newScheduleID()
+	if err != nil {
+		return storage.ScheduleEntry{}, err
+	}
+	entry.ID = id
+	_, err = s.edit(ctx, code, func(link *storage.Link) error {
+		link.Schedule = append(append([]storage.ScheduleEntry(nil), link.Schedule...), entry)
+		return nil
+	})
+	if err != nil {
+		return storage.ScheduleEntry{}, err
+	}
+	return entry, nil
+}
+
+// RemoveScheduleEntry deletes the entry with id from the link's schedule.
+func (s *InMemoryShortener) RemoveScheduleEntry(ctx context.Context, code, id string) error {
+	_, err := s.edit(ctx, code, func(link *storage.Link) error {
+		var kept []storage.ScheduleEntry
+		for _, e := range link.Schedule {
+			if e.ID != id {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == len(link.Schedule) {
+			return storage.ErrNotFound
+		}
+		link.Schedule = kept
+		return nil
+	})
+	return err
+}
+
+func (s *InMemoryShortener) History(ctx context.Context, code string) ([]storage.Version, error) {
+	return s.store.History(linkKey(ctx, code))
+}
+
+// Rollback restores the settings the link had at version, recording the
+// rollback as a new version. Unknown versions fail with storage.ErrNotFound.
+func (s *InMemoryShortener) Rollback(ctx context.Context, code string, version int) (storage.Link, error) {
+	link, err := s.store.RollbackLink(linkKey(ctx, code), ActorFrom(ctx), s.now(), version)
+	if err != nil {
+		return link, err
+	}
+	s.changed(ctx, ActionRollback, link)
+	return link, nil
+}
+
+// Delete removes the link stored under code, recording the deletion in its
+// history. Its code is not handed out again.
+func (s *InMemoryShortener) Delete(ctx context.Context, code string) error {
+	link, err := s.store.DeleteLink(linkKey(ctx, code), ActorFrom(ctx), s.now())
+	if err != nil {
+		return err
+	}
+	s.changed(ctx, ActionDelete, link)
+	return nil
+}
+
+// edit applies fn to the link stored under code on behalf of the caller,
+// versioning the change and reporting it to Hooks.Changed.
+func (s *InMemoryShortener) edit(ctx context.Context, code string, fn func(*storage.Link) error) (storage.Link, error) {
+	link, err := s.store.EditLink(linkKey(ctx, code), ActorFrom(ctx), s.now(), fn)
+	if err != nil {
+		return link, err
+	}
+	s.changed(ctx, ActionEdit, link)
+	return link, nil
+}
+
+func (s *InMemoryShortener) changed(ctx context.Context, action string, link storage.Link) {
+	if s.hooks.Changed != nil {
+		s.hooks.Changed(ctx, action, link)
+	}
+}
+
+func (s *InMemoryShortener) Links(ctx context.Context) []storage.Link {
+	tenant := TenantFrom(ctx)
+	var links []storage.Link
+	for _, link := range s.store.Links() {
+		if link.Tenant == tenant {
+			links = append(links, link)
+		}
+	}
+	return links
 }
 
 func (s *InMemoryShortener) GetTopDomains(ctx context.Context, limit int) []storage.DomainStats {
-	return s.store.GetTopDomains(limit)
+	return s.store.TopDomains(TenantFrom(ctx), limit)
+}
+
+// normalizeLocaleRules validates rules and returns a copy with countries
+// upper-cased.
+func normalizeLocaleRules(rules []storage.LocaleRule) ([]storage.LocaleRule, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	out := make([]storage.LocaleRule, len(rules))
+	for i, rule := range rules {
+		if rule.Language == "" && rule.Country == "" {
+			return nil, ErrInvalidLocale
+		}
+		if rule.Language != "" && !locale.ValidTag(rule.Language) {
+			return nil, ErrInvalidLocale
+		}
+		rule.Country = strings.ToUpper(rule.Country)
+		if rule.Country != "" && !isCountryCode(rule.Country) {
+			return nil, ErrInvalidLocale
+		}
+		if !isValidURL(rule.URL) {
+			return nil, ErrInvalidLocale
+		}
+		out[i] = rule
+	}
+	return out, nil
+}
+
+// normalizeVariants validates variants, names unnamed ones "a", "b", ... by
+// position and resets their click counts.
+func normalizeVariants(variants []storage.Variant) ([]storage.Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	out := make([]storage.Variant, len(variants))
+	seen := make(map[string]bool)
+	for i, v := range variants {
+		if v.Name == "" && i < 26 {
+			v.Name = string(rune('a' + i))
+		}
+		if v.Name == "" || seen[v.Name] || v.Weight <= 0 || !isValidURL(v.URL) {
+			return nil, ErrInvalidVariant
+		}
+		seen[v.Name] = true
+		v.Clicks = 0
+		out[i] = v
+	}
+	return out, nil
+}
+
+func validConflict(policy string) bool {
+	switch policy {
+	case "", storage.ConflictOverride, storage.ConflictKeep, storage.ConflictAppend:
+		return true
+	}
+	return false
+}
+
+func validDeviceRules(rules []storage.DeviceRule) bool {
+	for _, rule := range rules {
+		if !useragent.ValidTarget(rule.Platform) || !isValidURL(rule.URL) {
+			return false
+		}
+	}
+	return true
+}
+
+// validScheduleEntry requires a destination and at least one bound, with End
+// after Start when both are set.
+func validScheduleEntry(e storage.ScheduleEntry) bool {
+	return isValidURL(e.URL) && (!e.Start.IsZero() || !e.End.IsZero()) &&
+		(e.Start.IsZero() || e.End.IsZero() || e.End.After(e.Start))
+}
+
+func newScheduleID() (string, error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("schedule id: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func isCountryCode(c string) bool {
+	return len(c) == 2 && c[0] >= 'A' && c[0] <= 'Z' && c[1] >= 'A' && c[1] <= 'Z'
 }
 
 func isValidURL(u string) bool {
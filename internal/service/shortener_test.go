@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"assignment_infracloud/internal/storage"
 
@@ -96,7 +97,7 @@ func TestInMemoryShortener_Resolve_ValidCode(t *testing.T) {
 		t.Fatalf("Shorten() error = %v", err)
 	}
 
-	resolvedURL, err := shortener.Resolve(ctx, code)
+	resolvedURL, _, err := shortener.Resolve(ctx, code)
 	if err != nil {
 		t.Errorf("Resolve() error = %v", err)
 	}
@@ -139,7 +140,7 @@ func TestInMemoryShortener_ShortenAndResolve_Flow(t *testing.T) {
 
 	// Resolve all codes
 	for i, code := range codes {
-		resolvedURL, err := shortener.Resolve(ctx, code)
+		resolvedURL, _, err := shortener.Resolve(ctx, code)
 		if err != nil {
 			t.Errorf("Resolve(%s) error = %v", code, err)
 		}
@@ -236,3 +237,329 @@ func BenchmarkGetTopDomain(b *testing.B) {
 	}
 
 }
+
+func TestInMemoryShortener_ShortenWithOptions_Password(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := NewInMemoryShortener(store)
+	ctx := context.Background()
+
+	url := "https://example.com/private"
+	plain, _ := shortener.Shorten(ctx, url)
+	protected, err := shortener.ShortenWithOptions(ctx, url, Options{Password: "pw"})
+	if err != nil {
+		t.Fatalf("ShortenWithOptions() error = %v", err)
+	}
+	if protected == plain {
+		t.Error("protected link reused the code of the plain link")
+	}
+
+	link, err := shortener.Lookup(ctx, protected)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if link.PasswordHash == "" || link.PasswordHash == "pw" {
+		t.Errorf("Lookup().PasswordHash = %q, want a salted hash", link.PasswordHash)
+	}
+
+	// A later plain Shorten must still return the plain code.
+	again, _ := shortener.Shorten(ctx, url)
+	assert.Equal(t, again, plain)
+}
+
+func TestInMemoryShortener_Resolve_MaxClicks(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := NewInMemoryShortener(store)
+	ctx := context.Background()
+
+	code, err := shortener.ShortenWithOptions(ctx, "https://example.com/invite", Options{MaxClicks: 2})
+	if err != nil {
+		t.Fatalf("ShortenWithOptions() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := shortener.Resolve(ctx, code); err != nil {
+			t.Fatalf("Resolve() #%d error = %v", i+1, err)
+		}
+	}
+	if _, _, err := shortener.Resolve(ctx, code); err != ErrExhausted {
+		t.Errorf("Resolve() error = %v, want %v", err, ErrExhausted)
+	}
+
+	if _, err := shortener.ShortenWithOptions(ctx, "https://example.com/invite", Options{MaxClicks: -1}); err != ErrInvalidMaxClicks {
+		t.Errorf("ShortenWithOptions() error = %v, want %v", err, ErrInvalidMaxClicks)
+	}
+}
+
+func TestInMemoryShortener_ShortenWithOptions_UTMDedup(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := NewInMemoryShortener(store)
+	ctx := context.Background()
+
+	url := "https://example.com/landing"
+	plain, _ := shortener.Shorten(ctx, url)
+	spring, _ := shortener.ShortenWithOptions(ctx, url, Options{UTM: storage.UTM{Source: "mail", Campaign: "spring"}})
+	summer, _ := shortener.ShortenWithOptions(ctx, url, Options{UTM: storage.UTM{Source: "mail", Campaign: "summer"}})
+	springAgain, _ := shortener.ShortenWithOptions(ctx, url, Options{UTM: storage.UTM{Source: "mail", Campaign: "spring"}})
+
+	if plain == spring || spring == summer {
+		t.Errorf("codes plain=%s spring=%s summer=%s should all differ", plain, spring, summer)
+	}
+	assert.Equal(t, springAgain, spring)
+}
+
+func TestInMemoryShortener_ShortenWithOptions_Variants(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := NewInMemoryShortener(store)
+	ctx := context.Background()
+
+	code, err := shortener.ShortenWithOptions(ctx, "https://example.com", Options{Variants: []storage.Variant{
+		{URL: "https://example.com/a", Weight: 1},
+		{URL: "https://example.com/b", Weight: 2},
+	}})
+	if err != nil {
+		t.Fatalf("ShortenWithOptions() error = %v", err)
+	}
+	shortener.RecordVariant(ctx, code, "b")
+	link, err := shortener.Stats(ctx, code)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	assert.Equal(t, link.Variants[0].Name, "a")
+	assert.Equal(t, link.Variants[1].Name, "b")
+	assert.Equal(t, link.Variants[1].Clicks, 1)
+
+	shortener.RecordVariant(ctx, code, "a")
+	shortener.Flush()
+	stored, _ := store.GetLink(code)
+	assert.Equal(t, stored.Variants[0].Clicks, 1)
+
+	invalid := [][]storage.Variant{
+		{{Name: "x", URL: "https://example.com/a", Weight: 0}},
+		{{Name: "x", URL: "not-a-url", Weight: 1}},
+		{{Name: "x", URL: "https://example.com/a", Weight: 1}, {Name: "x", URL: "https://example.com/b", Weight: 1}},
+	}
+	for _, variants := range invalid {
+		if _, err := shortener.ShortenWithOptions(ctx, "https://example.com", Options{Variants: variants}); err != ErrInvalidVariant {
+			t.Errorf("ShortenWithOptions(%+v) error = %v, want %v", variants, err, ErrInvalidVariant)
+		}
+	}
+}
+
+func TestInMemoryShortener_Schedule(t *testing.T) {
+	now := time.Date(2026, 6, 1, 8, 0, 0, 0, time.UTC)
+	shortener := NewInMemoryShortenerWithClock(storage.NewInMemoryStore(), func() time.Time { return now })
+	ctx := context.Background()
+
+	code, _ := shortener.Shorten(ctx, "https://example.com/teaser")
+	launch := now.Add(time.Hour)
+	entry, err := shortener.AddScheduleEntry(ctx, code, storage.ScheduleEntry{URL: "https://example.com/live", Start: launch})
+	if err != nil {
+		t.Fatalf("AddScheduleEntry() error = %v", err)
+	}
+	if entry.ID == "" {
+		t.Error("AddScheduleEntry() returned an entry without ID")
+	}
+
+	got, _, _ := shortener.Resolve(ctx, code)
+	assert.Equal(t, got, "https://example.com/teaser")
+
+	now = launch
+	got, _, _ = shortener.Resolve(ctx, code)
+	assert.Equal(t, got, "https://example.com/live")
+
+	if err := shortener.RemoveScheduleEntry(ctx, code, entry.ID); err != nil {
+		t.Fatalf("RemoveScheduleEntry() error = %v", err)
+	}
+	got, _, _ = shortener.Resolve(ctx, code)
+	assert.Equal(t, got, "https://example.com/teaser")
+
+	if err := shortener.RemoveScheduleEntry(ctx, code, entry.ID); err != storage.ErrNotFound {
+		t.Errorf("RemoveScheduleEntry() twice error = %v, want %v", err, storage.ErrNotFound)
+	}
+
+	invalid := []storage.ScheduleEntry{
+		{URL: "https://example.com/x"},
+		{URL: "not-a-url", Start: now},
+		{URL: "https://example.com/x", Start: now, End: now},
+	}
+	for _, e := range invalid {
+		if _, err := shortener.AddScheduleEntry(ctx, code, e); err != ErrInvalidSchedule {
+			t.Errorf("AddScheduleEntry(%+v) error = %v, want %v", e, err, ErrInvalidSchedule)
+		}
+	}
+}
+
+func TestInMemoryShortener_Schedule_LeavesDedup(t *testing.T) {
+	shortener := NewInMemoryShortener(storage.NewInMemoryStore())
+	ctx := context.Background()
+
+	code, _ := shortener.Shorten(ctx, "https://example.com/a")
+	shortener.AddScheduleEntry(ctx, code, storage.ScheduleEntry{URL: "https://example.com/b", Start: time.Now()})
+
+	again, _ := shortener.Shorten(ctx, "https://example.com/a")
+	if again == code {
+		t.Error("Shorten() reused a link that now has a schedule")
+	}
+}
+
+func TestInMemoryShortener_Hooks_Created(t *testing.T) {
+	var created []string
+	shortener := NewInMemoryShortenerWithHooks(storage.NewInMemoryStore(), Hooks{
+		Created: func(link storage.Link) { created = append(created, link.Code+" "+link.URL) },
+	})
+	ctx := context.Background()
+
+	code, _ := shortener.Shorten(ctx, "https://example.com/a")
+	shortener.Shorten(ctx, "https://example.com/a") // deduplicated
+	shortener.Shorten(ctx, "not a url")
+
+	assert.DeepEqual(t, created, []string{code + " https://example.com/a"})
+}
+
+func TestInMemoryShortener_Hooks_ClickedExpired(t *testing.T) {
+	var clicks, expired []string
+	shortener := NewInMemoryShortenerWithHooks(storage.NewInMemoryStore(), Hooks{
+		Clicked: func(link storage.Link) { clicks = append(clicks, fmt.Sprintf("%s:%d", link.Code, link.Clicks)) },
+		Expired: func(link storage.Link) { expired = append(expired, link.Code) },
+	})
+	ctx := context.Background()
+
+	code, _ := shortener.ShortenWithOptions(ctx, "https://example.com", Options{MaxClicks: 2})
+	for i := 0; i < 3; i++ {
+		shortener.Resolve(ctx, code)
+	}
+
+	assert.DeepEqual(t, clicks, []string{code + ":1", code + ":2"})
+	assert.DeepEqual(t, expired, []string{code})
+}
+
+func TestInMemoryShortener_Hooks_Changed(t *testing.T) {
+	var events []string
+	shortener := NewInMemoryShortenerWithHooks(storage.NewInMemoryStore(), Hooks{
+		Changed: func(ctx context.Context, action string, link storage.Link) {
+			events = append(events, ActorFrom(ctx)+" "+RequestIDFrom(ctx)+" "+action+" "+link.Code)
+		},
+	})
+	ctx := WithRequestID(WithActor(context.Background(), "alice"), "r1")
+
+	code, _ := shortener.Shorten(ctx, "https://example.com/a")
+	shortener.Shorten(ctx, "https://example.com/a") // deduplicated
+	shortener.AddTags(ctx, code, []string{"launch"})
+	shortener.AddTags(ctx, "missing", []string{"launch"})
+	shortener.Rollback(ctx, code, 1)
+	shortener.Delete(ctx, code)
+	shortener.Delete(ctx, code)
+
+	assert.DeepEqual(t, events, []string{
+		"alice r1 link.create " + code,
+		"alice r1 link.edit " + code,
+		"alice r1 link.rollback " + code,
+		"alice r1 link.delete " + code,
+	})
+}
+
+func TestInMemoryShortener_Domains(t *testing.T) {
+	shortener := NewInMemoryShortener(storage.NewInMemoryStore())
+	ctx := context.Background()
+	acme := WithDomain(ctx, "acme.link")
+
+	plain, _ := shortener.Shorten(ctx, "https://example.com/x")
+	branded, _ := shortener.Shorten(acme, "https://example.com/x")
+	if plain == branded {
+		t.Fatalf("same URL on two domains got code %s twice", plain)
+	}
+	again, _ := shortener.Shorten(acme, "https://example.com/x")
+	assert.Equal(t, again, branded)
+
+	if _, err := shortener.Lookup(ctx, branded); err == nil {
+		t.Error("Lookup() found a branded code on the default domain")
+	}
+	link, err := shortener.Lookup(acme, branded)
+	assert.NilError(t, err)
+	assert.Equal(t, link.Domain, "acme.link")
+
+	url, _, err := shortener.Resolve(acme, branded)
+	assert.NilError(t, err)
+	assert.Equal(t, url, "https://example.com/x")
+	if _, _, err := shortener.Resolve(WithDomain(ctx, "other.link"), branded); err == nil {
+		t.Error("Resolve() found a code on a domain it was not created on")
+	}
+}
+
+func TestInMemoryShortener_TenantIsolation(t *testing.T) {
+	shortener := NewInMemoryShortener(storage.NewInMemoryStore())
+	acme := WithTenant(context.Background(), "acme")
+	globex := WithTenant(context.Background(), "globex")
+
+	code, err := shortener.Shorten(acme, "https://acme.example.com/launch")
+	assert.NilError(t, err)
+	link, err := shortener.Lookup(acme, code)
+	assert.NilError(t, err)
+	assert.Equal(t, link.Tenant, "acme")
+
+	if _, err := shortener.Lookup(globex, code); err == nil {
+		t.Error("Lookup() returned another tenant's link")
+	}
+	if _, _, err := shortener.Resolve(globex, code); err == nil {
+		t.Error("Resolve() resolved another tenant's link")
+	}
+	if _, err := shortener.Stats(context.Background(), code); err == nil {
+		t.Error("Stats() on the default tenant returned acme's link")
+	}
+	// The same URL gets its own code per tenant rather than being deduplicated
+	// across tenants.
+	other, _ := shortener.Shorten(globex, "https://acme.example.com/launch")
+	if other == code {
+		t.Error("Shorten() shared a code across tenants")
+	}
+
+	assert.Equal(t, len(shortener.Links(acme)), 1)
+	assert.Equal(t, len(shortener.Links(globex)), 1)
+	assert.Equal(t, len(shortener.Links(context.Background())), 0)
+	assert.Equal(t, len(shortener.GetTopDomains(context.Background(), 3)), 0)
+	assert.DeepEqual(t, shortener.GetTopDomains(acme, 3), []storage.DomainStats{{Domain: "acme.example.com", Count: 1}})
+}
+
+func TestInMemoryShortener_HistoryAndRollback(t *testing.T) {
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	shortener := newInMemoryShortener(storage.NewInMemoryStore(), func() time.Time { return now }, Hooks{})
+	alice := WithActor(context.Background(), "alice")
+	bob := WithActor(context.Background(), "bob")
+
+	code, _ := shortener.ShortenWithOptions(alice, "https://example.com", Options{Tags: []string{"launch"}})
+	now = now.Add(time.Minute)
+	shortener.AddTags(bob, code, []string{"q3"})
+	shortener.SetLocaleRules(bob, code, []storage.LocaleRule{{Language: "de", URL: "https://example.de"}})
+
+	versions, err := shortener.History(bob, code)
+	if err != nil || len(versions) != 3 {
+		t.Fatalf("History() = %+v, %v, want 3 versions", versions, err)
+	}
+	if versions[0].Actor != "alice" || versions[1].Actor != "bob" || !versions[1].At.Equal(now) {
+		t.Errorf("History() actors = %q, %q at %v, want alice then bob at %v", versions[0].Actor, versions[1].Actor, versions[1].At, now)
+	}
+	if got := versions[2].Changed; len(got) != 1 || got[0] != "locale_rules" {
+		t.Errorf("versions[2].Changed = %v, want [locale_rules]", got)
+	}
+
+	link, err := shortener.Rollback(alice, code, 1)
+	if err != nil || len(link.Tags) != 1 || len(link.LocaleRules) != 0 {
+		t.Errorf("Rollback(1) = %+v, %v, want the original settings", link, err)
+	}
+	if _, err := shortener.Rollback(alice, code, 0); err != storage.ErrNotFound {
+		t.Errorf("Rollback(0) error = %v, want %v", err, storage.ErrNotFound)
+	}
+}
+
+func TestInMemoryShortener_Shorten_SkipsTakenCodes(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	store.SaveLink(storage.Link{Code: "1", URL: "https://example.com/kept", Tags: []string{"x"}})
+	shortener := NewInMemoryShortener(store)
+
+	code, err := shortener.Shorten(context.Background(), "https://example.com/new")
+	if err != nil || code != "2" {
+		t.Errorf("Shorten() = %q, %v, want the next free code 2", code, err)
+	}
+	if url, _ := store.GetURL("1"); url != "https://example.com/kept" {
+		t.Errorf("GetURL(1) = %q, want the existing link untouched", url)
+	}
+}
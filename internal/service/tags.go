@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"assignment_infracloud/internal/storage"
+)
+
+const (
+	maxTags         = 20
+	maxGroupNameLen = 64
+)
+
+// AddTags adds tags to the link stored under code; tags it already has are
+// ignored.
+func (s *InMemoryShortener) AddTags(ctx context.Context, code string, tags []string) (storage.Link, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	return s.edit(ctx, code, func(link *storage.Link) error {
+		merged, err := normalizeTags(append(append([]string(nil), link.Tags...), tags...))
+		if err != nil {
+			return err
+		}
+		link.Tags = merged
+		return nil
+	})
+}
+
+// RemoveTag takes tag off the link stored under code, failing with
+// storage.ErrNotFound when the link does not carry it.
+func (s *InMemoryShortener) RemoveTag(ctx context.Context, code, tag string) (storage.Link, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	return s.edit(ctx, code, func(link *storage.Link) error {
+		var kept []string
+		for _, t := range link.Tags {
+			if t != tag {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) == len(link.Tags) {
+			return storage.ErrNotFound
+		}
+		link.Tags = kept
+		return nil
+	})
+}
+
+// LinksByTag returns the tenant's links carrying tag, oldest first.
+func (s *InMemoryShortener) LinksByTag(ctx context.Context, tag string) []storage.Link {
+	return s.store.LinksByTag(TenantFrom(ctx), strings.ToLower(tag))
+}
+
+// LinksByCampaign returns the tenant's links in campaign, oldest first.
+func (s *InMemoryShortener) LinksByCampaign(ctx context.Context, campaign string) []storage.Link {
+	return s.store.LinksByCampaign(TenantFrom(ctx), strings.ToLower(campaign))
+}
+
+func (s *InMemoryShortener) TagStats(ctx context.Context, tag string) storage.GroupStats {
+	return s.store.TagStats(TenantFrom(ctx), strings.ToLower(tag))
+}
+
+func (s *InMemoryShortener) CampaignStats(ctx context.Context, campaign string) storage.GroupStats {
+	return s.store.CampaignStats(TenantFrom(ctx), strings.ToLower(campaign))
+}
+
+// normalizeTags lower-cases tags, drops duplicates and sorts them, so links
+// compare and index the same however their tags were spelled.
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool)
+	var out []string
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !validGroupName(tag) {
+			return nil, ErrInvalidTag
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	if len(out) > maxTags {
+		return nil, ErrInvalidTag
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// validGroupName reports whether name can be used as a tag or campaign:
+// lower-case letters, digits and "-_.", with "/" separating folder levels.
+func validGroupName(name string) bool {
+	if name == "" || len(name) > maxGroupNameLen ||
+		name[0] == '/' || name[len(name)-1] == '/' || strings.Contains(name, "//") {
+		return false
+	}
+	for _, c := range name {
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.ContainsRune("-_./", c)) {
+			return false
+		}
+	}
+	return true
+}
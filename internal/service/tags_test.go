@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"assignment_infracloud/internal/storage"
+)
+
+func TestInMemoryShortener_Tags(t *testing.T) {
+	store := storage.NewInMemoryStore()
+	shortener := NewInMemoryShortener(store)
+	ctx := context.Background()
+
+	code, err := shortener.ShortenWithOptions(ctx, "https://example.com/a", Options{Tags: []string{"Launch", "marketing/emea", "launch"}, Campaign: "Spring-24"})
+	if err != nil {
+		t.Fatalf("ShortenWithOptions() error = %v", err)
+	}
+	link, _ := shortener.Lookup(ctx, code)
+	if strings.Join(link.Tags, ",") != "launch,marketing/emea" || link.Campaign != "spring-24" {
+		t.Errorf("Tags, Campaign = %v, %q, want [launch marketing/emea], spring-24", link.Tags, link.Campaign)
+	}
+
+	link, err = shortener.AddTags(ctx, code, []string{"q3", "launch"})
+	if err != nil || strings.Join(link.Tags, ",") != "launch,marketing/emea,q3" {
+		t.Errorf("AddTags() = %v, %v, want [launch marketing/emea q3]", link.Tags, err)
+	}
+	if link, err = shortener.RemoveTag(ctx, code, "LAUNCH"); err != nil || strings.Join(link.Tags, ",") != "marketing/emea,q3" {
+		t.Errorf("RemoveTag() = %v, %v, want [marketing/emea q3]", link.Tags, err)
+	}
+	if _, err := shortener.RemoveTag(ctx, code, "launch"); err != storage.ErrNotFound {
+		t.Errorf("RemoveTag(missing) error = %v, want %v", err, storage.ErrNotFound)
+	}
+
+	shortener.Resolve(ctx, code)
+	if got := shortener.TagStats(ctx, "q3"); got != (storage.GroupStats{Links: 1, Clicks: 1}) {
+		t.Errorf("TagStats(q3) = %+v, want {1 1}", got)
+	}
+	if got := shortener.LinksByCampaign(ctx, "SPRING-24"); len(got) != 1 || got[0].Code != code {
+		t.Errorf("LinksByCampaign() = %v, want [%s]", got, code)
+	}
+	if got := shortener.LinksByTag(WithTenant(ctx, "acme"), "q3"); len(got) != 0 {
+		t.Errorf("LinksByTag() for another tenant = %v, want none", got)
+	}
+
+	// Tagged links are kept apart from plain shortens of the same URL.
+	if plain, _ := shortener.Shorten(ctx, "https://example.com/a"); plain == code {
+		t.Errorf("Shorten() = %q, want a new code for the untagged link", plain)
+	}
+}
+
+func TestNormalizeTags_Invalid(t *testing.T) {
+	tests := [][]string{
+		{""},
+		{"has space"},
+		{"/leading"},
+		{"trailing/"},
+		{"a//b"},
+		{strings.Repeat("x", 65)},
+		strings.Fields("a b c d e f g h i j k l m n o p q r s t u"),
+	}
+	for _, tags := range tests {
+		if _, err := normalizeTags(tags); err != ErrInvalidTag {
+			t.Errorf("normalizeTags(%q) error = %v, want %v", tags, err, ErrInvalidTag)
+		}
+	}
+	if _, err := NewInMemoryShortener(storage.NewInMemoryStore()).ShortenWithOptions(context.Background(), "https://example.com", Options{Campaign: "no spaces"}); err != ErrInvalidCampaign {
+		t.Errorf("ShortenWithOptions(bad campaign) error = %v, want %v", err, ErrInvalidCampaign)
+	}
+}
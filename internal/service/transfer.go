@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"assignment_infracloud/internal/password"
+	"assignment_infracloud/internal/storage"
+)
+
+const maxCodeLen = 64
+
+var (
+	ErrInvalidCode         = errors.New("invalid code")
+	ErrInvalidPasswordHash = errors.New("invalid password hash")
+	ErrConflict            = storage.ErrConflict
+)
+
+// Export returns the links of every tenant, oldest first, with buffered
+// analytics applied. It is meant for backups and migrations, so callers
+// must restrict it to admins.
+func (s *InMemoryShortener) Export(ctx context.Context) []storage.Link {
+	s.variants.Flush()
+	return s.store.Links()
+}
+
+// Import stores a link taken from an export under its own tenant, domain and
+// code, keeping its counters. It is validated like a new link, so an import
+// cannot store settings the API would refuse. A code that is already in use
+// fails with ErrConflict. Codes that read as generated ones reserve their
+// number, so Shorten never hands them out again.
+func (s *InMemoryShortener) Import(ctx context.Context, link storage.Link) (storage.Link, error) {
+	if !validCode(link.Code) || strings.ContainsAny(link.Tenant, "@/") || strings.ContainsAny(link.Domain, "@/") {
+		return storage.Link{}, ErrInvalidCode
+	}
+	if !isValidURL(link.URL) {
+		return storage.Link{}, ErrInvalidURL
+	}
+	if link.MaxClicks < 0 || link.Clicks < 0 {
+		return storage.Link{}, ErrInvalidMaxClicks
+	}
+	if link.PasswordHash != "" && password.Validate(link.PasswordHash) != nil {
+		return storage.Link{}, ErrInvalidPasswordHash
+	}
+	if !validConflict(link.Passthrough.QueryConflict) {
+		return storage.Link{}, ErrInvalidConflict
+	}
+	if !validDeviceRules(link.DeviceRules) {
+		return storage.Link{}, ErrInvalidRule
+	}
+	localeRules, err := normalizeLocaleRules(link.LocaleRules)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	link.LocaleRules = localeRules
+	variants, err := normalizeVariants(link.Variants)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	for i := range variants {
+		if link.Variants[i].Clicks < 0 {
+			return storage.Link{}, ErrInvalidVariant
+		}
+		variants[i].Clicks = link.Variants[i].Clicks
+	}
+	link.Variants = variants
+	var schedule []storage.ScheduleEntry
+	for _, e := range link.Schedule {
+		if !validScheduleEntry(e) {
+			return storage.Link{}, ErrInvalidSchedule
+		}
+		if e.ID == "" {
+			if e.ID, err = newScheduleID(); err != nil {
+				return storage.Link{}, err
+			}
+		}
+		schedule = append(schedule, e)
+	}
+	link.Schedule = schedule
+	tags, err := normalizeTags(link.Tags)
+	if err != nil {
+		return storage.Link{}, err
+	}
+	link.Tags = tags
+	link.Campaign = strings.ToLower(strings.TrimSpace(link.Campaign))
+	if link.Campaign != "" && !validGroupName(link.Campaign) {
+		return storage.Link{}, ErrInvalidCampaign
+	}
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = s.now()
+	}
+	s.store.ReserveCode(link.Code)
+	if err := s.store.ImportLink(link); err != nil {
+		return storage.Link{}, err
+	}
+	s.changed(ctx, ActionImport, link)
+	return link, nil
+}
+
+// validCode reports whether code is usable as a path segment of a short
+// URL: letters, digits, '-' and '_'.
+func validCode(code string) bool {
+	if code == "" || len(code) > maxCodeLen {
+		return false
+	}
+	for _, c := range code {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
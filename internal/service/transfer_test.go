@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"assignment_infracloud/internal/encoding"
+	"assignment_infracloud/internal/storage"
+)
+
+func TestInMemoryShortener_ExportImport(t *testing.T) {
+	src := NewInMemoryShortener(storage.NewInMemoryStore())
+	ctx := context.Background()
+	acme := WithTenant(ctx, "acme")
+	src.Shorten(ctx, "https://example.com/a")
+	src.ShortenWithOptions(acme, "https://example.com/b", Options{Tags: []string{"launch"}})
+	exported := src.Export(ctx)
+	if len(exported) != 2 {
+		t.Fatalf("Export() = %d links, want both tenants' 2", len(exported))
+	}
+
+	var actions []string
+	dst := NewInMemoryShortenerWithHooks(storage.NewInMemoryStore(), Hooks{
+		Changed: func(_ context.Context, action string, _ storage.Link) { actions = append(actions, action) },
+	})
+	// A generated code far ahead of the target's counter.
+	big := storage.Link{Code: encoding.Base62Encode(500), URL: "https://example.com/c"}
+	for _, link := range append(exported, big) {
+		if _, err := dst.Import(ctx, link); err != nil {
+			t.Fatalf("Import(%s) error = %v", link.Key(), err)
+		}
+	}
+	if len(actions) != 3 || actions[0] != ActionImport {
+		t.Errorf("Changed actions = %v, want 3 imports", actions)
+	}
+	variants := storage.Link{Code: "ab-test", URL: "https://example.com/v", Variants: []storage.Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 1, Clicks: 7},
+		{Name: "b", URL: "https://example.com/b", Weight: 1, Clicks: 3},
+	}}
+	if link, err := dst.Import(ctx, variants); err != nil || link.Variants[0].Clicks != 7 {
+		t.Errorf("Import(variants) = %+v, %v, want their clicks kept", link, err)
+	}
+	if link, err := dst.Lookup(acme, exported[1].Code); err != nil || link.Tags[0] != "launch" {
+		t.Errorf("Lookup(acme's link) = %+v, %v", link, err)
+	}
+	if _, err := dst.Import(ctx, exported[0]); !errors.Is(err, ErrConflict) {
+		t.Errorf("Import(again) error = %v, want ErrConflict", err)
+	}
+
+	code, _ := dst.Shorten(ctx, "https://example.com/new")
+	if code != encoding.Base62Encode(501) {
+		t.Errorf("Shorten() after import = %q, want the code after the imported ones", code)
+	}
+	if code, _ := dst.Shorten(ctx, "https://example.com/a"); code != exported[0].Code {
+		t.Errorf("Shorten(imported destination) = %q, want %q", code, exported[0].Code)
+	}
+
+	// A code decoding to MaxUint64 must not push the counter to its end.
+	if _, err := dst.Import(ctx, storage.Link{Code: "lYGhA16ahyf", URL: "https://example.com/max"}); err != nil {
+		t.Fatalf("Import(lYGhA16ahyf) error = %v", err)
+	}
+	if code, err := dst.Shorten(ctx, "https://example.com/after-max"); err != nil || code != encoding.Base62Encode(502) {
+		t.Errorf("Shorten() after importing a huge code = %q, %v, want the next code in sequence", code, err)
+	}
+
+	for _, link := range []storage.Link{
+		{Code: "", URL: "https://example.com"},
+		{Code: "a/b", URL: "https://example.com"},
+		{Code: "ok", Tenant: "a@b", URL: "https://example.com"},
+		{Code: "ok", URL: "ftp://example.com"},
+		{Code: "ok", URL: "https://example.com", Tags: []string{"/bad"}},
+		{Code: "ok", URL: "https://example.com", Variants: []storage.Variant{{Name: "a", URL: "https://example.com/a"}}},
+		{Code: "ok", URL: "https://example.com", Variants: []storage.Variant{{Name: "a", URL: "javascript:alert(1)", Weight: 1}}},
+		{Code: "ok", URL: "https://example.com", DeviceRules: []storage.DeviceRule{{Platform: "ios", URL: "javascript:alert(1)"}}},
+		{Code: "ok", URL: "https://example.com", DeviceRules: []storage.DeviceRule{{Platform: "toaster", URL: "https://example.com/t"}}},
+		{Code: "ok", URL: "https://example.com", LocaleRules: []storage.LocaleRule{{Language: "pt", URL: "data:text/html,hi"}}},
+		{Code: "ok", URL: "https://example.com", Schedule: []storage.ScheduleEntry{{URL: "https://example.com/s"}}},
+		{Code: "ok", URL: "https://example.com", Passthrough: storage.Passthrough{QueryConflict: "merge"}},
+		{Code: "ok", URL: "https://example.com", PasswordHash: "plain"},
+		{Code: "ok", URL: "https://example.com", PasswordHash: "pbkdf2-sha256$2000000000$c2FsdA$a2V5"},
+	} {
+		if _, err := dst.Import(ctx, link); err == nil {
+			t.Errorf("Import(%+v) succeeded, want an error", link)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package signing
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseKeyring(t *testing.T) {
+	ring, err := ParseKeyring("")
+	if err != nil || ring != nil {
+		t.Errorf("ParseKeyring(\"\") = %v, %v, want nil, nil", ring, err)
+	}
+
+	ring, err = ParseKeyring("k2:new, k1:old")
+	if err != nil {
+		t.Fatalf("ParseKeyring() error = %v", err)
+	}
+	if ring.current != "k2" || len(ring.keys) != 2 {
+		t.Errorf("ParseKeyring() current = %q with %d keys, want k2 with 2", ring.current, len(ring.keys))
+	}
+
+	for _, spec := range []string{"nokey", "k1:", ":secret", "k1:a,k1:b"} {
+		if _, err := ParseKeyring(spec); err == nil {
+			t.Errorf("ParseKeyring(%q) should return error", spec)
+		}
+	}
+}
+
+func TestKeyring_SignVerify(t *testing.T) {
+	ring, _ := ParseKeyring("k1:secret")
+	signed := ring.Sign("abc", url.Values{"r": {"alice"}, "list": {"a", "b"}})
+
+	params, err := ring.Verify("abc", signed)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if params.Get("r") != "alice" || len(params["list"]) != 2 {
+		t.Errorf("Verify() params = %v", params)
+	}
+	if params.Has(SigParam) || params.Has(KeyIDParam) {
+		t.Errorf("Verify() params still contain signature fields: %v", params)
+	}
+}
+
+func TestKeyring_VerifyTampering(t *testing.T) {
+	ring, _ := ParseKeyring("k1:secret")
+	signed := ring.Sign("abc", url.Values{"r": {"alice"}})
+
+	tests := []struct {
+		name   string
+		code   string
+		mutate func(url.Values)
+		want   error
+	}{
+		{"changed param", "abc", func(v url.Values) { v.Set("r", "mallory") }, ErrBadSignature},
+		{"added param", "abc", func(v url.Values) { v.Set("admin", "1") }, ErrBadSignature},
+		{"other code", "xyz", func(v url.Values) {}, ErrBadSignature},
+		{"unknown key", "abc", func(v url.Values) { v.Set(KeyIDParam, "k9") }, ErrUnknownKey},
+		{"no signature", "abc", func(v url.Values) { v.Del(SigParam) }, ErrMissingSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(signed.Encode())
+			tt.mutate(q)
+			if _, err := ring.Verify(tt.code, q); err != tt.want {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	old, _ := ParseKeyring("k1:old")
+	signedWithOld := old.Sign("abc", url.Values{"r": {"bob"}})
+
+	rotated, _ := ParseKeyring("k2:new,k1:old")
+	if _, err := rotated.Verify("abc", signedWithOld); err != nil {
+		t.Errorf("Verify() with retired-but-present key error = %v", err)
+	}
+	if got := rotated.Sign("abc", nil).Get(KeyIDParam); got != "k2" {
+		t.Errorf("Sign() kid = %q, want k2", got)
+	}
+
+	retired, _ := ParseKeyring("k2:new")
+	if _, err := retired.Verify("abc", signedWithOld); err != ErrUnknownKey {
+		t.Errorf("Verify() after removing key error = %v, want %v", err, ErrUnknownKey)
+	}
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxRecord bounds one line of an NDJSON snapshot.
+const maxRecord = 16 << 20
+
+// Backend persists store snapshots. Load fails with an error wrapping
+// os.ErrNotExist when nothing has been saved yet.
+type Backend interface {
+	Load() (Snapshot, error)
+	Save(Snapshot) error
+	String() string
+}
+
+// OpenBackend returns the backend spec names: "json:<path>" keeps the
+// snapshot as one JSON document, "ndjson:<path>" as one link per line so
+// large stores can be streamed and diffed. A bare path picks ndjson for
+// .ndjson and .jsonl files and json otherwise.
+func OpenBackend(spec string) (Backend, error) {
+	kind, path, ok := strings.Cut(spec, ":")
+	if !ok {
+		kind, path = "json", spec
+		if ext := filepath.Ext(spec); ext == ".ndjson" || ext == ".jsonl" {
+			kind = "ndjson"
+		}
+	}
+	if path == "" {
+		return nil, fmt.Errorf("backend %q: missing path", spec)
+	}
+	switch kind {
+	case "json":
+		return jsonFile(path), nil
+	case "ndjson":
+		return ndjsonFile(path), nil
+	}
+	return nil, fmt.Errorf("backend %q: unknown kind %q", spec, kind)
+}
+
+// LoadStore restores the store saved in b, or returns an empty store if
+// nothing has been saved yet.
+func LoadStore(b Backend) (*InMemoryStore, error) {
+	snap, err := b.Load()
+	if errors.Is(err, os.ErrNotExist) {
+		return NewInMemoryStore(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s, err := Restore(snap)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", b, err)
+	}
+	return s, nil
+}
+
+type jsonFile string
+
+func (f jsonFile) String() string { return "json:" + string(f) }
+
+func (f jsonFile) Load() (Snapshot, error) {
+	var snap Snapshot
+	r, err := os.Open(string(f))
+	if err != nil {
+		return snap, err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(bufio.NewReader(r)).Decode(&snap); err != nil {
+		return snap, fmt.Errorf("%s: %w", f, err)
+	}
+	return snap, nil
+}
+
+func (f jsonFile) Save(snap Snapshot) error {
+	return writeFile(string(f), func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(snap)
+	})
+}
+
+// ndjsonFile holds a header line, the snapshot without its links, followed
+// by one ndjsonLink per line. Histories of keys without a link stay in the
+// header.
+type ndjsonFile string
+
+type ndjsonLink struct {
+	Link    Link
+	History []Version
+}
+
+func (f ndjsonFile) String() string { return "ndjson:" + string(f) }
+
+func (f ndjsonFile) Load() (Snapshot, error) {
+	var snap Snapshot
+	r, err := os.Open(string(f))
+	if err != nil {
+		return snap, err
+	}
+	defer r.Close()
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxRecord)
+	for line := 1; sc.Scan(); line++ {
+		if line == 1 {
+			if err := json.Unmarshal(sc.Bytes(), &snap); err != nil {
+				return snap, fmt.Errorf("%s:%d: %w", f, line, err)
+			}
+			if snap.History == nil {
+				snap.History = make(map[string][]Version)
+			}
+			continue
+		}
+		var rec ndjsonLink
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return snap, fmt.Errorf("%s:%d: %w", f, line, err)
+		}
+		snap.Links = append(snap.Links, rec.Link)
+		if rec.History != nil {
+			snap.History[rec.Link.Key()] = rec.History
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return snap, fmt.Errorf("%s: %w", f, err)
+	}
+	return snap, nil
+}
+
+func (f ndjsonFile) Save(snap Snapshot) error {
+	links, history := snap.Links, make(map[string][]Version, len(snap.History))
+	for key, versions := range snap.History {
+		history[key] = versions
+	}
+	for _, link := range links {
+		delete(history, link.Key())
+	}
+	header := snap
+	header.Links, header.History = nil, history
+	return writeFile(string(f), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		if err := enc.Encode(header); err != nil {
+			return err
+		}
+		for _, link := range links {
+			if err := enc.Encode(ndjsonLink{Link: link, History: snap.History[link.Key()]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeFile replaces path with what write produces, through a temporary file
+// renamed into place, so a crash mid-save leaves the previous contents.
+func writeFile(path string, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	err = write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"slices"
+	"time"
+)
+
+// Version is one immutable entry in a link's edit history. The first version
+// records the link as created; every later one an edit that changed at least
+// one setting, or the link's deletion.
+type Version struct {
+	Number int
+	Actor  string
+	At     time.Time
+	// Changed names the settings that differ from the previous version,
+	// using their API names ("url", "tags", ...); for the first version, the
+	// settings the link was created with.
+	Changed []string
+	// RestoredFrom is the version a rollback copied its settings from, zero
+	// for ordinary edits.
+	RestoredFrom int
+	// Deleted marks the final version of a deleted link; Link holds the
+	// settings it had when it was removed.
+	Deleted bool
+	// Link holds the link's settings after the change. Counters, health and
+	// metadata are not versioned and are left zero.
+	Link Link
+}
+
+// EditLink is UpdateLink on behalf of actor: when fn changes any of the
+// link's settings, a new version is appended to its history.
+func (s *InMemoryStore) EditLink(key, actor string, at time.Time, fn func(*Link) error) (Link, error) {
+	return s.edit(key, actor, at, 0, fn)
+}
+
+// RollbackLink restores the settings the link had at version number,
+// recording the rollback as a new version. Clicks are kept, including those
+// of variants that exist in both versions.
+func (s *InMemoryStore) RollbackLink(key, actor string, at time.Time, number int) (Link, error) {
+	s.mu.RLock()
+	versions := s.history[key]
+	s.mu.RUnlock()
+	if number < 1 || number > len(versions) {
+		return Link{}, ErrNotFound
+	}
+	target := versions[number-1].Link
+	return s.edit(key, actor, at, number, func(link *Link) error {
+		clicks := make(map[string]int, len(link.Variants))
+		for _, v := range link.Variants {
+			clicks[v.Name] = v.Clicks
+		}
+		restored := settings(target)
+		for i := range restored.Variants {
+			restored.Variants[i].Clicks = clicks[restored.Variants[i].Name]
+		}
+		link.URL = restored.URL
+		link.PasswordHash = restored.PasswordHash
+		link.MaxClicks = restored.MaxClicks
+		link.RequireSignature = restored.RequireSignature
+		link.Passthrough = restored.Passthrough
+		link.UTM = restored.UTM
+		link.DeviceRules = restored.DeviceRules
+		link.LocaleRules = restored.LocaleRules
+		link.Variants = restored.Variants
+		link.Schedule = restored.Schedule
+		link.Interstitial = restored.Interstitial
+		link.Tags = restored.Tags
+		link.Campaign = restored.Campaign
+		return nil
+	})
+}
+
+func (s *InMemoryStore) edit(key, actor string, at time.Time, restoredFrom int, fn func(*Link) error) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, next, err := s.update(key, fn)
+	if err != nil {
+		return next, err
+	}
+	if changed := changedSettings(prev, next); len(changed) > 0 {
+		s.history[key] = append(s.history[key], Version{
+			Number:       len(s.history[key]) + 1,
+			Actor:        actor,
+			At:           at,
+			Changed:      changed,
+			RestoredFrom: restoredFrom,
+			Link:         settings(next),
+		})
+	}
+	return next, nil
+}
+
+// firstVersion records link as created.
+func firstVersion(link Link) Version {
+	return Version{
+		Number:  1,
+		Actor:   link.CreatedBy,
+		At:      link.CreatedAt,
+		Changed: changedSettings(Link{}, link),
+		Link:    settings(link),
+	}
+}
+
+// History returns the versions of the link stored under key, oldest first.
+// A deleted link keeps its history, ending in the version that deleted it.
+func (s *InMemoryStore) History(key string) ([]Version, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions, ok := s.history[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return slices.Clone(versions), nil
+}
+
+// deleted reports whether versions ends in a deletion.
+func deleted(versions []Version) bool {
+	return len(versions) > 0 && versions[len(versions)-1].Deleted
+}
+
+// settings returns the versioned part of link: its identity and what its
+// owner configured, without counters, health or metadata.
+func settings(link Link) Link {
+	out := Link{
+		Code:             link.Code,
+		Tenant:           link.Tenant,
+		Domain:           link.Domain,
+		URL:              link.URL,
+		CreatedAt:        link.CreatedAt,
+		CreatedBy:        link.CreatedBy,
+		PasswordHash:     link.PasswordHash,
+		MaxClicks:        link.MaxClicks,
+		RequireSignature: link.RequireSignature,
+		Passthrough:      link.Passthrough,
+		UTM:              link.UTM,
+		DeviceRules:      link.DeviceRules,
+		LocaleRules:      link.LocaleRules,
+		Schedule:         link.Schedule,
+		Interstitial:     link.Interstitial,
+		Tags:             link.Tags,
+		Campaign:         link.Campaign,
+	}
+	for _, v := range link.Variants {
+		v.Clicks = 0
+		out.Variants = append(out.Variants, v)
+	}
+	return out
+}
+
+// changedSettings lists, by API name, the settings that differ between a and
+// b.
+func changedSettings(a, b Link) []string {
+	a, b = settings(a), settings(b)
+	var changed []string
+	for _, f := range []struct {
+		name   string
+		differ bool
+	}{
+		{"url", a.URL != b.URL},
+		{"password", a.PasswordHash != b.PasswordHash},
+		{"max_clicks", a.MaxClicks != b.MaxClicks},
+		{"require_signature", a.RequireSignature != b.RequireSignature},
+		{"passthrough", a.Passthrough != b.Passthrough},
+		{"utm", a.UTM != b.UTM},
+		{"device_rules", !slices.Equal(a.DeviceRules, b.DeviceRules)},
+		{"locale_rules", !slices.Equal(a.LocaleRules, b.LocaleRules)},
+		{"variants", !slices.Equal(a.Variants, b.Variants)},
+		{"schedule", !slices.Equal(a.Schedule, b.Schedule)},
+		{"interstitial", a.Interstitial != b.Interstitial},
+		{"tags", !slices.Equal(a.Tags, b.Tags)},
+		{"campaign", a.Campaign != b.Campaign},
+	} {
+		if f.differ {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInMemoryStore_History(t *testing.T) {
+	store := NewInMemoryStore()
+	created := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	store.SaveLink(Link{Code: "abc", URL: "https://example.com/v1", CreatedAt: created, CreatedBy: "alice",
+		Variants: []Variant{{Name: "a", URL: "https://example.com/a", Weight: 1}}})
+
+	edited := created.Add(time.Hour)
+	store.EditLink("abc", "bob", edited, func(l *Link) error {
+		l.URL, l.Tags = "https://example.com/v2", []string{"launch"}
+		return nil
+	})
+	// Counters are not settings and leave no trace in the history.
+	store.Visit("abc")
+	store.AddVariantClicks("abc", map[string]int{"a": 3})
+	store.EditLink("abc", "bob", edited, func(l *Link) error { return nil })
+
+	versions, err := store.History("abc")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("History() = %+v, %v, want 2 versions", versions, err)
+	}
+	if v := versions[0]; v.Number != 1 || v.Actor != "alice" || !v.At.Equal(created) || v.Link.URL != "https://example.com/v1" ||
+		strings.Join(v.Changed, ",") != "url,variants" {
+		t.Errorf("versions[0] = %+v, want alice's creation", v)
+	}
+	if v := versions[1]; v.Number != 2 || v.Actor != "bob" || strings.Join(v.Changed, ",") != "url,tags" {
+		t.Errorf("versions[1] = %+v, want bob changing url and tags", v)
+	}
+
+	link, err := store.RollbackLink("abc", "carol", edited.Add(time.Hour), 1)
+	if err != nil || link.URL != "https://example.com/v1" || len(link.Tags) != 0 {
+		t.Errorf("RollbackLink(1) = %+v, %v, want v1 settings", link, err)
+	}
+	if link.Clicks != 1 || link.Variants[0].Clicks != 3 {
+		t.Errorf("RollbackLink(1) clicks = %d, %v, want counters kept", link.Clicks, link.Variants)
+	}
+	if got := store.LinksByTag("", "launch"); len(got) != 0 {
+		t.Errorf("LinksByTag(launch) after rollback = %v, want none", got)
+	}
+	versions, _ = store.History("abc")
+	if v := versions[len(versions)-1]; v.Number != 3 || v.Actor != "carol" || v.RestoredFrom != 1 {
+		t.Errorf("rollback version = %+v, want version 3 by carol restoring 1", v)
+	}
+
+	if _, err := store.RollbackLink("abc", "carol", edited, 9); err != ErrNotFound {
+		t.Errorf("RollbackLink(unknown version) error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := store.History("missing"); err != ErrNotFound {
+		t.Errorf("History(missing) error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestInMemoryStore_DeleteLink_KeepsHistory(t *testing.T) {
+	store := NewInMemoryStore()
+	created := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	store.SaveLink(Link{Code: "my-docs", URL: "https://example.com/v1", CreatedAt: created, CreatedBy: "alice"})
+	store.EditLink("my-docs", "bob", created.Add(time.Hour), func(l *Link) error {
+		l.URL = "https://example.com/v2"
+		return nil
+	})
+
+	deletedAt := created.Add(2 * time.Hour)
+	if _, err := store.DeleteLink("my-docs", "carol", deletedAt); err != nil {
+		t.Fatalf("DeleteLink() error = %v", err)
+	}
+	versions, err := store.History("my-docs")
+	if err != nil || len(versions) != 3 {
+		t.Fatalf("History() after delete = %+v, %v, want 3 versions", versions, err)
+	}
+	if v := versions[2]; v.Number != 3 || v.Actor != "carol" || !v.At.Equal(deletedAt) || !v.Deleted ||
+		v.Link.URL != "https://example.com/v2" {
+		t.Errorf("versions[2] = %+v, want carol deleting v2", v)
+	}
+	if _, err := store.RollbackLink("my-docs", "carol", deletedAt, 1); err != ErrNotFound {
+		t.Errorf("RollbackLink() on a deleted link error = %v, want %v", err, ErrNotFound)
+	}
+	if problems := store.Check(); len(problems) != 0 {
+		t.Errorf("Check() after delete = %q, want none", problems)
+	}
+	store.Reindex()
+	if _, err := store.History("my-docs"); err != nil {
+		t.Errorf("History() after Reindex error = %v, want the history kept", err)
+	}
+
+	// A link imported under the same key continues the history.
+	if err := store.ImportLink(Link{Code: "my-docs", URL: "https://example.com/v3", CreatedAt: deletedAt.Add(time.Hour)}); err != nil {
+		t.Fatalf("ImportLink() error = %v", err)
+	}
+	versions, _ = store.History("my-docs")
+	if len(versions) != 4 || versions[3].Number != 4 || versions[3].Deleted {
+		t.Errorf("History() after import = %+v, want a fourth, live version", versions)
+	}
+	if problems := store.Check(); len(problems) != 0 {
+		t.Errorf("Check() after import = %q, want none", problems)
+	}
+}
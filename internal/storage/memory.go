@@ -5,60 +5,654 @@ import (
 	"net/url"
 	"sort"
 	"sync"
+	"time"
+
+	"assignment_infracloud/internal/encoding"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrExhausted = errors.New("link exhausted")
+	// ErrConflict is returned when inserting a link whose key is taken.
+	ErrConflict = errors.New("code already in use")
+	// ErrIDsExhausted is returned by NextID once MaxID has been handed out.
+	ErrIDsExhausted = errors.New("ids exhausted")
 )
 
-var ErrNotFound = errors.New("not found")
+// MaxID is the largest ID NextID hands out. No store gets near it; it keeps
+// imported codes from reserving the whole range and the counter from
+// wrapping around.
+const MaxID = 1<<48 - 1
 
 type DomainStats struct {
 	Domain string
 	Count  int
 }
 
+// Query conflict policies for Passthrough.QueryConflict, deciding which value
+// wins when the visit and the destination carry the same query key.
+const (
+	ConflictOverride = "override" // the visitor's value replaces the destination's
+	ConflictKeep     = "keep"     // the destination's value is kept
+	ConflictAppend   = "append"   // both values are sent
+)
+
+// Passthrough controls which parts of the visited URL are carried over to the
+// destination.
+type Passthrough struct {
+	Query         bool
+	Path          bool
+	QueryConflict string
+}
+
+// UTM is a template of utm_* parameters merged into the destination at
+// resolve time. Values may contain placeholders such as {code} and {date}.
+type UTM struct {
+	Source   string
+	Medium   string
+	Campaign string
+	Term     string
+	Content  string
+}
+
+// Params returns the template as utm_* query parameters, skipping empty
+// fields. Placeholders are left unexpanded.
+func (u UTM) Params() url.Values {
+	params := url.Values{}
+	for key, val := range map[string]string{
+		"utm_source":   u.Source,
+		"utm_medium":   u.Medium,
+		"utm_campaign": u.Campaign,
+		"utm_term":     u.Term,
+		"utm_content":  u.Content,
+	} {
+		if val != "" {
+			params.Set(key, val)
+		}
+	}
+	return params
+}
+
+// DeviceRule sends visitors whose User-Agent matches Platform (a platform
+// name or the "mobile"/"desktop" group) to URL instead of the default
+// destination.
+type DeviceRule struct {
+	Platform string
+	URL      string
+}
+
+// LocaleRule sends visitors to URL by language and/or region. Language is a
+// language tag such as "pt-BR" or "pt"; Country an ISO 3166 code as set by
+// the edge. Either may be empty, but not both.
+type LocaleRule struct {
+	Language string
+	Country  string
+	URL      string
+}
+
+// Variant is one weighted destination of an A/B split. Clicks counts the
+// visits it has been served.
+type Variant struct {
+	Name   string
+	URL    string
+	Weight int
+	Clicks int
+}
+
+// ScheduleEntry points the link at URL from Start (inclusive) until End
+// (exclusive). A zero Start or End leaves that side of the range open.
+type ScheduleEntry struct {
+	ID    string
+	URL   string
+	Start time.Time
+	End   time.Time
+}
+
+// Active reports whether the entry applies at t.
+func (e ScheduleEntry) Active(t time.Time) bool {
+	return (e.Start.IsZero() || !t.Before(e.Start)) && (e.End.IsZero() || t.Before(e.End))
+}
+
+// Destination health statuses recorded by the health checker.
+const (
+	HealthOK     = "ok"
+	HealthBroken = "broken"
+)
+
+// Health is the outcome of the last check of a link's destination. A zero
+// CheckedAt means the link has not been checked yet.
+type Health struct {
+	Status     string
+	StatusCode int
+	Error      string
+	CheckedAt  time.Time
+}
+
+// Metadata is what the destination page says about itself, taken from its
+// OpenGraph tags or, failing that, its <title>.
+type Metadata struct {
+	Title       string
+	Description string
+	Image       string
+	FetchedAt   time.Time
+}
+
+// Link is the full record behind a short code. Links carrying extra settings
+// (a password, for instance) are not deduplicated by URL.
+type Link struct {
+	Code string
+	// Tenant owns the link, empty for the default tenant. Tenants never see
+	// each other's links.
+	Tenant string
+	// Domain is the branded short domain the code lives on, empty for the
+	// default domain. Each domain has its own code space.
+	Domain    string
+	URL       string
+	CreatedAt time.Time
+	// CreatedBy identifies who created the link, empty when unknown.
+	CreatedBy    string
+	PasswordHash string
+	// MaxClicks limits how many times the link can be resolved; zero means
+	// unlimited.
+	MaxClicks int
+	Clicks    int
+	// RequireSignature rejects visits that do not carry a valid signature.
+	RequireSignature bool
+	Passthrough      Passthrough
+	UTM              UTM
+	// DeviceRules are evaluated in order; the first match wins.
+	DeviceRules []DeviceRule
+	LocaleRules []LocaleRule
+	// Variants split traffic by weight; when present they replace URL as
+	// the destination for visitors not matched by a device or locale rule.
+	Variants []Variant
+	Schedule []ScheduleEntry
+	// Interstitial shows a "you're leaving" page before every redirect.
+	Interstitial bool
+	// Tags are lower-case labels, kept sorted; a "/" in a tag can be used to
+	// file links in folders such as "marketing/emea".
+	Tags []string
+	// Campaign optionally groups the link with others for reporting.
+	Campaign string
+	Health   Health
+	Metadata Metadata
+}
+
+// Key returns the store key of the link with code on domain, owned by
+// tenant: the code itself for the default tenant and domain, otherwise
+// "tenant@domain/code" with the empty parts left out.
+func Key(tenant, domain, code string) string {
+	key := code
+	if domain != "" {
+		key = domain + "/" + key
+	}
+	if tenant != "" {
+		key = tenant + "@" + key
+	}
+	return key
+}
+
+// Key returns the key the link is stored under.
+func (l Link) Key() string {
+	return Key(l.Tenant, l.Domain, l.Code)
+}
+
+// DestinationAt returns the URL the link points to at t: the active schedule
+// entry with the latest start, or URL when none is active. Among entries with
+// the same start the one added last wins.
+func (l Link) DestinationAt(t time.Time) string {
+	if e := l.activeEntry(t); e != nil {
+		return e.URL
+	}
+	return l.URL
+}
+
+// ScheduledAt reports whether a schedule entry is active at t, replacing the
+// link's URL.
+func (l Link) ScheduledAt(t time.Time) bool {
+	return l.activeEntry(t) != nil
+}
+
+// activeEntry returns the schedule entry DestinationAt uses at t, or nil.
+func (l Link) activeEntry(t time.Time) *ScheduleEntry {
+	var best *ScheduleEntry
+	for i := range l.Schedule {
+		e := &l.Schedule[i]
+		if e.Active(t) && (best == nil || !e.Start.Before(best.Start)) {
+			best = e
+		}
+	}
+	return best
+}
+
+// Remaining returns the number of visits left and whether the link is
+// click-limited at all.
+func (l Link) Remaining() (int, bool) {
+	if l.MaxClicks <= 0 {
+		return 0, false
+	}
+	if l.Clicks >= l.MaxClicks {
+		return 0, true
+	}
+	return l.MaxClicks - l.Clicks, true
+}
+
+// DedupKey returns the key under which the link is indexed for
+// deduplication, and false if it carries settings that make it unique. Links
+// differing only in their UTM template get distinct keys, so one destination
+// can back several campaigns; links on different domains or of different
+// tenants never share a key.
+func (l Link) DedupKey() (string, bool) {
+	if l.PasswordHash != "" || l.MaxClicks != 0 || l.RequireSignature ||
+		l.Passthrough != (Passthrough{}) || len(l.DeviceRules) > 0 || len(l.LocaleRules) > 0 ||
+		len(l.Variants) > 0 || len(l.Schedule) > 0 || l.Interstitial ||
+		len(l.Tags) > 0 || l.Campaign != "" {
+		return "", false
+	}
+	key := l.URL
+	if l.UTM != (UTM{}) {
+		key += "\x00" + l.UTM.Params().Encode()
+	}
+	if l.Domain != "" || l.Tenant != "" {
+		key = l.Tenant + "\x00" + l.Domain + "\x00" + key
+	}
+	return key, true
+}
+
+// InMemoryStore holds links keyed by Key(tenant, domain, code). Methods
+// taking a key accept a bare code for the default tenant and domain.
 type InMemoryStore struct {
 	mu           sync.RWMutex
 	idCounter    uint64
 	codeToURL    map[string]string
 	urlToCode    map[string]string
-	domainCounts map[string]int
+	domainCounts map[string]map[string]int // by tenant
+	links        map[string]*Link
+	// tags and campaigns are inverted indexes from groupKey(tenant, name)
+	// to the keys of the links in that group.
+	tags      map[string]map[string]struct{}
+	campaigns map[string]map[string]struct{}
+	history   map[string][]Version
+}
+
+// GroupStats aggregates the links sharing a tag or campaign.
+type GroupStats struct {
+	Links  int
+	Clicks int
 }
 
 func NewInMemoryStore() *InMemoryStore {
 	return &InMemoryStore{
 		codeToURL:    make(map[string]string),
 		urlToCode:    make(map[string]string),
-		domainCounts: make(map[string]int),
+		domainCounts: make(map[string]map[string]int),
+		links:        make(map[string]*Link),
+		tags:         make(map[string]map[string]struct{}),
+		campaigns:    make(map[string]map[string]struct{}),
+		history:      make(map[string][]Version),
 	}
 }
 
-func (s *InMemoryStore) NextID() uint64 {
+// NextID returns a new ID for a generated code, failing with
+// ErrIDsExhausted once MaxID has been used.
+func (s *InMemoryStore) NextID() (uint64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.idCounter >= MaxID {
+		return 0, ErrIDsExhausted
+	}
 	s.idCounter++
-	return s.idCounter
+	return s.idCounter, nil
 }
 
 func (s *InMemoryStore) SaveMapping(code, url string) {
+	s.SaveLink(Link{Code: code, URL: url, CreatedAt: time.Now()})
+}
+
+// SaveLink stores the link under its key, replacing any previous link with
+// that key, and starts its history afresh. Only links with a dedup key are
+// indexed in urlToCode, so a plain Shorten never hands out a protected code.
+func (s *InMemoryStore) SaveLink(link Link) {
+	s.mu.Lock()
+	if old, ok := s.links[link.Key()]; ok {
+		s.unindex(old)
+	}
+	delete(s.history, link.Key())
+	s.insert(&link)
+	s.mu.Unlock()
+}
+
+// InsertLink stores a new link like SaveLink, but fails with ErrConflict
+// instead of replacing a link that already has its key.
+func (s *InMemoryStore) InsertLink(link Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.links[link.Key()]; ok {
+		return ErrConflict
+	}
+	s.insert(&link)
+	return nil
+}
+
+// ImportLink stores a link brought over from elsewhere under its own key,
+// counters included, failing with ErrConflict if the key is taken. A plain
+// link whose destination is already shortened does not take over the
+// deduplication index, so Shorten keeps returning the existing code.
+func (s *InMemoryStore) ImportLink(link Link) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.links[link.Key()]; ok {
+		return ErrConflict
+	}
+	key, dedup := link.DedupKey()
+	existing, indexed := s.urlToCode[key]
+	s.insert(&link)
+	if dedup && indexed {
+		s.urlToCode[key] = existing
+	}
+	return nil
+}
+
+// insert indexes link and starts its history, continuing that of a deleted
+// link with the same key; callers hold s.mu.
+func (s *InMemoryStore) insert(link *Link) {
+	s.index(link)
+	key := link.Key()
+	first := firstVersion(*link)
+	if versions := s.history[key]; deleted(versions) {
+		first.Number = len(versions) + 1
+		s.history[key] = append(versions, first)
+		return
+	}
+	s.history[key] = []Version{first}
+}
+
+// ReserveCode makes sure NextID never returns the ID code encodes, or any
+// below it, so an imported code is not handed out again. Codes NextID could
+// not have produced are left alone; generated codes that collide with them
+// are skipped at insertion instead.
+func (s *InMemoryStore) ReserveCode(code string) {
+	id, ok := generatedID(code)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id > s.idCounter {
+		s.idCounter = id
+	}
+}
+
+// generatedID returns the ID code encodes, if it is one NextID can return.
+func generatedID(code string) (uint64, bool) {
+	id, ok := encoding.Base62Decode(code)
+	return id, ok && id >= 1 && id <= MaxID
+}
+
+// UpdateLink applies fn to a copy of the link stored under key and saves the
+// result, keeping the URL index and domain statistics in step. If fn returns
+// an error the stored link is left untouched. fn must replace slice fields
+// rather than modify them in place, since the copy shares their backing
+// arrays with the stored link.
+func (s *InMemoryStore) UpdateLink(key string, fn func(*Link) error) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, next, err := s.update(key, fn)
+	return next, err
+}
+
+// update is UpdateLink for callers holding s.mu; it also returns the link as
+// it was before fn ran.
+func (s *InMemoryStore) update(key string, fn func(*Link) error) (prev, next Link, err error) {
+	cur, ok := s.links[key]
+	if !ok {
+		return Link{}, Link{}, ErrNotFound
+	}
+	next = *cur
+	if err := fn(&next); err != nil {
+		return *cur, *cur, err
+	}
+	next.Code, next.Tenant, next.Domain = cur.Code, cur.Tenant, cur.Domain
+	prev = *cur
+	s.unindex(cur)
+	s.index(&next)
+	return prev, next, nil
+}
+
+// DeleteLink removes the link stored under key on behalf of actor. Its
+// history is kept and ends in a version recording the deletion.
+func (s *InMemoryStore) DeleteLink(key, actor string, at time.Time) (Link, error) {
 	s.mu.Lock()
-	s.codeToURL[code] = url
-	s.urlToCode[url] = code
+	defer s.mu.Unlock()
+	link, ok := s.links[key]
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+	s.unindex(link)
+	s.history[key] = append(s.history[key], Version{
+		Number:  len(s.history[key]) + 1,
+		Actor:   actor,
+		At:      at,
+		Deleted: true,
+		Link:    settings(*link),
+	})
+	return *link, nil
+}
+
+// index adds link to every map; callers hold s.mu.
+func (s *InMemoryStore) index(link *Link) {
+	s.links[link.Key()] = link
+	s.codeToURL[link.Key()] = link.URL
+	if key, ok := link.DedupKey(); ok {
+		s.urlToCode[key] = link.Code
+	}
 
 	// Track domain statistics
-	if domain := extractDomain(url); domain != "" {
-		s.domainCounts[domain]++
+	if domain := extractDomain(link.URL); domain != "" {
+		counts, ok := s.domainCounts[link.Tenant]
+		if !ok {
+			counts = make(map[string]int)
+			s.domainCounts[link.Tenant] = counts
+		}
+		counts[domain]++
 	}
-	s.mu.Unlock()
+
+	for _, tag := range link.Tags {
+		addToGroup(s.tags, groupKey(link.Tenant, tag), link.Key())
+	}
+	if link.Campaign != "" {
+		addToGroup(s.campaigns, groupKey(link.Tenant, link.Campaign), link.Key())
+	}
+}
+
+// unindex removes link from every map; callers hold s.mu.
+func (s *InMemoryStore) unindex(link *Link) {
+	delete(s.links, link.Key())
+	delete(s.codeToURL, link.Key())
+	if key, ok := link.DedupKey(); ok && s.urlToCode[key] == link.Code {
+		delete(s.urlToCode, key)
+	}
+	if domain := extractDomain(link.URL); domain != "" {
+		counts := s.domainCounts[link.Tenant]
+		if counts[domain]--; counts[domain] <= 0 {
+			delete(counts, domain)
+		}
+		if len(counts) == 0 {
+			delete(s.domainCounts, link.Tenant)
+		}
+	}
+	for _, tag := range link.Tags {
+		removeFromGroup(s.tags, groupKey(link.Tenant, tag), link.Key())
+	}
+	if link.Campaign != "" {
+		removeFromGroup(s.campaigns, groupKey(link.Tenant, link.Campaign), link.Key())
+	}
+}
+
+// groupKey scopes a tag or campaign name to a tenant.
+func groupKey(tenant, name string) string {
+	return tenant + "\x00" + name
+}
+
+func addToGroup(groups map[string]map[string]struct{}, group, key string) {
+	members, ok := groups[group]
+	if !ok {
+		members = make(map[string]struct{})
+		groups[group] = members
+	}
+	members[key] = struct{}{}
+}
+
+func removeFromGroup(groups map[string]map[string]struct{}, group, key string) {
+	if members, ok := groups[group]; ok {
+		delete(members, key)
+		if len(members) == 0 {
+			delete(groups, group)
+		}
+	}
+}
+
+// LinksByTag returns the tenant's links carrying tag, oldest first.
+func (s *InMemoryStore) LinksByTag(tenant, tag string) []Link {
+	return s.groupLinks(s.tags, groupKey(tenant, tag))
+}
+
+// LinksByCampaign returns the tenant's links in campaign, oldest first.
+func (s *InMemoryStore) LinksByCampaign(tenant, campaign string) []Link {
+	return s.groupLinks(s.campaigns, groupKey(tenant, campaign))
+}
+
+// TagStats sums the clicks of the tenant's links carrying tag.
+func (s *InMemoryStore) TagStats(tenant, tag string) GroupStats {
+	return s.groupStats(s.tags, groupKey(tenant, tag))
+}
+
+// CampaignStats sums the clicks of the tenant's links in campaign.
+func (s *InMemoryStore) CampaignStats(tenant, campaign string) GroupStats {
+	return s.groupStats(s.campaigns, groupKey(tenant, campaign))
+}
+
+func (s *InMemoryStore) groupLinks(groups map[string]map[string]struct{}, group string) []Link {
+	s.mu.RLock()
+	members := groups[group]
+	links := make([]Link, 0, len(members))
+	for key := range members {
+		links = append(links, *s.links[key])
+	}
+	s.mu.RUnlock()
+	sortLinks(links)
+	return links
+}
+
+func (s *InMemoryStore) groupStats(groups map[string]map[string]struct{}, group string) GroupStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var stats GroupStats
+	for key := range groups[group] {
+		stats.Links++
+		stats.Clicks += s.links[key].Clicks
+	}
+	return stats
 }
 
-func (s *InMemoryStore) GetURL(code string) (string, error) {
+func (s *InMemoryStore) GetURL(key string) (string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	url, ok := s.codeToURL[code]
+	url, ok := s.codeToURL[key]
 	if !ok {
 		return "", ErrNotFound
 	}
 	return url, nil
 }
 
+func (s *InMemoryStore) GetLink(key string) (Link, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	link, ok := s.links[key]
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+	return *link, nil
+}
+
+// Visit counts one resolution of the link under key and returns the updated link. The check
+// against MaxClicks and the increment happen under the same lock, so
+// concurrent visitors can never exceed the limit.
+func (s *InMemoryStore) Visit(key string) (Link, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[key]
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+	if link.MaxClicks > 0 && link.Clicks >= link.MaxClicks {
+		return *link, ErrExhausted
+	}
+	link.Clicks++
+	return *link, nil
+}
+
+// AddVariantClicks adds counts, keyed by variant name, to the link's
+// variants. The variants slice is replaced rather than updated in place so
+// copies already handed out by GetLink stay consistent.
+func (s *InMemoryStore) AddVariantClicks(key string, counts map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	link, ok := s.links[key]
+	if !ok {
+		return
+	}
+	variants := make([]Variant, len(link.Variants))
+	copy(variants, link.Variants)
+	for i := range variants {
+		variants[i].Clicks += counts[variants[i].Name]
+	}
+	link.Variants = variants
+}
+
+// Links returns a copy of every stored link, oldest first.
+func (s *InMemoryStore) Links() []Link {
+	s.mu.RLock()
+	links := make([]Link, 0, len(s.links))
+	for _, link := range s.links {
+		links = append(links, *link)
+	}
+	s.mu.RUnlock()
+	sortLinks(links)
+	return links
+}
+
+// sortLinks orders links oldest first, by code among equals.
+func sortLinks(links []Link) {
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].Code < links[j].Code
+		}
+		return links[i].CreatedAt.Before(links[j].CreatedAt)
+	})
+}
+
+// SetHealth records the result of a destination check on the link stored
+// under key. Unknown keys are ignored, since the link may have been
+// replaced while it was being checked.
+func (s *InMemoryStore) SetHealth(key string, h Health) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if link, ok := s.links[key]; ok {
+		link.Health = h
+	}
+}
+
+// SetMetadata records the destination page metadata of the link stored under
+// key. Unknown keys are ignored.
+func (s *InMemoryStore) SetMetadata(key string, m Metadata) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if link, ok := s.links[key]; ok {
+		link.Metadata = m
+	}
+}
+
 func (s *InMemoryStore) GetCode(url string) (string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -69,13 +663,22 @@ func (s *InMemoryStore) GetCode(url string) (string, error) {
 	return code, nil
 }
 
+// GetTopDomains returns the default tenant's most shortened destination
+// domains.
 func (s *InMemoryStore) GetTopDomains(limit int) []DomainStats {
+	return s.TopDomains("", limit)
+}
+
+// TopDomains returns the most shortened destination domains among tenant's
+// links.
+func (s *InMemoryStore) TopDomains(tenant string, limit int) []DomainStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	// Convert map to slice for sorting
-	stats := make([]DomainStats, 0, len(s.domainCounts))
-	for domain, count := range s.domainCounts {
+	counts := s.domainCounts[tenant]
+	stats := make([]DomainStats, 0, len(counts))
+	for domain, count := range counts {
 		stats = append(stats, DomainStats{Domain: domain, Count: count})
 	}
 
@@ -1,9 +1,13 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
+	"time"
+
+	"assignment_infracloud/internal/encoding"
 )
 
 func TestNewInMemoryStore(t *testing.T) {
@@ -28,7 +32,7 @@ func TestInMemoryStore_NextID(t *testing.T) {
 	// Test that IDs are sequential
 	ids := make([]uint64, 10)
 	for i := 0; i < 10; i++ {
-		ids[i] = store.NextID()
+		ids[i], _ = store.NextID()
 	}
 
 	// Verify IDs are sequential starting from 1
@@ -224,7 +228,7 @@ func TestInMemoryStore_ConcurrentAccess(t *testing.T) {
 
 	// Verify we got the expected number of IDs
 	expectedID := uint64(numGoroutines)
-	actualID := store.NextID()
+	actualID, _ := store.NextID()
 	if actualID != expectedID+1 {
 		t.Errorf("After concurrent access, NextID() = %d, want %d", actualID, expectedID+1)
 	}
@@ -310,3 +314,357 @@ func BenchmarkInMemoryStore_GetTopDomains(b *testing.B) {
 		store.GetTopDomains(3)
 	}
 }
+
+func TestInMemoryStore_SaveLink_ProtectedNotDeduped(t *testing.T) {
+	store := NewInMemoryStore()
+	store.SaveLink(Link{Code: "p1", URL: "https://example.com", PasswordHash: "hash"})
+
+	if _, err := store.GetCode("https://example.com"); err != ErrNotFound {
+		t.Errorf("GetCode() error = %v, want %v", err, ErrNotFound)
+	}
+	link, err := store.GetLink("p1")
+	if err != nil {
+		t.Fatalf("GetLink() error = %v", err)
+	}
+	if link.PasswordHash != "hash" {
+		t.Errorf("GetLink().PasswordHash = %q, want %q", link.PasswordHash, "hash")
+	}
+	if url, _ := store.GetURL("p1"); url != "https://example.com" {
+		t.Errorf("GetURL() = %q, want %q", url, "https://example.com")
+	}
+}
+
+func TestInMemoryStore_Visit_MaxClicks(t *testing.T) {
+	store := NewInMemoryStore()
+	store.SaveLink(Link{Code: "once", URL: "https://example.com", MaxClicks: 1})
+
+	link, err := store.Visit("once")
+	if err != nil {
+		t.Fatalf("Visit() error = %v", err)
+	}
+	if remaining, limited := link.Remaining(); !limited || remaining != 0 {
+		t.Errorf("Remaining() = %d, %v, want 0, true", remaining, limited)
+	}
+	if _, err := store.Visit("once"); err != ErrExhausted {
+		t.Errorf("Visit() error = %v, want %v", err, ErrExhausted)
+	}
+	if _, err := store.Visit("missing"); err != ErrNotFound {
+		t.Errorf("Visit() error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestInMemoryStore_Visit_ConcurrentLimit(t *testing.T) {
+	store := NewInMemoryStore()
+	store.SaveLink(Link{Code: "five", URL: "https://example.com", MaxClicks: 5})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	served := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.Visit("five"); err == nil {
+				mu.Lock()
+				served++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if served != 5 {
+		t.Errorf("served %d visits, want 5", served)
+	}
+	link, _ := store.GetLink("five")
+	if link.Clicks != 5 {
+		t.Errorf("Clicks = %d, want 5", link.Clicks)
+	}
+}
+
+func TestLink_DedupKey(t *testing.T) {
+	plain := Link{URL: "https://example.com"}
+	if key, ok := plain.DedupKey(); !ok || key != "https://example.com" {
+		t.Errorf("plain DedupKey() = %q, %v, want URL, true", key, ok)
+	}
+
+	a := Link{URL: "https://example.com", UTM: UTM{Source: "newsletter", Campaign: "spring"}}
+	b := Link{URL: "https://example.com", UTM: UTM{Source: "newsletter", Campaign: "summer"}}
+	keyA, okA := a.DedupKey()
+	keyB, okB := b.DedupKey()
+	if !okA || !okB || keyA == keyB || keyA == "https://example.com" {
+		t.Errorf("UTM DedupKey() = %q, %q, want two distinct keys", keyA, keyB)
+	}
+
+	if _, ok := (Link{URL: "https://example.com", MaxClicks: 1}).DedupKey(); ok {
+		t.Error("click-limited link should not be deduplicated")
+	}
+}
+
+func TestInMemoryStore_UpdateLink(t *testing.T) {
+	store := NewInMemoryStore()
+	store.SaveMapping("c1", "https://old.com/a")
+
+	updated, err := store.UpdateLink("c1", func(l *Link) error {
+		l.URL = "https://new.com/a"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateLink() error = %v", err)
+	}
+	if updated.URL != "https://new.com/a" {
+		t.Errorf("UpdateLink().URL = %s, want https://new.com/a", updated.URL)
+	}
+	if url, _ := store.GetURL("c1"); url != "https://new.com/a" {
+		t.Errorf("GetURL() = %s, want https://new.com/a", url)
+	}
+	if _, err := store.GetCode("https://old.com/a"); err != ErrNotFound {
+		t.Errorf("old URL still indexed: GetCode() error = %v", err)
+	}
+	top := store.GetTopDomains(5)
+	if len(top) != 1 || top[0].Domain != "new.com" || top[0].Count != 1 {
+		t.Errorf("GetTopDomains() = %v, want only new.com with count 1", top)
+	}
+
+	abort := errors.New("abort")
+	if _, err := store.UpdateLink("c1", func(l *Link) error {
+		l.URL = "https://other.com"
+		return abort
+	}); err != abort {
+		t.Errorf("UpdateLink() error = %v, want %v", err, abort)
+	}
+	if url, _ := store.GetURL("c1"); url != "https://new.com/a" {
+		t.Errorf("aborted update changed URL to %s", url)
+	}
+
+	if _, err := store.UpdateLink("missing", func(*Link) error { return nil }); err != ErrNotFound {
+		t.Errorf("UpdateLink(missing) error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestInMemoryStore_AddVariantClicks(t *testing.T) {
+	store := NewInMemoryStore()
+	store.SaveLink(Link{Code: "ab", URL: "https://example.com", Variants: []Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 1},
+		{Name: "b", URL: "https://example.com/b", Weight: 1},
+	}})
+	before, _ := store.GetLink("ab")
+
+	store.AddVariantClicks("ab", map[string]int{"a": 2, "b": 1, "gone": 5})
+	store.AddVariantClicks("missing", map[string]int{"a": 1})
+
+	after, _ := store.GetLink("ab")
+	if after.Variants[0].Clicks != 2 || after.Variants[1].Clicks != 1 {
+		t.Errorf("variant clicks = %+v, want a=2 b=1", after.Variants)
+	}
+	if before.Variants[0].Clicks != 0 {
+		t.Error("AddVariantClicks() modified a previously returned copy")
+	}
+}
+
+func TestLink_DestinationAt(t *testing.T) {
+	launch := time.Date(2026, 6, 1, 9, 0, 0, 0, time.UTC)
+	link := Link{
+		URL: "https://example.com/default",
+		Schedule: []ScheduleEntry{
+			{ID: "teaser", URL: "https://example.com/teaser", End: launch},
+			{ID: "live", URL: "https://example.com/live", Start: launch},
+			{ID: "flash", URL: "https://example.com/flash", Start: launch.Add(24 * time.Hour), End: launch.Add(48 * time.Hour)},
+		},
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{"before launch", launch.Add(-time.Minute), "https://example.com/teaser"},
+		{"at launch", launch, "https://example.com/live"},
+		{"later start wins overlap", launch.Add(30 * time.Hour), "https://example.com/flash"},
+		{"after flash sale", launch.Add(72 * time.Hour), "https://example.com/live"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := link.DestinationAt(tt.at); got != tt.want {
+				t.Errorf("DestinationAt() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := (Link{URL: "https://example.com"}).DestinationAt(launch); got != "https://example.com" {
+		t.Errorf("DestinationAt() without schedule = %s, want the link URL", got)
+	}
+}
+
+func TestInMemoryStore_LinksAndHealth(t *testing.T) {
+	store := NewInMemoryStore()
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.SaveLink(Link{Code: "b", URL: "https://example.com/b", CreatedAt: t0.Add(time.Hour)})
+	store.SaveLink(Link{Code: "a", URL: "https://example.com/a", CreatedAt: t0})
+
+	store.SetHealth("b", Health{Status: HealthBroken, StatusCode: 404, CheckedAt: t0})
+	store.SetHealth("missing", Health{Status: HealthOK})
+
+	links := store.Links()
+	if len(links) != 2 || links[0].Code != "a" || links[1].Code != "b" {
+		t.Fatalf("Links() = %+v, want a then b", links)
+	}
+	if links[1].Health.Status != HealthBroken || links[1].Health.StatusCode != 404 {
+		t.Errorf("Links()[1].Health = %+v, want broken 404", links[1].Health)
+	}
+	if links[0].Health != (Health{}) {
+		t.Errorf("Links()[0].Health = %+v, want unchecked", links[0].Health)
+	}
+
+	store.SetMetadata("a", Metadata{Title: "A page", FetchedAt: t0})
+	if link, _ := store.GetLink("a"); link.Metadata.Title != "A page" {
+		t.Errorf("Metadata.Title = %q, want %q", link.Metadata.Title, "A page")
+	}
+}
+
+func TestInMemoryStore_DomainScopedCodes(t *testing.T) {
+	store := NewInMemoryStore()
+	store.SaveLink(Link{Code: "abc", URL: "https://example.com/default"})
+	store.SaveLink(Link{Code: "abc", Domain: "acme.link", URL: "https://example.com/branded"})
+
+	if link, _ := store.GetLink("abc"); link.URL != "https://example.com/default" {
+		t.Errorf("GetLink(abc).URL = %q, want the default-domain link", link.URL)
+	}
+	link, err := store.GetLink(Key("", "acme.link", "abc"))
+	if err != nil || link.URL != "https://example.com/branded" || link.Code != "abc" {
+		t.Errorf("GetLink(acme.link/abc) = %+v, %v, want the branded link", link, err)
+	}
+
+	updated, err := store.UpdateLink(Key("", "acme.link", "abc"), func(l *Link) error {
+		l.Domain, l.Code = "other", "zzz"
+		return nil
+	})
+	if err != nil || updated.Domain != "acme.link" || updated.Code != "abc" {
+		t.Errorf("UpdateLink() = %+v, %v, want code and domain kept", updated, err)
+	}
+
+	// The same URL dedups separately per domain.
+	store.SaveLink(Link{Code: "d1", URL: "https://example.com/same"})
+	store.SaveLink(Link{Code: "d2", Domain: "acme.link", URL: "https://example.com/same"})
+	k1, _ := Link{URL: "https://example.com/same"}.DedupKey()
+	k2, _ := Link{URL: "https://example.com/same", Domain: "acme.link"}.DedupKey()
+	if c, _ := store.GetCode(k1); c != "d1" {
+		t.Errorf("GetCode(default) = %q, want d1", c)
+	}
+	if c, _ := store.GetCode(k2); c != "d2" {
+		t.Errorf("GetCode(acme.link) = %q, want d2", c)
+	}
+}
+
+func TestInMemoryStore_TenantIsolation(t *testing.T) {
+	store := NewInMemoryStore()
+	store.SaveLink(Link{Code: "abc", URL: "https://default.example.com/x"})
+	store.SaveLink(Link{Code: "abc", Tenant: "acme", Domain: "acme.link", URL: "https://acme.example.com/x"})
+	store.SaveLink(Link{Code: "def", Tenant: "acme", Domain: "acme.link", URL: "https://acme.example.com/y"})
+
+	if link, _ := store.GetLink("abc"); link.Tenant != "" {
+		t.Errorf("GetLink(abc).Tenant = %q, want default tenant", link.Tenant)
+	}
+	if link, err := store.GetLink(Key("acme", "acme.link", "abc")); err != nil || link.Tenant != "acme" {
+		t.Errorf("GetLink(acme@acme.link/abc) = %+v, %v, want acme's link", link, err)
+	}
+	if _, err := store.GetLink(Key("", "acme.link", "abc")); err != ErrNotFound {
+		t.Errorf("GetLink() without tenant error = %v, want %v", err, ErrNotFound)
+	}
+
+	if got := store.TopDomains("acme", 5); len(got) != 1 || got[0] != (DomainStats{"acme.example.com", 2}) {
+		t.Errorf("TopDomains(acme) = %v, want [acme.example.com 2]", got)
+	}
+	if got := store.GetTopDomains(5); len(got) != 1 || got[0].Domain != "default.example.com" {
+		t.Errorf("GetTopDomains() = %v, want only the default tenant's domain", got)
+	}
+	if got := store.TopDomains("globex", 5); len(got) != 0 {
+		t.Errorf("TopDomains(globex) = %v, want none", got)
+	}
+}
+
+func TestInMemoryStore_TagIndex(t *testing.T) {
+	store := NewInMemoryStore()
+	store.SaveLink(Link{Code: "a", URL: "https://example.com/a", Tags: []string{"launch", "q3"}, Campaign: "spring", Clicks: 2, CreatedAt: time.Unix(1, 0)})
+	store.SaveLink(Link{Code: "b", URL: "https://example.com/b", Tags: []string{"launch"}, Clicks: 3, CreatedAt: time.Unix(2, 0)})
+	store.SaveLink(Link{Code: "a", Tenant: "acme", URL: "https://example.com/a", Tags: []string{"launch"}, Campaign: "spring", Clicks: 7})
+
+	if got := store.LinksByTag("", "launch"); len(got) != 2 || got[0].Code != "a" || got[1].Code != "b" {
+		t.Errorf("LinksByTag(launch) = %v, want [a b]", got)
+	}
+	if got := store.TagStats("", "launch"); got != (GroupStats{Links: 2, Clicks: 5}) {
+		t.Errorf("TagStats(launch) = %+v, want {2 5}", got)
+	}
+	if got := store.CampaignStats("acme", "spring"); got != (GroupStats{Links: 1, Clicks: 7}) {
+		t.Errorf("CampaignStats(acme, spring) = %+v, want {1 7}", got)
+	}
+
+	store.UpdateLink("a", func(l *Link) error {
+		l.Tags, l.Campaign = []string{"q3"}, ""
+		return nil
+	})
+	if got := store.LinksByTag("", "launch"); len(got) != 1 || got[0].Code != "b" {
+		t.Errorf("LinksByTag(launch) after untag = %v, want [b]", got)
+	}
+	if got := store.LinksByCampaign("", "spring"); len(got) != 0 {
+		t.Errorf("LinksByCampaign(spring) = %v, want none", got)
+	}
+	if got := store.LinksByCampaign("acme", "spring"); len(got) != 1 {
+		t.Errorf("LinksByCampaign(acme, spring) = %v, want acme's link", got)
+	}
+
+	// Clicks show up in the stats without touching the index.
+	store.Visit("b")
+	if got := store.TagStats("", "launch"); got.Clicks != 4 {
+		t.Errorf("TagStats(launch).Clicks after visit = %d, want 4", got.Clicks)
+	}
+}
+
+func TestInMemoryStore_ImportLink(t *testing.T) {
+	store := NewInMemoryStore()
+	store.SaveLink(Link{Code: "1", URL: "https://example.com/a"})
+
+	if err := store.ImportLink(Link{Code: "1", URL: "https://example.com/other"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("ImportLink(taken code) = %v, want ErrConflict", err)
+	}
+	if err := store.ImportLink(Link{Code: "1", Domain: "go.example", URL: "https://example.com/other"}); err != nil {
+		t.Errorf("ImportLink(same code, other domain) = %v", err)
+	}
+	if err := store.ImportLink(Link{Code: "x", URL: "https://example.com/a", Clicks: 5}); err != nil {
+		t.Fatalf("ImportLink(duplicate destination) = %v", err)
+	}
+	if link, _ := store.GetLink("x"); link.Clicks != 5 {
+		t.Errorf("imported clicks = %d, want 5", link.Clicks)
+	}
+	if code, _ := store.GetCode("https://example.com/a"); code != "1" {
+		t.Errorf("GetCode after import = %q, want the existing code 1", code)
+	}
+	if versions, _ := store.History("x"); len(versions) != 1 {
+		t.Errorf("imported history = %d versions, want 1", len(versions))
+	}
+
+	store.ReserveCode(encoding.Base62Encode(40))
+	store.ReserveCode(encoding.Base62Encode(7))
+	store.ReserveCode("lYGhA16ahyf") // MaxUint64
+	store.ReserveCode("0a")
+	if id, _ := store.NextID(); id != 41 {
+		t.Errorf("NextID after reserving code 40 = %d, want 41", id)
+	}
+
+	store.ReserveCode(encoding.Base62Encode(MaxID))
+	if id, err := store.NextID(); err != ErrIDsExhausted {
+		t.Errorf("NextID after reserving MaxID = %d, %v, want ErrIDsExhausted", id, err)
+	}
+}
+
+func TestInMemoryStore_InsertLink(t *testing.T) {
+	store := NewInMemoryStore()
+	if err := store.InsertLink(Link{Code: "1", URL: "https://example.com/a"}); err != nil {
+		t.Fatalf("InsertLink() error = %v", err)
+	}
+	if err := store.InsertLink(Link{Code: "1", URL: "https://example.com/b"}); err != ErrConflict {
+		t.Errorf("InsertLink(taken) error = %v, want ErrConflict", err)
+	}
+	if url, _ := store.GetURL("1"); url != "https://example.com/a" {
+		t.Errorf("GetURL after conflicting insert = %q, want the original", url)
+	}
+}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+)
+
+// snapshotVersion is written into every snapshot; Restore refuses others.
+const snapshotVersion = 1
+
+// Snapshot is everything an InMemoryStore holds, derived indexes included,
+// so a persisted store can be checked against its links and repaired.
+type Snapshot struct {
+	Version   int
+	IDCounter uint64
+	Links     []Link
+	History   map[string][]Version
+	CodeToURL map[string]string
+	URLToCode map[string]string
+	// DomainCounts is keyed by tenant, then destination domain.
+	DomainCounts map[string]map[string]int
+	// Tags and Campaigns map a tenant-scoped group to the keys of its links.
+	Tags      map[string][]string
+	Campaigns map[string][]string
+}
+
+// Snapshot returns a copy of the store's contents, links oldest first.
+func (s *InMemoryStore) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snap := Snapshot{
+		Version:      snapshotVersion,
+		IDCounter:    s.idCounter,
+		Links:        make([]Link, 0, len(s.links)),
+		History:      make(map[string][]Version, len(s.history)),
+		CodeToURL:    maps.Clone(s.codeToURL),
+		URLToCode:    maps.Clone(s.urlToCode),
+		DomainCounts: make(map[string]map[string]int, len(s.domainCounts)),
+		Tags:         groupMembers(s.tags),
+		Campaigns:    groupMembers(s.campaigns),
+	}
+	for _, link := range s.links {
+		snap.Links = append(snap.Links, *link)
+	}
+	sortLinks(snap.Links)
+	for key, versions := range s.history {
+		snap.History[key] = slices.Clone(versions)
+	}
+	for tenant, counts := range s.domainCounts {
+		snap.DomainCounts[tenant] = maps.Clone(counts)
+	}
+	return snap
+}
+
+// Restore creates a store holding exactly what snap holds. Its indexes are
+// taken as they are, not recomputed; Check finds where they disagree with
+// the links and Reindex rebuilds them.
+func Restore(snap Snapshot) (*InMemoryStore, error) {
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+	s := NewInMemoryStore()
+	s.idCounter = snap.IDCounter
+	for i := range snap.Links {
+		link := snap.Links[i]
+		if _, ok := s.links[link.Key()]; ok {
+			return nil, fmt.Errorf("duplicate link %s", link.Key())
+		}
+		s.links[link.Key()] = &link
+	}
+	for key, versions := range snap.History {
+		s.history[key] = slices.Clone(versions)
+	}
+	maps.Copy(s.codeToURL, snap.CodeToURL)
+	maps.Copy(s.urlToCode, snap.URLToCode)
+	for tenant, counts := range snap.DomainCounts {
+		s.domainCounts[tenant] = maps.Clone(counts)
+	}
+	for group, keys := range snap.Tags {
+		for _, key := range keys {
+			addToGroup(s.tags, group, key)
+		}
+	}
+	for group, keys := range snap.Campaigns {
+		for _, key := range keys {
+			addToGroup(s.campaigns, group, key)
+		}
+	}
+	return s, nil
+}
+
+// Check compares the store's derived indexes with its links and returns a
+// description of every inconsistency, or nothing for a healthy store. The
+// code and URL indexes must map each link to its destination and each
+// deduplicable destination back to one of its links; domain counts and tag
+// and campaign groups must match the links; every link needs a history, only
+// deleted links may have a history without a link, and no generated code may
+// lie beyond the ID counter.
+func (s *InMemoryStore) Check() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	want := derive(s.links)
+
+	for _, key := range sortedKeys(s.links) {
+		link := s.links[key]
+		if url, ok := s.codeToURL[key]; !ok {
+			add("link %s is missing from the code index", key)
+		} else if url != link.URL {
+			add("code index maps %s to %q, the link points to %q", key, url, link.URL)
+		}
+		if _, ok := s.history[key]; !ok {
+			add("link %s has no history", key)
+		}
+		if id, ok := generatedID(link.Code); ok && id > s.idCounter {
+			add("link %s is beyond the ID counter %d", key, s.idCounter)
+		}
+	}
+	for _, key := range sortedKeys(s.codeToURL) {
+		if _, ok := s.links[key]; !ok {
+			add("code index entry %s has no link", key)
+		}
+	}
+	for _, key := range sortedKeys(s.history) {
+		_, ok := s.links[key]
+		if dead := deleted(s.history[key]); !ok && !dead {
+			add("history of %s has no link", key)
+		} else if ok && dead {
+			add("link %s has a history ending in its deletion", key)
+		}
+	}
+
+	for _, dk := range sortedKeys(s.urlToCode) {
+		code := s.urlToCode[dk]
+		if codes, ok := want.dedup[dk]; !ok {
+			add("url index entry %q has no link", dk)
+		} else if !slices.Contains(codes, code) {
+			add("url index maps %q to %s, which has another destination", dk, code)
+		}
+	}
+	for _, dk := range sortedKeys(want.dedup) {
+		if _, ok := s.urlToCode[dk]; !ok {
+			add("destination %q of %s is missing from the url index", dk, want.dedup[dk][0])
+		}
+	}
+
+	for _, tenant := range sortedKeys(mergeKeys(s.domainCounts, want.domainCounts)) {
+		have, need := s.domainCounts[tenant], want.domainCounts[tenant]
+		for _, domain := range sortedKeys(mergeKeys(have, need)) {
+			if have[domain] != need[domain] {
+				add("domain count of %s for tenant %q is %d, its links say %d", domain, tenant, have[domain], need[domain])
+			}
+		}
+	}
+
+	for _, g := range []struct {
+		kind       string
+		have, need map[string]map[string]struct{}
+	}{{"tag", s.tags, want.tags}, {"campaign", s.campaigns, want.campaigns}} {
+		for _, group := range sortedKeys(mergeKeys(g.have, g.need)) {
+			if !maps.Equal(g.have[group], g.need[group]) {
+				add("%s index for %q lists %v, its links say %v", g.kind, group,
+					sortedKeys(g.have[group]), sortedKeys(g.need[group]))
+			}
+		}
+	}
+	return problems
+}
+
+// Reindex rebuilds every derived index from the links, starting a history
+// for links that lack a live one and moving the ID counter past generated
+// codes. Histories of missing links are dropped unless they record a
+// deletion. Where several links share a destination, the oldest becomes the
+// one Shorten returns.
+func (s *InMemoryStore) Reindex() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	links := make([]*Link, 0, len(s.links))
+	for _, link := range s.links {
+		links = append(links, link)
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].CreatedAt.Equal(links[j].CreatedAt) {
+			return links[i].Key() < links[j].Key()
+		}
+		return links[i].CreatedAt.Before(links[j].CreatedAt)
+	})
+
+	want := derive(s.links)
+	s.codeToURL = make(map[string]string, len(links))
+	s.urlToCode = make(map[string]string, len(want.dedup))
+	s.domainCounts, s.tags, s.campaigns = want.domainCounts, want.tags, want.campaigns
+	for _, link := range links {
+		s.codeToURL[link.Key()] = link.URL
+		if dk, ok := link.DedupKey(); ok {
+			if _, taken := s.urlToCode[dk]; !taken {
+				s.urlToCode[dk] = link.Code
+			}
+		}
+		if versions, ok := s.history[link.Key()]; !ok || deleted(versions) {
+			first := firstVersion(*link)
+			first.Number = len(versions) + 1
+			s.history[link.Key()] = append(versions, first)
+		}
+		if id, ok := generatedID(link.Code); ok && id > s.idCounter {
+			s.idCounter = id
+		}
+	}
+	for key, versions := range s.history {
+		if _, ok := s.links[key]; !ok && !deleted(versions) {
+			delete(s.history, key)
+		}
+	}
+}
+
+// derived is what the indexes should hold for a set of links.
+type derived struct {
+	// dedup maps each dedup key to the codes of the links having it.
+	dedup        map[string][]string
+	domainCounts map[string]map[string]int
+	tags         map[string]map[string]struct{}
+	campaigns    map[string]map[string]struct{}
+}
+
+func derive(links map[string]*Link) derived {
+	d := derived{
+		dedup:        make(map[string][]string),
+		domainCounts: make(map[string]map[string]int),
+		tags:         make(map[string]map[string]struct{}),
+		campaigns:    make(map[string]map[string]struct{}),
+	}
+	for key, link := range links {
+		if dk, ok := link.DedupKey(); ok {
+			d.dedup[dk] = append(d.dedup[dk], link.Code)
+		}
+		if domain := extractDomain(link.URL); domain != "" {
+			if d.domainCounts[link.Tenant] == nil {
+				d.domainCounts[link.Tenant] = make(map[string]int)
+			}
+			d.domainCounts[link.Tenant][domain]++
+		}
+		for _, tag := range link.Tags {
+			addToGroup(d.tags, groupKey(link.Tenant, tag), key)
+		}
+		if link.Campaign != "" {
+			addToGroup(d.campaigns, groupKey(link.Tenant, link.Campaign), key)
+		}
+	}
+	for _, codes := range d.dedup {
+		slices.Sort(codes)
+	}
+	return d
+}
+
+func groupMembers(groups map[string]map[string]struct{}) map[string][]string {
+	out := make(map[string][]string, len(groups))
+	for group, members := range groups {
+		out[group] = sortedKeys(members)
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// mergeKeys returns a set of the keys of a and b.
+func mergeKeys[V any](a, b map[string]V) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for k := range a {
+		keys[k] = struct{}{}
+	}
+	for k := range b {
+		keys[k] = struct{}{}
+	}
+	return keys
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSnapshotStore() *InMemoryStore {
+	store := NewInMemoryStore()
+	for i := 0; i < 3; i++ {
+		store.NextID()
+	}
+	store.SaveLink(Link{Code: "1", URL: "https://example.com/a", Tags: []string{"launch"}, CreatedAt: time.Unix(1, 0)})
+	store.SaveLink(Link{Code: "2", Tenant: "acme", URL: "https://example.org/b", Campaign: "spring", CreatedAt: time.Unix(2, 0)})
+	store.SaveLink(Link{Code: "3", URL: "https://example.com/d", CreatedAt: time.Unix(3, 0)})
+	store.EditLink("1", "alice", time.Unix(3, 0), func(l *Link) error {
+		l.URL = "https://example.com/c"
+		return nil
+	})
+	store.Visit("1")
+	return store
+}
+
+func TestBackends_RoundTrip(t *testing.T) {
+	store := newSnapshotStore()
+	dir := t.TempDir()
+	for _, spec := range []string{filepath.Join(dir, "store.json"), filepath.Join(dir, "store.ndjson"), "json:" + filepath.Join(dir, "other")} {
+		b, err := OpenBackend(spec)
+		if err != nil {
+			t.Fatalf("OpenBackend(%q) error = %v", spec, err)
+		}
+		if _, err := b.Load(); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: Load() before Save error = %v, want ErrNotExist", b, err)
+		}
+		if err := b.Save(store.Snapshot()); err != nil {
+			t.Fatalf("%s: Save() error = %v", b, err)
+		}
+		loaded, err := LoadStore(b)
+		if err != nil {
+			t.Fatalf("%s: LoadStore() error = %v", b, err)
+		}
+		if problems := loaded.Check(); len(problems) != 0 {
+			t.Errorf("%s: Check() after load = %v", b, problems)
+		}
+		link, _ := loaded.GetLink("1")
+		versions, _ := loaded.History("1")
+		if link.URL != "https://example.com/c" || link.Clicks != 1 || len(versions) != 2 {
+			t.Errorf("%s: loaded link = %+v with %d versions", b, link, len(versions))
+		}
+		if got := loaded.CampaignStats("acme", "spring"); got.Links != 1 {
+			t.Errorf("%s: CampaignStats after load = %+v", b, got)
+		}
+		if id, _ := loaded.NextID(); id != 4 {
+			t.Errorf("%s: NextID after load = %d, want 4", b, id)
+		}
+	}
+	if _, err := OpenBackend("redis:localhost"); err == nil {
+		t.Error("OpenBackend(redis) succeeded, want an error")
+	}
+}
+
+func TestInMemoryStore_CheckAndReindex(t *testing.T) {
+	snap := newSnapshotStore().Snapshot()
+	snap.IDCounter = 1
+	snap.CodeToURL["1"] = "https://example.com/a"
+	delete(snap.URLToCode, "https://example.com/d")
+	snap.URLToCode["https://example.com/gone"] = "9"
+	snap.DomainCounts[""]["example.com"] = 5
+	snap.Tags["\x00launch"] = append(snap.Tags["\x00launch"], "2")
+	delete(snap.History, "acme@2")
+	store, err := Restore(snap)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	problems := strings.Join(store.Check(), "\n")
+	for _, want := range []string{
+		"code index maps 1",
+		"link acme@2 has no history",
+		"beyond the ID counter",
+		`url index entry "https://example.com/gone" has no link`,
+		"missing from the url index",
+		"domain count of example.com for tenant \"\" is 5, its links say 2",
+		"tag index",
+	} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Check() = %s\nwant a problem containing %q", problems, want)
+		}
+	}
+
+	store.Reindex()
+	if problems := store.Check(); len(problems) != 0 {
+		t.Errorf("Check() after Reindex = %v", problems)
+	}
+	if url, _ := store.GetURL("1"); url != "https://example.com/c" {
+		t.Errorf("GetURL(1) after Reindex = %q", url)
+	}
+	if id, _ := store.NextID(); id != 4 {
+		t.Errorf("NextID after Reindex = %d, want 4", id)
+	}
+
+	snap.Links = append(snap.Links, snap.Links[0])
+	if _, err := Restore(snap); err == nil {
+		t.Error("Restore(duplicate link) succeeded, want an error")
+	}
+}
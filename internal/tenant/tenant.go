@@ -0,0 +1,162 @@
+// Package tenant maps API keys and short domains to the business unit that
+// owns them. The default tenant has the empty ID and owns the deployment's
+// base domain and any domain not assigned to another tenant.
+package tenant
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrUnknownKey = errors.New("unknown api key")
+
+// Registry is the parsed tenant configuration. It is read-only once built.
+type Registry struct {
+	// keys maps the SHA-256 of an API key to its tenant, so lookups do not
+	// compare secrets byte by byte.
+	keys    map[[sha256.Size]byte]string
+	owners  map[string]string
+	domains map[string][]string
+}
+
+// Parse builds a registry from two specs:
+//
+//	tenants: "acme:go.acme.com acme.link,globex:glbx.io"
+//	apiKeys: "acme:k3y1,globex:k3y2,adminkey"
+//
+// Each tenant lists the short domains it owns, the first being where its
+// links are created by default; every tenant needs at least one. API keys
+// without a "tenant:" prefix belong to the default tenant.
+func Parse(tenants, apiKeys string) (*Registry, error) {
+	r := &Registry{
+		keys:    make(map[[sha256.Size]byte]string),
+		owners:  make(map[string]string),
+		domains: make(map[string][]string),
+	}
+	for _, entry := range split(tenants) {
+		id, list, ok := strings.Cut(entry, ":")
+		domains := strings.Fields(strings.ToLower(list))
+		if !ok || !ValidID(id) || len(domains) == 0 {
+			return nil, fmt.Errorf("invalid tenant entry %q, want id:domain [domain...]", entry)
+		}
+		if _, dup := r.domains[id]; dup {
+			return nil, fmt.Errorf("duplicate tenant %q", id)
+		}
+		for _, d := range domains {
+			if owner, taken := r.owners[d]; taken {
+				return nil, fmt.Errorf("domain %q assigned to both %q and %q", d, owner, id)
+			}
+			r.owners[d] = id
+		}
+		r.domains[id] = domains
+	}
+	for _, entry := range split(apiKeys) {
+		id, key, ok := strings.Cut(entry, ":")
+		if !ok {
+			id, key = "", entry
+		}
+		if key == "" {
+			return nil, fmt.Errorf("invalid api key entry %q", entry)
+		}
+		if _, known := r.domains[id]; id != "" && !known {
+			return nil, fmt.Errorf("api key for unknown tenant %q", id)
+		}
+		sum := sha256.Sum256([]byte(key))
+		if _, dup := r.keys[sum]; dup {
+			return nil, errors.New("duplicate api key")
+		}
+		r.keys[sum] = id
+	}
+	return r, nil
+}
+
+// ValidID reports whether id can name a tenant: lower-case letters, digits
+// and dashes.
+func ValidID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireKeys reports whether API keys are configured, in which case API
+// calls must present one.
+func (r *Registry) RequireKeys() bool {
+	return len(r.keys) > 0
+}
+
+// ForKey returns the tenant owning an API key.
+func (r *Registry) ForKey(key string) (string, error) {
+	id, ok := r.keys[sha256.Sum256([]byte(key))]
+	if !ok {
+		return "", ErrUnknownKey
+	}
+	return id, nil
+}
+
+// KeyID returns a short fingerprint of an API key that identifies it in
+// logs and link histories without revealing it.
+func KeyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// KeyIDs returns the KeyID of every configured API key, sorted.
+func (r *Registry) KeyIDs() []string {
+	ids := make([]string, 0, len(r.keys))
+	for sum := range r.keys {
+		ids = append(ids, hex.EncodeToString(sum[:4]))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Owner returns the tenant owning a short domain; domains not assigned to
+// any tenant belong to the default one.
+func (r *Registry) Owner(domain string) string {
+	return r.owners[strings.ToLower(domain)]
+}
+
+// IDs returns the configured tenants, excluding the default one, sorted.
+func (r *Registry) IDs() []string {
+	ids := make([]string, 0, len(r.domains))
+	for id := range r.domains {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Domains returns the short domains of a tenant, primary first. The default
+// tenant's list is empty: its primary domain is the base domain.
+func (r *Registry) Domains(id string) []string {
+	return r.domains[id]
+}
+
+// PrimaryDomain is where a tenant's links go when no domain is requested,
+// "" meaning the base domain.
+func (r *Registry) PrimaryDomain(id string) string {
+	if d := r.domains[id]; len(d) > 0 {
+		return d[0]
+	}
+	return ""
+}
+
+func split(spec string) []string {
+	var out []string
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package tenant
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	r, err := Parse("acme:Go.Acme.com acme.link, globex:glbx.io", "acme:k1,globex:k2,admin")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if !r.RequireKeys() {
+		t.Error("RequireKeys() = false, want true")
+	}
+
+	for key, want := range map[string]string{"k1": "acme", "k2": "globex", "admin": ""} {
+		if got, err := r.ForKey(key); err != nil || got != want {
+			t.Errorf("ForKey(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+	if _, err := r.ForKey("acme:k1"); err != ErrUnknownKey {
+		t.Errorf("ForKey(unknown) error = %v, want %v", err, ErrUnknownKey)
+	}
+
+	for domain, want := range map[string]string{"go.acme.com": "acme", "ACME.LINK": "acme", "glbx.io": "globex", "sho.rt": ""} {
+		if got := r.Owner(domain); got != want {
+			t.Errorf("Owner(%q) = %q, want %q", domain, got, want)
+		}
+	}
+	if got := r.PrimaryDomain("acme"); got != "go.acme.com" {
+		t.Errorf("PrimaryDomain(acme) = %q, want go.acme.com", got)
+	}
+	if got := r.IDs(); len(got) != 2 || got[0] != "acme" || got[1] != "globex" {
+		t.Errorf("IDs() = %v, want [acme globex]", got)
+	}
+	if got := r.PrimaryDomain(""); got != "" {
+		t.Errorf("PrimaryDomain(default) = %q, want base domain", got)
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	r, err := Parse("", "")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if r.RequireKeys() {
+		t.Error("RequireKeys() = true with no keys configured")
+	}
+	if got := r.Owner("anything.example"); got != "" {
+		t.Errorf("Owner() = %q, want default tenant", got)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name, tenants, keys string
+	}{
+		{"no domains", "acme:", ""},
+		{"no separator", "acme", ""},
+		{"bad id", "Acme Corp:acme.link", ""},
+		{"duplicate tenant", "acme:a.link,acme:b.link", ""},
+		{"shared domain", "acme:a.link,globex:a.link", ""},
+		{"unknown tenant key", "acme:a.link", "globex:k"},
+		{"empty key", "acme:a.link", "acme:"},
+		{"duplicate key", "acme:a.link", "acme:k,k"},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.tenants, tt.keys); err == nil {
+			t.Errorf("%s: Parse(%q, %q) error = nil, want error", tt.name, tt.tenants, tt.keys)
+		}
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	if got := KeyID("k1"); len(got) != 8 || got == KeyID("k2") {
+		t.Errorf("KeyID(k1) = %q, want 8 hex digits distinct from KeyID(k2)", got)
+	}
+	if KeyID("k1") != KeyID("k1") {
+		t.Error("KeyID() is not stable")
+	}
+	r, _ := Parse("", "k1")
+	if got := r.KeyIDs(); len(got) != 1 || got[0] != KeyID("k1") {
+		t.Errorf("KeyIDs() = %v, want [%s]", got, KeyID("k1"))
+	}
+}
@@ -0,0 +1,73 @@
+package useragent
+
+import (
+	"strings"
+)
+
+type Platform string
+
+const (
+	IOS      Platform = "ios"
+	Android  Platform = "android"
+	Windows  Platform = "windows"
+	MacOS    Platform = "macos"
+	Linux    Platform = "linux"
+	ChromeOS Platform = "chromeos"
+	Other    Platform = "other"
+)
+
+// Groups usable in targeting rules alongside the concrete platforms.
+const (
+	Mobile  = "mobile"
+	Desktop = "desktop"
+)
+
+type Info struct {
+	Platform Platform
+	Mobile   bool
+}
+
+// Parse classifies a User-Agent header by operating system. It only looks
+// for the handful of tokens needed for redirect targeting; anything it does
+// not recognise is reported as Other.
+func Parse(ua string) Info {
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "iPod"):
+		return Info{Platform: IOS, Mobile: true}
+	case strings.Contains(ua, "Windows Phone"):
+		// Windows Phone UAs also claim Android for compatibility.
+		return Info{Platform: Other, Mobile: true}
+	case strings.Contains(ua, "Android"):
+		return Info{Platform: Android, Mobile: true}
+	case strings.Contains(ua, "Windows"):
+		return Info{Platform: Windows}
+	case strings.Contains(ua, "CrOS"):
+		return Info{Platform: ChromeOS}
+	case strings.Contains(ua, "Macintosh"), strings.Contains(ua, "Mac OS X"):
+		return Info{Platform: MacOS}
+	case strings.Contains(ua, "Linux"), strings.Contains(ua, "X11"):
+		return Info{Platform: Linux}
+	}
+	return Info{Platform: Other, Mobile: strings.Contains(ua, "Mobile")}
+}
+
+// Matches reports whether target, a platform name or one of the Mobile and
+// Desktop groups, applies to i.
+func (i Info) Matches(target string) bool {
+	switch target {
+	case Mobile:
+		return i.Mobile
+	case Desktop:
+		return !i.Mobile && i.Platform != Other
+	}
+	return string(i.Platform) == target
+}
+
+// ValidTarget reports whether target can be used in a rule.
+func ValidTarget(target string) bool {
+	switch Platform(target) {
+	case IOS, Android, Windows, MacOS, Linux, ChromeOS, Other:
+		return true
+	}
+	return target == Mobile || target == Desktop
+}
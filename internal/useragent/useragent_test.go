@@ -0,0 +1,76 @@
+package useragent
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		ua     string
+		want   Platform
+		mobile bool
+	}{
+		{"iphone safari", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1", IOS, true},
+		{"ipad", "Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1", IOS, true},
+		{"iphone chrome", "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/123.0.6312.52 Mobile/15E148 Safari/604.1", IOS, true},
+		{"android chrome", "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Mobile Safari/537.36", Android, true},
+		{"android tablet", "Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36", Android, true},
+		{"windows edge", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36 Edg/123.0.2420.65", Windows, false},
+		{"windows firefox", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:124.0) Gecko/20100101 Firefox/124.0", Windows, false},
+		{"mac safari", "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15", MacOS, false},
+		{"linux firefox", "Mozilla/5.0 (X11; Linux x86_64; rv:124.0) Gecko/20100101 Firefox/124.0", Linux, false},
+		{"ubuntu chrome", "Mozilla/5.0 (X11; Ubuntu; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36", Linux, false},
+		{"chromebook", "Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/123.0.0.0 Safari/537.36", ChromeOS, false},
+		{"windows phone", "Mozilla/5.0 (Windows Phone 10.0; Android 6.0.1; Microsoft; Lumia 950) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/52.0.2743.116 Mobile Safari/537.36 Edge/15.15063", Other, true},
+		{"curl", "curl/8.5.0", Other, false},
+		{"empty", "", Other, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.ua)
+			if got.Platform != tt.want || got.Mobile != tt.mobile {
+				t.Errorf("Parse() = %+v, want platform %s mobile %v", got, tt.want, tt.mobile)
+			}
+		})
+	}
+}
+
+func TestInfo_Matches(t *testing.T) {
+	ios := Info{Platform: IOS, Mobile: true}
+	mac := Info{Platform: MacOS}
+	unknown := Info{Platform: Other}
+
+	tests := []struct {
+		info   Info
+		target string
+		want   bool
+	}{
+		{ios, "ios", true},
+		{ios, Mobile, true},
+		{ios, Desktop, false},
+		{ios, "android", false},
+		{mac, Desktop, true},
+		{mac, Mobile, false},
+		{unknown, Desktop, false},
+		{unknown, "other", true},
+	}
+	for _, tt := range tests {
+		if got := tt.info.Matches(tt.target); got != tt.want {
+			t.Errorf("%+v.Matches(%q) = %v, want %v", tt.info, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestValidTarget(t *testing.T) {
+	for _, target := range []string{"ios", "android", "windows", "macos", "linux", "chromeos", "other", "mobile", "desktop"} {
+		if !ValidTarget(target) {
+			t.Errorf("ValidTarget(%q) = false, want true", target)
+		}
+	}
+	for _, target := range []string{"", "iOS", "blackberry"} {
+		if ValidTarget(target) {
+			t.Errorf("ValidTarget(%q) = true, want false", target)
+		}
+	}
+}
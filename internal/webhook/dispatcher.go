@@ -0,0 +1,362 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"assignment_infracloud/internal/netguard"
+)
+
+const (
+	// maxDeadLetters bounds the dead-letter list; the oldest entries go first.
+	maxDeadLetters = 1000
+	deliverTimeout = 10 * time.Second
+)
+
+// Subscription sends the events of one tenant to URL. An empty Events list
+// subscribes to every type.
+type Subscription struct {
+	ID        string
+	Tenant    string
+	URL       string
+	Secret    string
+	Events    []string
+	CreatedAt time.Time
+}
+
+func (s Subscription) wants(e Event) bool {
+	if e.Tenant != s.Tenant {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, typ := range s.Events {
+		if typ == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// DeadLetter is an event that could not be delivered to a subscription.
+type DeadLetter struct {
+	Subscription string
+	Tenant       string
+	Event        Event
+	Attempts     int
+	Error        string
+	FailedAt     time.Time
+}
+
+// Backoff is the retry policy: up to Attempts deliveries, waiting roughly
+// Base, 2*Base, 4*Base, ... (at most Max) between them.
+type Backoff struct {
+	Attempts int
+	Base     time.Duration
+	Max      time.Duration
+}
+
+// delay is the wait before retrying after attempt failed, with jitter over
+// its upper half so failed deliveries do not retry in lockstep.
+func (b Backoff) delay(attempt int) time.Duration {
+	d := b.Base
+	for i := 1; i < attempt && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	if half := d / 2; half > 0 {
+		d = half + rand.N(half)
+	}
+	return d
+}
+
+type delivery struct {
+	sub     Subscription
+	event   Event
+	body    []byte
+	attempt int
+}
+
+// Dispatcher holds the subscriptions and delivers published events to them
+// from a pool of background workers.
+type Dispatcher struct {
+	client  *http.Client
+	workers int
+	backoff Backoff
+	queue   chan delivery
+	now     func() time.Time
+
+	mu   sync.Mutex
+	subs map[string]Subscription
+	dead []DeadLetter
+	// retries holds the deliveries waiting on their backoff, by timer.
+	retries map[*time.Timer]delivery
+	// stopped is set once Run has returned; deliveries are dead-lettered
+	// from then on instead of queued.
+	stopped bool
+}
+
+// NewDispatcher creates a dispatcher queueing up to queueSize deliveries for
+// workers goroutines. A nil client means one with a 10 second timeout that
+// only connects to public addresses and does not follow redirects, so
+// subscriptions cannot be pointed at internal services.
+func NewDispatcher(client *http.Client, workers, queueSize int, backoff Backoff) *Dispatcher {
+	if client == nil {
+		client = &http.Client{
+			Timeout:   deliverTimeout,
+			Transport: netguard.Transport(deliverTimeout),
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if backoff.Attempts < 1 {
+		backoff.Attempts = 1
+	}
+	return &Dispatcher{
+		client:  client,
+		workers: workers,
+		backoff: backoff,
+		queue:   make(chan delivery, queueSize),
+		now:     time.Now,
+		subs:    make(map[string]Subscription),
+		retries: make(map[*time.Timer]delivery),
+	}
+}
+
+// Subscribe adds a subscription for the tenant. An empty secret is replaced
+// by a random one, returned in the subscription.
+func (d *Dispatcher) Subscribe(tenant, rawURL, secret string, events []string) (Subscription, error) {
+	u, err := url.ParseRequestURI(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Subscription{}, ErrInvalidURL
+	}
+	for _, typ := range events {
+		if !ValidEvent(typ) {
+			return Subscription{}, ErrInvalidEvent
+		}
+	}
+	if secret == "" {
+		secret = randomHex(32)
+	}
+	sub := Subscription{
+		ID:        randomHex(8),
+		Tenant:    tenant,
+		URL:       rawURL,
+		Secret:    secret,
+		Events:    append([]string(nil), events...),
+		CreatedAt: d.now().UTC(),
+	}
+	d.mu.Lock()
+	d.subs[sub.ID] = sub
+	d.mu.Unlock()
+	return sub, nil
+}
+
+// Unsubscribe removes one of the tenant's subscriptions.
+func (d *Dispatcher) Unsubscribe(tenant, id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if sub, ok := d.subs[id]; !ok || sub.Tenant != tenant {
+		return ErrNotFound
+	}
+	delete(d.subs, id)
+	return nil
+}
+
+// Subscriptions returns the tenant's subscriptions, oldest first.
+func (d *Dispatcher) Subscriptions(tenant string) []Subscription {
+	d.mu.Lock()
+	var subs []Subscription
+	for _, sub := range d.subs {
+		if sub.Tenant == tenant {
+			subs = append(subs, sub)
+		}
+	}
+	d.mu.Unlock()
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].ID < subs[j].ID
+		}
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+	return subs
+}
+
+// DeadLetters returns the tenant's undeliverable events, oldest first.
+func (d *Dispatcher) DeadLetters(tenant string) []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var out []DeadLetter
+	for _, dl := range d.dead {
+		if dl.Tenant == tenant {
+			out = append(out, dl)
+		}
+	}
+	return out
+}
+
+// Publish queues e for every subscription that wants it. It never blocks:
+// when the queue is full the delivery goes straight to the dead letters.
+func (d *Dispatcher) Publish(e Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("webhook event %s: %v", e.ID, err)
+		return
+	}
+	d.mu.Lock()
+	var targets []Subscription
+	for _, sub := range d.subs {
+		if sub.wants(e) {
+			targets = append(targets, sub)
+		}
+	}
+	d.mu.Unlock()
+	for _, sub := range targets {
+		d.enqueue(delivery{sub: sub, event: e, body: body, attempt: 1})
+	}
+}
+
+func (d *Dispatcher) enqueue(dl delivery) {
+	var reason string
+	d.mu.Lock()
+	if d.stopped {
+		reason = stopReason
+	} else {
+		select {
+		case d.queue <- dl:
+		default:
+			reason = "delivery queue full"
+		}
+	}
+	d.mu.Unlock()
+	if reason != "" {
+		d.bury(dl, dl.attempt-1, reason)
+	}
+}
+
+const stopReason = "dispatcher stopped before delivery"
+
+// Run delivers queued events until ctx is done. Deliveries still queued or
+// waiting to be retried then, and any published later, are dead-lettered, so
+// none is lost without a trace.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case dl := <-d.queue:
+					d.attempt(ctx, dl)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	d.mu.Lock()
+	d.stopped = true
+	retries := d.retries
+	d.retries = nil
+	d.mu.Unlock()
+	for t, dl := range retries {
+		t.Stop()
+		d.bury(dl, dl.attempt-1, stopReason)
+	}
+	for {
+		select {
+		case dl := <-d.queue:
+			d.bury(dl, dl.attempt-1, stopReason)
+		default:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, dl delivery) {
+	err := d.deliver(ctx, dl)
+	if err == nil {
+		return
+	}
+	if dl.attempt >= d.backoff.Attempts {
+		d.bury(dl, dl.attempt, err.Error())
+		return
+	}
+	next := dl
+	next.attempt++
+	// Workers are done before Run sets stopped, so the retry is always
+	// registered while Run can still take it over.
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var t *time.Timer
+	t = time.AfterFunc(d.backoff.delay(dl.attempt), func() {
+		d.mu.Lock()
+		_, pending := d.retries[t]
+		delete(d.retries, t)
+		d.mu.Unlock()
+		// Run takes over retries that have not fired when it stops.
+		if pending {
+			d.enqueue(next)
+		}
+	})
+	d.retries[t] = next
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, dl delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.sub.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", dl.event.ID)
+	req.Header.Set("X-Webhook-Event", dl.event.Type)
+	req.Header.Set("X-Webhook-Attempt", fmt.Sprint(dl.attempt))
+	req.Header.Set(SignatureHeader, Sign(dl.sub.Secret, d.now(), dl.body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) bury(dl delivery, attempts int, reason string) {
+	log.Printf("webhook %s: giving up on event %s after %d attempts: %s", dl.sub.ID, dl.event.ID, attempts, reason)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.dead) >= maxDeadLetters {
+		d.dead = d.dead[1:]
+	}
+	d.dead = append(d.dead, DeadLetter{
+		Subscription: dl.sub.ID,
+		Tenant:       dl.sub.Tenant,
+		Event:        dl.event,
+		Attempts:     attempts,
+		Error:        reason,
+		FailedAt:     d.now().UTC(),
+	})
+}
@@ -0,0 +1,209 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/netguard"
+	"assignment_infracloud/internal/storage"
+)
+
+// receiver records the deliveries it accepts and fails the first failFirst
+// attempts of each event.
+type receiver struct {
+	t         *testing.T
+	secret    string
+	failFirst int
+
+	mu       sync.Mutex
+	attempts map[string]int
+	events   []Event
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if err := Verify(rc.secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Minute); err != nil {
+		rc.t.Errorf("delivery signature: %v", err)
+	}
+	var e Event
+	json.Unmarshal(body, &e)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.attempts[e.ID]++
+	if rc.attempts[e.ID] <= rc.failFirst {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	rc.events = append(rc.events, e)
+}
+
+func (rc *receiver) received() []Event {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]Event(nil), rc.events...)
+}
+
+func startDispatcher(t *testing.T, rc *receiver, attempts int) (*Dispatcher, *httptest.Server) {
+	t.Helper()
+	srv := httptest.NewServer(rc)
+	t.Cleanup(srv.Close)
+	d := NewDispatcher(srv.Client(), 2, 16, Backoff{Attempts: attempts, Base: time.Millisecond, Max: 5 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return d, srv
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func TestDispatcher_DeliversWithRetries(t *testing.T) {
+	rc := &receiver{t: t, secret: "s3cret", failFirst: 2, attempts: map[string]int{}}
+	d, srv := startDispatcher(t, rc, 3)
+	if _, err := d.Subscribe("", srv.URL, "s3cret", []string{EventClicked}); err != nil {
+		t.Fatalf("Subscribe() error = %v", err)
+	}
+
+	link := storage.Link{Code: "abc", URL: "https://example.com"}
+	d.Publish(NewEvent(EventCreated, link)) // filtered out
+	d.Publish(NewEvent(EventClicked, link))
+	d.Publish(NewEvent(EventClicked, storage.Link{Code: "abc", Tenant: "acme"})) // another tenant
+
+	waitFor(t, "delivery", func() bool { return len(rc.received()) == 1 })
+	if e := rc.received()[0]; e.Type != EventClicked || e.Link.Code != "abc" {
+		t.Errorf("received %+v, want the click on abc", e)
+	}
+	if got := d.DeadLetters(""); len(got) != 0 {
+		t.Errorf("DeadLetters() = %+v, want none", got)
+	}
+}
+
+func TestDispatcher_DeadLetters(t *testing.T) {
+	rc := &receiver{t: t, secret: "s3cret", failFirst: 100, attempts: map[string]int{}}
+	d, srv := startDispatcher(t, rc, 3)
+	sub, _ := d.Subscribe("acme", srv.URL, "s3cret", nil)
+
+	e := NewEvent(EventExpired, storage.Link{Code: "abc", Tenant: "acme"})
+	d.Publish(e)
+	waitFor(t, "dead letter", func() bool { return len(d.DeadLetters("acme")) == 1 })
+
+	dl := d.DeadLetters("acme")[0]
+	if dl.Subscription != sub.ID || dl.Event.ID != e.ID || dl.Attempts != 3 || dl.Error != "status 503" {
+		t.Errorf("dead letter = %+v, want event %s after 3 attempts with status 503", dl, e.ID)
+	}
+	if got := d.DeadLetters(""); len(got) != 0 {
+		t.Errorf("DeadLetters(default tenant) = %+v, want none", got)
+	}
+}
+
+func TestDispatcher_Subscriptions(t *testing.T) {
+	d := NewDispatcher(nil, 1, 1, Backoff{})
+	if _, err := d.Subscribe("", "ftp://example.com", "", nil); err != ErrInvalidURL {
+		t.Errorf("Subscribe(ftp) error = %v, want %v", err, ErrInvalidURL)
+	}
+	if _, err := d.Subscribe("", "https://example.com", "", []string{"link.deleted"}); err != ErrInvalidEvent {
+		t.Errorf("Subscribe(unknown event) error = %v, want %v", err, ErrInvalidEvent)
+	}
+	sub, err := d.Subscribe("acme", "https://example.com/hook", "", nil)
+	if err != nil || sub.Secret == "" {
+		t.Fatalf("Subscribe() = %+v, %v, want a generated secret", sub, err)
+	}
+	if got := d.Subscriptions("acme"); len(got) != 1 || got[0].ID != sub.ID {
+		t.Errorf("Subscriptions(acme) = %+v, want [%s]", got, sub.ID)
+	}
+	if err := d.Unsubscribe("", sub.ID); err != ErrNotFound {
+		t.Errorf("Unsubscribe() by another tenant error = %v, want %v", err, ErrNotFound)
+	}
+	if err := d.Unsubscribe("acme", sub.ID); err != nil || len(d.Subscriptions("acme")) != 0 {
+		t.Errorf("Unsubscribe() error = %v, subscriptions = %v", err, d.Subscriptions("acme"))
+	}
+
+	// Without a running worker the queue fills and deliveries are buried.
+	d.Subscribe("", "https://example.com/hook", "", nil)
+	d.Publish(NewEvent(EventClicked, storage.Link{Code: "a"}))
+	d.Publish(NewEvent(EventClicked, storage.Link{Code: "b"}))
+	if got := d.DeadLetters(""); len(got) != 1 || got[0].Event.Link.Code != "b" || got[0].Attempts != 0 {
+		t.Errorf("DeadLetters() = %+v, want b never attempted", got)
+	}
+}
+
+func TestDispatcher_Run_DeadLettersOnStop(t *testing.T) {
+	rc := &receiver{t: t, secret: "s3cret", failFirst: 100, attempts: map[string]int{}}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+	d := NewDispatcher(srv.Client(), 1, 16, Backoff{Attempts: 5, Base: time.Hour, Max: time.Hour})
+	d.Subscribe("", srv.URL, "s3cret", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	retried := NewEvent(EventClicked, storage.Link{Code: "a"})
+	d.Publish(retried)
+	waitFor(t, "first attempt", func() bool {
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		return rc.attempts[retried.ID] == 1
+	})
+	cancel()
+	<-done
+	late := NewEvent(EventClicked, storage.Link{Code: "b"})
+	d.Publish(late)
+
+	got := d.DeadLetters("")
+	if len(got) != 2 || got[0].Event.ID != retried.ID || got[0].Attempts != 1 || got[1].Event.ID != late.ID || got[1].Attempts != 0 {
+		t.Fatalf("DeadLetters() = %+v, want the waiting retry and the late event", got)
+	}
+	if got[0].Error != stopReason {
+		t.Errorf("dead letter error = %q, want %q", got[0].Error, stopReason)
+	}
+}
+
+func TestDispatcher_DefaultClient(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	d := NewDispatcher(nil, 1, 16, Backoff{Attempts: 1})
+	d.Subscribe("", srv.URL, "", nil)
+	d.Publish(NewEvent(EventClicked, storage.Link{Code: "a"}))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	waitFor(t, "dead letter", func() bool { return len(d.DeadLetters("")) == 1 })
+	cancel()
+	<-done
+	if dl := d.DeadLetters("")[0]; !strings.Contains(dl.Error, netguard.ErrPrivateAddress.Error()) {
+		t.Errorf("delivery to loopback failed with %q, want the address check", dl.Error)
+	}
+
+	client := NewDispatcher(nil, 1, 1, Backoff{}).client
+	if client.CheckRedirect == nil || client.CheckRedirect(nil, nil) != http.ErrUseLastResponse {
+		t.Error("default client follows redirects")
+	}
+}
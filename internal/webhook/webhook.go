@@ -0,0 +1,128 @@
+// Package webhook delivers link events to subscribers' HTTP endpoints.
+// Deliveries are signed with the subscription's secret, retried with
+// exponential backoff and, once out of attempts, kept in a dead-letter list.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"assignment_infracloud/internal/storage"
+)
+
+// Event types.
+const (
+	EventCreated = "link.created"
+	EventClicked = "link.clicked"
+	// EventExpired is sent when a click-limited link is used up.
+	EventExpired = "link.expired"
+)
+
+// SignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>", the MAC
+// being over "<unix seconds>.<body>" keyed with the subscription's secret.
+const SignatureHeader = "X-Webhook-Signature"
+
+var (
+	ErrInvalidURL       = errors.New("invalid webhook url")
+	ErrInvalidEvent     = errors.New("invalid event type")
+	ErrNotFound         = errors.New("subscription not found")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// ValidEvent reports whether typ is an event type subscribers can ask for.
+func ValidEvent(typ string) bool {
+	return typ == EventCreated || typ == EventClicked || typ == EventExpired
+}
+
+// LinkData is the link an event is about, as sent to subscribers.
+type LinkData struct {
+	Code     string   `json:"code"`
+	Domain   string   `json:"domain,omitempty"`
+	URL      string   `json:"url"`
+	Clicks   int      `json:"clicks"`
+	Tags     []string `json:"tags,omitempty"`
+	Campaign string   `json:"campaign,omitempty"`
+}
+
+// Event is the JSON body of a delivery. Tenant decides which subscriptions
+// see it and is not sent.
+type Event struct {
+	ID     string    `json:"id"`
+	Type   string    `json:"type"`
+	Time   time.Time `json:"time"`
+	Tenant string    `json:"-"`
+	Link   LinkData  `json:"link"`
+}
+
+// NewEvent describes something that just happened to link.
+func NewEvent(typ string, link storage.Link) Event {
+	return Event{
+		ID:     randomHex(8),
+		Type:   typ,
+		Time:   time.Now().UTC(),
+		Tenant: link.Tenant,
+		Link: LinkData{
+			Code:     link.Code,
+			Domain:   link.Domain,
+			URL:      link.URL,
+			Clicks:   link.Clicks,
+			Tags:     link.Tags,
+			Campaign: link.Campaign,
+		},
+	}
+}
+
+// Sign returns the SignatureHeader value for body sent at t.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + mac(secret, ts, body)
+}
+
+// Verify checks a SignatureHeader value against body, rejecting signatures
+// made more than tolerance away from now so captured deliveries cannot be
+// replayed later.
+func Verify(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(sec, 0)); d > tolerance || d < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+	if !hmac.Equal([]byte(sig), []byte(mac(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret, ts string, body []byte) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(ts + "."))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("webhook: random id: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
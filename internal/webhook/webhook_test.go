@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"assignment_infracloud/internal/storage"
+)
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"type":"link.clicked"}`)
+	now := time.Unix(1_780_000_000, 0)
+	sig := Sign("s3cret", now, body)
+
+	if err := Verify("s3cret", sig, body, now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	tests := []struct {
+		name, secret, header string
+		body                 []byte
+		at                   time.Time
+	}{
+		{"wrong secret", "other", sig, body, now},
+		{"altered body", "s3cret", sig, []byte(`{"type":"link.created"}`), now},
+		{"stale", "s3cret", sig, body, now.Add(time.Hour)},
+		{"malformed", "s3cret", "v1=abc", body, now},
+	}
+	for _, tt := range tests {
+		if err := Verify(tt.secret, tt.header, tt.body, tt.at, 5*time.Minute); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: Verify() error = %v, want %v", tt.name, err, ErrInvalidSignature)
+		}
+	}
+}
+
+func TestNewEvent(t *testing.T) {
+	e := NewEvent(EventClicked, storage.Link{Code: "abc", Tenant: "acme", Domain: "acme.link", URL: "https://example.com", Clicks: 3})
+	if e.ID == "" || e.Type != EventClicked || e.Tenant != "acme" || e.Link.Code != "abc" || e.Link.Clicks != 3 {
+		t.Errorf("NewEvent() = %+v", e)
+	}
+}
+
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Attempts: 5, Base: 100 * time.Millisecond, Max: time.Second}
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond},
+		{5, time.Second},
+		{30, time.Second},
+	}
+	for _, tt := range tests {
+		if got := b.delay(tt.attempt); got < tt.max/2 || got > tt.max {
+			t.Errorf("delay(%d) = %v, want within [%v, %v]", tt.attempt, got, tt.max/2, tt.max)
+		}
+	}
+}